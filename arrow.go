@@ -0,0 +1,889 @@
+package grngo
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"time"
+)
+
+// ArrowField is a field of an Apache Arrow schema.
+type ArrowField struct {
+	Name     string            // The field name.
+	Type     string            // The type name, such as "int32" and "utf8".
+	Nullable bool              // Whether the field is nullable.
+	Elem     *ArrowField       // The element field of a list.
+	Metadata map[string]string // Custom metadata.
+	typeID   uint8             // The type ID in Schema.fbs.
+	typePos  int               // The position of the Type table.
+
+	dictionary *arrowDictionary // The dictionary encoding, or nil.
+}
+
+// arrowDictionary is a DictionaryEncoding in Schema.fbs.
+type arrowDictionary struct {
+	id    int64       // The dictionary ID.
+	index *ArrowField // The field of indices.
+}
+
+// ArrowSchema is an Apache Arrow schema.
+type ArrowSchema struct {
+	Fields   []*ArrowField     // Fields.
+	Metadata map[string]string // Custom metadata.
+}
+
+// ArrowColumn is a column of an Apache Arrow record batch.
+//
+// Values is []bool, []int8, []int16, []int32, []int64, []uint8, []uint16,
+// []uint32, []uint64, []float32, []float64, []string for utf8, [][]byte for
+// binary, []time.Time for timestamp, []interface{} for null, or a slice of
+// the element slices for list, e.g. [][]string for list<utf8>.
+// A dictionary-encoded column is decoded into the dictionary values.
+type ArrowColumn struct {
+	Field  *ArrowField // The field.
+	Values interface{} // The values.
+	Valid  []bool      // Whether each value is valid, or nil if no null.
+}
+
+// ArrowRecord is an Apache Arrow record batch.
+type ArrowRecord struct {
+	Schema  *ArrowSchema   // The schema.
+	NRows   int            // The number of rows.
+	Columns []*ArrowColumn // Columns.
+}
+
+// ArrowReader reads record batches from Apache Arrow IPC streams.
+//
+// ArrowReader supports fixed-width primitive types, bool, utf8, binary,
+// timestamp, null, list and dictionary encoding. Compressed record batches
+// are not supported. Concatenated streams are read one after another.
+type ArrowReader struct {
+	r            io.Reader               // The input.
+	schema       *ArrowSchema            // The current schema.
+	dictionaries map[int64]reflect.Value // The current dictionaries.
+	record       *ArrowRecord            // The current record batch.
+	err          error                   // The first error.
+	wait         func() error            // Waits for the command of QueryArrow.
+}
+
+// NewArrowReader returns a new ArrowReader.
+func NewArrowReader(r io.Reader) *ArrowReader {
+	return &ArrowReader{r: r}
+}
+
+// Schema returns the current schema.
+func (reader *ArrowReader) Schema() *ArrowSchema {
+	return reader.schema
+}
+
+// Record returns the current record batch.
+func (reader *ArrowReader) Record() *ArrowRecord {
+	return reader.record
+}
+
+// Err returns the first error except io.EOF.
+func (reader *ArrowReader) Err() error {
+	return reader.err
+}
+
+// Close stops reading and waits for the command of QueryArrow if any.
+// Close must be called if the reader is not read until Next returns false.
+func (reader *ArrowReader) Close() error {
+	reader.record = nil
+	reader.finish()
+	return nil
+}
+
+// finish waits for the command of QueryArrow. An error of the command takes
+// precedence over an error of the stream.
+func (reader *ArrowReader) finish() {
+	if reader.wait == nil {
+		return
+	}
+	if err := reader.wait(); err != nil {
+		reader.err = err
+	}
+	reader.wait = nil
+}
+
+// Next reads the next record batch and returns whether it succeeded.
+func (reader *ArrowReader) Next() bool {
+	if reader.next() {
+		return true
+	}
+	reader.finish()
+	return false
+}
+
+// next reads the next record batch.
+func (reader *ArrowReader) next() bool {
+	reader.record = nil
+	for reader.err == nil {
+		message, body, err := reader.readMessage()
+		if err != nil {
+			if err != io.EOF {
+				reader.err = err
+			}
+			return false
+		}
+		if message == nil {
+			// End of stream.
+			reader.schema = nil
+			continue
+		}
+		switch message.headerType {
+		case arrowSchemaHeader:
+			reader.schema, reader.err = message.schema()
+			reader.dictionaries = make(map[int64]reflect.Value)
+		case arrowRecordBatchHeader:
+			if reader.schema == nil {
+				reader.err = fmt.Errorf("invalid Arrow stream: no schema")
+				return false
+			}
+			reader.record, reader.err = message.recordBatch(reader.schema,
+				body, reader.dictionaries)
+			return reader.err == nil
+		case arrowDictionaryBatchHeader:
+			if reader.schema == nil {
+				reader.err = fmt.Errorf("invalid Arrow stream: no schema")
+				return false
+			}
+			reader.err = message.dictionaryBatch(reader.schema, body,
+				reader.dictionaries)
+		default:
+			reader.err = fmt.Errorf("unsupported Arrow message: type = %d",
+				message.headerType)
+		}
+	}
+	return false
+}
+
+// Arrow message header types in Message.fbs.
+const (
+	arrowSchemaHeader          = 1
+	arrowDictionaryBatchHeader = 2
+	arrowRecordBatchHeader     = 3
+)
+
+// arrowMessage is a Message in Message.fbs.
+type arrowMessage struct {
+	fb         flatbuffer // The metadata.
+	headerType uint8      // The header type.
+	headerPos  int        // The position of the header table.
+}
+
+// readMessage reads an encapsulated message.
+// readMessage returns nil at the end of a stream and io.EOF at the end of
+// the input.
+func (reader *ArrowReader) readMessage() (*arrowMessage, []byte, error) {
+	var prefix [4]byte
+	if _, err := io.ReadFull(reader.r, prefix[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = fmt.Errorf("invalid Arrow stream: %v", err)
+		}
+		return nil, nil, err
+	}
+	size := binary.LittleEndian.Uint32(prefix[:])
+	if size == 0xffffffff {
+		// A continuation marker is followed by the metadata size.
+		if _, err := io.ReadFull(reader.r, prefix[:]); err != nil {
+			return nil, nil, fmt.Errorf("invalid Arrow stream: %v", err)
+		}
+		size = binary.LittleEndian.Uint32(prefix[:])
+	}
+	if size == 0 {
+		return nil, nil, nil
+	}
+	if size > math.MaxInt32 {
+		return nil, nil, fmt.Errorf("invalid Arrow stream: metadata size = %d", size)
+	}
+	fb := flatbuffer(make([]byte, size))
+	if _, err := io.ReadFull(reader.r, fb); err != nil {
+		return nil, nil, fmt.Errorf("invalid Arrow stream: %v", err)
+	}
+	root, err := fb.root()
+	if err != nil {
+		return nil, nil, err
+	}
+	message := &arrowMessage{fb: fb}
+	message.headerType = fb.uint8Field(root, 1)
+	if message.headerPos, err = fb.tableField(root, 2); err != nil {
+		return nil, nil, err
+	}
+	bodyLength := fb.int64Field(root, 3)
+	if (bodyLength < 0) || (bodyLength > math.MaxInt32) {
+		return nil, nil, fmt.Errorf("invalid Arrow stream: body length = %d", bodyLength)
+	}
+	body := make([]byte, bodyLength)
+	if _, err := io.ReadFull(reader.r, body); err != nil {
+		return nil, nil, fmt.Errorf("invalid Arrow stream: %v", err)
+	}
+	return message, body, nil
+}
+
+// arrowMetadata reads a vector of KeyValue.
+func (fb flatbuffer) arrowMetadata(table, field int) (map[string]string, error) {
+	pos, n, err := fb.vectorField(table, field)
+	if (err != nil) || (n == 0) {
+		return nil, err
+	}
+	metadata := make(map[string]string, n)
+	for i := 0; i < n; i++ {
+		keyValue, err := fb.indirect(pos + 4*i)
+		if err != nil {
+			return nil, err
+		}
+		key, err := fb.stringField(keyValue, 0)
+		if err != nil {
+			return nil, err
+		}
+		value, err := fb.stringField(keyValue, 1)
+		if err != nil {
+			return nil, err
+		}
+		metadata[key] = value
+	}
+	return metadata, nil
+}
+
+// schema decodes a Schema message.
+func (message *arrowMessage) schema() (*ArrowSchema, error) {
+	fb := message.fb
+	if message.headerPos == 0 {
+		return nil, fmt.Errorf("invalid Arrow schema: no header")
+	}
+	schema := new(ArrowSchema)
+	pos, n, err := fb.vectorField(message.headerPos, 1)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < n; i++ {
+		field, err := fb.arrowField(pos + 4*i)
+		if err != nil {
+			return nil, err
+		}
+		schema.Fields = append(schema.Fields, field)
+	}
+	if schema.Metadata, err = fb.arrowMetadata(message.headerPos, 2); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// arrowField decodes a Field referred to by the offset at pos.
+func (fb flatbuffer) arrowField(pos int) (*ArrowField, error) {
+	table, err := fb.indirect(pos)
+	if err != nil {
+		return nil, err
+	}
+	field := new(ArrowField)
+	if field.Name, err = fb.stringField(table, 0); err != nil {
+		return nil, err
+	}
+	field.Nullable = fb.uint8Field(table, 1) != 0
+	field.typeID = fb.uint8Field(table, 2)
+	if field.typePos, err = fb.tableField(table, 3); err != nil {
+		return nil, err
+	}
+	if field.dictionary, err = fb.arrowDictionary(field.Name, table); err != nil {
+		return nil, err
+	}
+	if field.Metadata, err = fb.arrowMetadata(table, 6); err != nil {
+		return nil, err
+	}
+	if field.Type, err = fb.arrowTypeName(field); err != nil {
+		return nil, err
+	}
+	if field.typeID == arrowList {
+		children, n, err := fb.vectorField(table, 5)
+		if err != nil {
+			return nil, err
+		}
+		if n != 1 {
+			return nil, fmt.Errorf("invalid Arrow field: name = %s: %d children",
+				field.Name, n)
+		}
+		if field.Elem, err = fb.arrowField(children); err != nil {
+			return nil, err
+		}
+		field.Type = "list<" + field.Elem.Type + ">"
+	}
+	return field, nil
+}
+
+// arrowDictionary decodes the DictionaryEncoding of a Field, or returns nil
+// if the field is not dictionary-encoded.
+func (fb flatbuffer) arrowDictionary(name string, table int) (*arrowDictionary, error) {
+	pos, err := fb.tableField(table, 4)
+	if (err != nil) || (pos == 0) {
+		return nil, err
+	}
+	dictionary := &arrowDictionary{id: fb.int64Field(pos, 0)}
+	// The default index type is int32.
+	index := &ArrowField{Name: name, Type: "int32", typeID: arrowInt}
+	if index.typePos, err = fb.tableField(pos, 1); err != nil {
+		return nil, err
+	}
+	if index.typePos != 0 {
+		if index.Type, err = fb.arrowTypeName(index); err != nil {
+			return nil, err
+		}
+	}
+	dictionary.index = index
+	return dictionary, nil
+}
+
+// arrowDictionaryField returns the dictionary-encoded field with id.
+func arrowDictionaryField(fields []*ArrowField, id int64) *ArrowField {
+	for _, field := range fields {
+		if (field.dictionary != nil) && (field.dictionary.id == id) {
+			return field
+		}
+		if field.Elem != nil {
+			if elem := arrowDictionaryField([]*ArrowField{field.Elem}, id); elem != nil {
+				return elem
+			}
+		}
+	}
+	return nil
+}
+
+// Arrow types in Schema.fbs.
+const (
+	arrowNull          = 1
+	arrowInt           = 2
+	arrowFloatingPoint = 3
+	arrowBinary        = 4
+	arrowUtf8          = 5
+	arrowBool          = 6
+	arrowTimestamp     = 10
+	arrowList          = 12
+)
+
+// arrowTypeName returns the type name of a field.
+func (fb flatbuffer) arrowTypeName(field *ArrowField) (string, error) {
+	switch field.typeID {
+	case arrowNull:
+		return "null", nil
+	case arrowInt:
+		bitWidth := fb.int32Field(field.typePos, 0)
+		switch bitWidth {
+		case 8, 16, 32, 64:
+		default:
+			return "", fmt.Errorf("invalid Arrow field: name = %s: bit width = %d",
+				field.Name, bitWidth)
+		}
+		if fb.uint8Field(field.typePos, 1) != 0 {
+			return fmt.Sprintf("int%d", bitWidth), nil
+		}
+		return fmt.Sprintf("uint%d", bitWidth), nil
+	case arrowFloatingPoint:
+		switch fb.int16Field(field.typePos, 0) {
+		case 1:
+			return "float32", nil
+		case 2:
+			return "float64", nil
+		}
+	case arrowBinary:
+		return "binary", nil
+	case arrowUtf8:
+		return "utf8", nil
+	case arrowBool:
+		return "bool", nil
+	case arrowTimestamp:
+		units := []string{"s", "ms", "us", "ns"}
+		unit := fb.int16Field(field.typePos, 0)
+		if (unit >= 0) && (int(unit) < len(units)) {
+			return "timestamp[" + units[unit] + "]", nil
+		}
+	case arrowList:
+		return "list", nil
+	}
+	return "", fmt.Errorf("unsupported Arrow field: name = %s: type = %d",
+		field.Name, field.typeID)
+}
+
+// arrowBatch is a cursor over the nodes and buffers of a record batch.
+type arrowBatch struct {
+	fb           flatbuffer              // The metadata.
+	body         []byte                  // The body.
+	dictionaries map[int64]reflect.Value // The dictionaries.
+	nodes        int                     // The position of FieldNode structs.
+	nNodes       int                     // The number of nodes.
+	buffers      int                     // The position of Buffer structs.
+	nBufs        int                     // The number of buffers.
+	node         int                     // The index of the next node.
+	buffer       int                     // The index of the next buffer.
+}
+
+// nextNode returns the length and the null count of the next node.
+func (batch *arrowBatch) nextNode() (int, int, error) {
+	if batch.node >= batch.nNodes {
+		return 0, 0, fmt.Errorf("invalid Arrow record batch: too few nodes")
+	}
+	pos := batch.nodes + 16*batch.node
+	batch.node++
+	length := batch.fb.int64At(pos)
+	nullCount := batch.fb.int64At(pos + 8)
+	if (length < 0) || (length > math.MaxInt32) ||
+		(nullCount < 0) || (nullCount > length) {
+		return 0, 0, fmt.Errorf("invalid Arrow record batch: length = %d, null count = %d",
+			length, nullCount)
+	}
+	return int(length), int(nullCount), nil
+}
+
+// nextBuffer returns the next buffer.
+func (batch *arrowBatch) nextBuffer() ([]byte, error) {
+	if batch.buffer >= batch.nBufs {
+		return nil, fmt.Errorf("invalid Arrow record batch: too few buffers")
+	}
+	pos := batch.buffers + 16*batch.buffer
+	batch.buffer++
+	offset := batch.fb.int64At(pos)
+	length := batch.fb.int64At(pos + 8)
+	if (offset < 0) || (length < 0) || (offset > int64(len(batch.body))) ||
+		(length > int64(len(batch.body))-offset) {
+		return nil, fmt.Errorf("invalid Arrow record batch: offset = %d, length = %d",
+			offset, length)
+	}
+	return batch.body[offset : offset+length], nil
+}
+
+// recordBatch decodes a RecordBatch message.
+func (message *arrowMessage) recordBatch(schema *ArrowSchema, body []byte,
+	dictionaries map[int64]reflect.Value) (*ArrowRecord, error) {
+	if message.headerPos == 0 {
+		return nil, fmt.Errorf("invalid Arrow record batch: no header")
+	}
+	record, err := message.fb.arrowRecordBatch(message.headerPos,
+		schema.Fields, body, dictionaries)
+	if err != nil {
+		return nil, err
+	}
+	record.Schema = schema
+	return record, nil
+}
+
+// dictionaryBatch decodes a DictionaryBatch message into dictionaries.
+// A delta dictionary batch appends values to the dictionary.
+func (message *arrowMessage) dictionaryBatch(schema *ArrowSchema, body []byte,
+	dictionaries map[int64]reflect.Value) error {
+	fb := message.fb
+	if message.headerPos == 0 {
+		return fmt.Errorf("invalid Arrow dictionary batch: no header")
+	}
+	id := fb.int64Field(message.headerPos, 0)
+	field := arrowDictionaryField(schema.Fields, id)
+	if field == nil {
+		return fmt.Errorf("invalid Arrow dictionary batch: id = %d", id)
+	}
+	pos, err := fb.tableField(message.headerPos, 1)
+	if err != nil {
+		return err
+	}
+	if pos == 0 {
+		return fmt.Errorf("invalid Arrow dictionary batch: no data")
+	}
+	valueField := *field
+	valueField.dictionary = nil
+	record, err := fb.arrowRecordBatch(pos, []*ArrowField{&valueField},
+		body, dictionaries)
+	if err != nil {
+		return err
+	}
+	values := reflect.ValueOf(record.Columns[0].Values)
+	if dictionary, ok := dictionaries[id]; ok && (fb.uint8Field(message.headerPos, 2) != 0) {
+		values = reflect.AppendSlice(dictionary, values)
+	}
+	dictionaries[id] = values
+	return nil
+}
+
+// arrowRecordBatch decodes a RecordBatch table.
+func (fb flatbuffer) arrowRecordBatch(table int, fields []*ArrowField, body []byte,
+	dictionaries map[int64]reflect.Value) (*ArrowRecord, error) {
+	if err := fb.arrowCompression(table); err != nil {
+		return nil, err
+	}
+	batch := &arrowBatch{fb: fb, body: body, dictionaries: dictionaries}
+	var err error
+	if batch.nodes, batch.nNodes, err = fb.vectorField(table, 1); err != nil {
+		return nil, err
+	}
+	if batch.buffers, batch.nBufs, err = fb.vectorField(table, 2); err != nil {
+		return nil, err
+	}
+	if (batch.nodes+16*batch.nNodes > len(fb)) ||
+		(batch.buffers+16*batch.nBufs > len(fb)) {
+		return nil, fmt.Errorf("invalid Arrow record batch: out of range")
+	}
+	length := fb.int64Field(table, 0)
+	if (length < 0) || (length > math.MaxInt32) {
+		return nil, fmt.Errorf("invalid Arrow record batch: length = %d", length)
+	}
+	record := &ArrowRecord{NRows: int(length)}
+	for _, field := range fields {
+		column, err := batch.column(field)
+		if err != nil {
+			return nil, err
+		}
+		record.Columns = append(record.Columns, column)
+	}
+	return record, nil
+}
+
+// arrowCompression rejects a compressed RecordBatch table.
+func (fb flatbuffer) arrowCompression(table int) error {
+	compression, err := fb.tableField(table, 3)
+	if (err != nil) || (compression == 0) {
+		return err
+	}
+	switch codec := fb.uint8Field(compression, 0); codec {
+	case 0:
+		return fmt.Errorf("unsupported Arrow record batch: compression = lz4_frame")
+	case 1:
+		return fmt.Errorf("unsupported Arrow record batch: compression = zstd")
+	default:
+		return fmt.Errorf("unsupported Arrow record batch: compression = %d", codec)
+	}
+}
+
+// arrowValidity decodes a validity bitmap.
+func arrowValidity(bitmap []byte, length, nullCount int) ([]bool, error) {
+	if (nullCount == 0) || (len(bitmap) == 0) {
+		return nil, nil
+	}
+	if len(bitmap) < (length+7)/8 {
+		return nil, fmt.Errorf("invalid Arrow record batch: short bitmap")
+	}
+	valid := make([]bool, length)
+	for i := range valid {
+		valid[i] = (bitmap[i/8]>>uint(i%8))&1 != 0
+	}
+	return valid, nil
+}
+
+// arrowOffsets decodes the int32 offsets of a variable-size layout.
+func arrowOffsets(buf []byte, length, dataSize int) ([]int, error) {
+	if len(buf) < 4*(length+1) {
+		return nil, fmt.Errorf("invalid Arrow record batch: short offsets")
+	}
+	offsets := make([]int, length+1)
+	for i := range offsets {
+		offsets[i] = int(int32(binary.LittleEndian.Uint32(buf[4*i:])))
+		if (offsets[i] < 0) || (offsets[i] > dataSize) ||
+			((i != 0) && (offsets[i] < offsets[i-1])) {
+			return nil, fmt.Errorf("invalid Arrow record batch: offset = %d", offsets[i])
+		}
+	}
+	return offsets, nil
+}
+
+// column decodes a column.
+func (batch *arrowBatch) column(field *ArrowField) (*ArrowColumn, error) {
+	if field.dictionary != nil {
+		return batch.dictionaryColumn(field)
+	}
+	length, nullCount, err := batch.nextNode()
+	if err != nil {
+		return nil, err
+	}
+	column := &ArrowColumn{Field: field}
+	if field.typeID == arrowNull {
+		column.Values = make([]interface{}, length)
+		return column, nil
+	}
+	bitmap, err := batch.nextBuffer()
+	if err != nil {
+		return nil, err
+	}
+	if column.Valid, err = arrowValidity(bitmap, length, nullCount); err != nil {
+		return nil, err
+	}
+	data, err := batch.nextBuffer()
+	if err != nil {
+		return nil, err
+	}
+	switch field.typeID {
+	case arrowBool:
+		if len(data) < (length+7)/8 {
+			return nil, fmt.Errorf("invalid Arrow record batch: short data")
+		}
+		values := make([]bool, length)
+		for i := range values {
+			values[i] = (data[i/8]>>uint(i%8))&1 != 0
+		}
+		column.Values = values
+	case arrowBinary, arrowUtf8:
+		values, err := batch.nextBuffer()
+		if err != nil {
+			return nil, err
+		}
+		offsets, err := arrowOffsets(data, length, len(values))
+		if err != nil {
+			return nil, err
+		}
+		if field.typeID == arrowUtf8 {
+			strs := make([]string, length)
+			for i := range strs {
+				strs[i] = string(values[offsets[i]:offsets[i+1]])
+			}
+			column.Values = strs
+		} else {
+			bins := make([][]byte, length)
+			for i := range bins {
+				bins[i] = append([]byte(nil), values[offsets[i]:offsets[i+1]]...)
+			}
+			column.Values = bins
+		}
+	case arrowList:
+		elem, err := batch.column(field.Elem)
+		if err != nil {
+			return nil, err
+		}
+		elems := reflect.ValueOf(elem.Values)
+		offsets, err := arrowOffsets(data, length, elems.Len())
+		if err != nil {
+			return nil, err
+		}
+		values := reflect.MakeSlice(reflect.SliceOf(elems.Type()), length, length)
+		for i := 0; i < length; i++ {
+			values.Index(i).Set(elems.Slice(offsets[i], offsets[i+1]))
+		}
+		column.Values = values.Interface()
+	default:
+		if column.Values, err = arrowFixedValues(field, data, length); err != nil {
+			return nil, err
+		}
+	}
+	return column, nil
+}
+
+// dictionaryColumn decodes a dictionary-encoded column into the dictionary
+// values.
+func (batch *arrowBatch) dictionaryColumn(field *ArrowField) (*ArrowColumn, error) {
+	dictionary, ok := batch.dictionaries[field.dictionary.id]
+	if !ok {
+		return nil, fmt.Errorf("invalid Arrow record batch: no dictionary: id = %d",
+			field.dictionary.id)
+	}
+	indices, err := batch.column(field.dictionary.index)
+	if err != nil {
+		return nil, err
+	}
+	indexValues := reflect.ValueOf(indices.Values)
+	length := indexValues.Len()
+	values := reflect.MakeSlice(dictionary.Type(), length, length)
+	for i := 0; i < length; i++ {
+		if (indices.Valid != nil) && !indices.Valid[i] {
+			continue
+		}
+		var index uint64
+		switch elem := indexValues.Index(i); elem.Kind() {
+		case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			index = elem.Uint()
+		default:
+			index = uint64(elem.Int())
+		}
+		if index >= uint64(dictionary.Len()) {
+			return nil, fmt.Errorf("invalid Arrow record batch: index = %d", index)
+		}
+		values.Index(i).Set(dictionary.Index(int(index)))
+	}
+	return &ArrowColumn{Field: field, Values: values.Interface(), Valid: indices.Valid}, nil
+}
+
+// arrowFixedValues decodes the values of a fixed-width primitive type.
+func arrowFixedValues(field *ArrowField, data []byte, length int) (interface{}, error) {
+	var elemType reflect.Type
+	switch field.Type {
+	case "int8":
+		elemType = reflect.TypeOf(int8(0))
+	case "int16":
+		elemType = reflect.TypeOf(int16(0))
+	case "int32":
+		elemType = reflect.TypeOf(int32(0))
+	case "int64":
+		elemType = reflect.TypeOf(int64(0))
+	case "uint8":
+		elemType = reflect.TypeOf(uint8(0))
+	case "uint16":
+		elemType = reflect.TypeOf(uint16(0))
+	case "uint32":
+		elemType = reflect.TypeOf(uint32(0))
+	case "uint64":
+		elemType = reflect.TypeOf(uint64(0))
+	case "float32":
+		elemType = reflect.TypeOf(float32(0))
+	case "float64":
+		elemType = reflect.TypeOf(float64(0))
+	default:
+		if field.typeID != arrowTimestamp {
+			return nil, fmt.Errorf("unsupported Arrow field: name = %s: type = %s",
+				field.Name, field.Type)
+		}
+		if len(data) < 8*length {
+			return nil, fmt.Errorf("invalid Arrow record batch: short data")
+		}
+		units := map[string]time.Duration{"timestamp[s]": time.Second,
+			"timestamp[ms]": time.Millisecond, "timestamp[us]": time.Microsecond,
+			"timestamp[ns]": time.Nanosecond}
+		unit := int64(units[field.Type])
+		values := make([]time.Time, length)
+		for i := range values {
+			value := int64(binary.LittleEndian.Uint64(data[8*i:]))
+			sec, nsec := value/(int64(time.Second)/unit), value%(int64(time.Second)/unit)
+			if nsec < 0 {
+				sec, nsec = sec-1, nsec+int64(time.Second)/unit
+			}
+			values[i] = time.Unix(sec, nsec*unit)
+		}
+		return values, nil
+	}
+	size := int(elemType.Size())
+	if len(data) < size*length {
+		return nil, fmt.Errorf("invalid Arrow record batch: short data")
+	}
+	values := reflect.MakeSlice(reflect.SliceOf(elemType), length, length)
+	for i := 0; i < length; i++ {
+		var bits uint64
+		switch size {
+		case 1:
+			bits = uint64(data[i])
+		case 2:
+			bits = uint64(binary.LittleEndian.Uint16(data[2*i:]))
+		case 4:
+			bits = uint64(binary.LittleEndian.Uint32(data[4*i:]))
+		case 8:
+			bits = binary.LittleEndian.Uint64(data[8*i:])
+		}
+		value := values.Index(i)
+		switch elemType.Kind() {
+		case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			shift := uint(64 - 8*size)
+			value.SetInt(int64(bits<<shift) >> shift)
+		case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			value.SetUint(bits)
+		case reflect.Float32:
+			value.SetFloat(float64(math.Float32frombits(uint32(bits))))
+		case reflect.Float64:
+			value.SetFloat(math.Float64frombits(bits))
+		}
+	}
+	return values.Interface(), nil
+}
+
+// flatbuffer is a FlatBuffers buffer.
+type flatbuffer []byte
+
+// int64At returns the little-endian int64 at pos.
+func (fb flatbuffer) int64At(pos int) int64 {
+	return int64(binary.LittleEndian.Uint64(fb[pos:]))
+}
+
+// root returns the position of the root table.
+func (fb flatbuffer) root() (int, error) {
+	return fb.indirect(0)
+}
+
+// indirect follows the offset at pos.
+func (fb flatbuffer) indirect(pos int) (int, error) {
+	if (pos < 0) || (pos+4 > len(fb)) {
+		return 0, fmt.Errorf("invalid FlatBuffers: position = %d", pos)
+	}
+	target := pos + int(binary.LittleEndian.Uint32(fb[pos:]))
+	if (target < pos) || (target+4 > len(fb)) {
+		return 0, fmt.Errorf("invalid FlatBuffers: offset = %d", target)
+	}
+	return target, nil
+}
+
+// field returns the position of a field of a table, or 0 if it is absent.
+func (fb flatbuffer) field(table, field, size int) int {
+	if (table <= 0) || (table+4 > len(fb)) {
+		return 0
+	}
+	vtable := table - int(int32(binary.LittleEndian.Uint32(fb[table:])))
+	if (vtable < 0) || (vtable+4 > len(fb)) {
+		return 0
+	}
+	vtableSize := int(binary.LittleEndian.Uint16(fb[vtable:]))
+	entry := 4 + 2*field
+	if (entry+2 > vtableSize) || (vtable+entry+2 > len(fb)) {
+		return 0
+	}
+	offset := int(binary.LittleEndian.Uint16(fb[vtable+entry:]))
+	if (offset == 0) || (table+offset+size > len(fb)) {
+		return 0
+	}
+	return table + offset
+}
+
+// uint8Field returns a uint8 or bool field.
+func (fb flatbuffer) uint8Field(table, field int) uint8 {
+	if pos := fb.field(table, field, 1); pos != 0 {
+		return fb[pos]
+	}
+	return 0
+}
+
+// int16Field returns an int16 field.
+func (fb flatbuffer) int16Field(table, field int) int16 {
+	if pos := fb.field(table, field, 2); pos != 0 {
+		return int16(binary.LittleEndian.Uint16(fb[pos:]))
+	}
+	return 0
+}
+
+// int32Field returns an int32 field.
+func (fb flatbuffer) int32Field(table, field int) int32 {
+	if pos := fb.field(table, field, 4); pos != 0 {
+		return int32(binary.LittleEndian.Uint32(fb[pos:]))
+	}
+	return 0
+}
+
+// int64Field returns an int64 field.
+func (fb flatbuffer) int64Field(table, field int) int64 {
+	if pos := fb.field(table, field, 8); pos != 0 {
+		return fb.int64At(pos)
+	}
+	return 0
+}
+
+// tableField returns the position of a table field, or 0 if it is absent.
+func (fb flatbuffer) tableField(table, field int) (int, error) {
+	if pos := fb.field(table, field, 4); pos != 0 {
+		return fb.indirect(pos)
+	}
+	return 0, nil
+}
+
+// vectorField returns the position of the first element and the number of
+// elements of a vector field.
+func (fb flatbuffer) vectorField(table, field int) (int, int, error) {
+	pos, err := fb.tableField(table, field)
+	if (err != nil) || (pos == 0) {
+		return 0, 0, err
+	}
+	n := int(binary.LittleEndian.Uint32(fb[pos:]))
+	if (n < 0) || (n > len(fb)) {
+		return 0, 0, fmt.Errorf("invalid FlatBuffers: vector length = %d", n)
+	}
+	return pos + 4, n, nil
+}
+
+// stringField returns a string field.
+func (fb flatbuffer) stringField(table, field int) (string, error) {
+	pos, n, err := fb.vectorField(table, field)
+	if (err != nil) || (pos == 0) {
+		return "", err
+	}
+	if pos+n > len(fb) {
+		return "", fmt.Errorf("invalid FlatBuffers: string length = %d", n)
+	}
+	return string(fb[pos : pos+n]), nil
+}
@@ -0,0 +1,135 @@
+package grngo
+
+import (
+	"math"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readArrowFixture reads all the record batches of a fixture.
+func readArrowFixture(t *testing.T, name string) ([]*ArrowRecord, error) {
+	file, err := os.Open("testdata/" + name)
+	if err != nil {
+		t.Fatalf("os.Open() failed: %v", err)
+	}
+	defer file.Close()
+	reader := NewArrowReader(file)
+	var records []*ArrowRecord
+	for reader.Next() {
+		records = append(records, reader.Record())
+	}
+	return records, reader.Err()
+}
+
+// checkArrowColumns checks the values and the validity of columns.
+func checkArrowColumns(t *testing.T, record *ArrowRecord, values []interface{}, valid [][]bool) {
+	if len(record.Columns) != len(values) {
+		t.Fatalf("ArrowReader.Next() failed: nColumns = %d", len(record.Columns))
+	}
+	for i, column := range record.Columns {
+		if !reflect.DeepEqual(column.Values, values[i]) {
+			t.Fatalf("ArrowReader.Next() failed: name = %s, values = %v, expected = %v",
+				column.Field.Name, column.Values, values[i])
+		}
+		if !reflect.DeepEqual(column.Valid, valid[i]) {
+			t.Fatalf("ArrowReader.Next() failed: name = %s, valid = %v, expected = %v",
+				column.Field.Name, column.Valid, valid[i])
+		}
+	}
+}
+
+func TestArrowReader(t *testing.T) {
+	records, err := readArrowFixture(t, "types.arrows")
+	if err != nil {
+		t.Fatalf("ArrowReader.Next() failed: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("ArrowReader.Next() failed: nRecords = %d", len(records))
+	}
+	var types []string
+	for _, field := range records[0].Schema.Fields {
+		types = append(types, field.Name+":"+field.Type)
+	}
+	expectedTypes := []string{"id:uint32", "name:utf8", "score:float64",
+		"tags:list<utf8>", "time:timestamp[us]", "flag:bool", "int8:int8",
+		"int16:int16", "int64:int64", "uint64:uint64", "float32:float32",
+		"data:binary", "none:null"}
+	if !reflect.DeepEqual(types, expectedTypes) ||
+		(records[0].Schema.Metadata["GROONGA:n_hits"] != "3") {
+		t.Fatalf("ArrowReader.Next() failed: types = %v, metadata = %v",
+			types, records[0].Schema.Metadata)
+	}
+	if (records[0].NRows != 3) || (records[1].NRows != 1) {
+		t.Fatalf("ArrowReader.Next() failed: nRows = %d, %d",
+			records[0].NRows, records[1].NRows)
+	}
+	checkArrowColumns(t, records[0], []interface{}{
+		[]uint32{1, 2, 3},
+		[]string{"a", "", "ccc"},
+		[]float64{0.5, 1.5, 2.5},
+		[][]string{{"x"}, {}, {"y", "z"}},
+		[]time.Time{time.Unix(1, 0), time.Unix(-1, 999000000), time.Unix(0, 0)},
+		[]bool{true, false, true},
+		[]int8{-1, 0, math.MaxInt8},
+		[]int16{-1, 0, math.MaxInt16},
+		[]int64{-1, 0, math.MaxInt64},
+		[]uint64{1, 0, math.MaxUint64},
+		[]float32{0.25, 0, 1.5},
+		[][]byte{{0, 1}, nil, []byte("b")},
+		[]interface{}{nil, nil, nil},
+	}, [][]bool{nil, {true, false, true}, nil, nil, nil, nil, nil, nil, nil,
+		nil, nil, nil, nil})
+	checkArrowColumns(t, records[1], []interface{}{
+		[]uint32{4},
+		[]string{"dddd"},
+		[]float64{-1},
+		[][]string{{}},
+		[]time.Time{time.Unix(-2, 500000000)},
+		[]bool{true},
+		[]int8{math.MinInt8},
+		[]int16{math.MinInt16},
+		[]int64{math.MinInt64},
+		[]uint64{0},
+		[]float32{-0.5},
+		[][]byte{nil},
+		[]interface{}{nil},
+	}, [][]bool{nil, nil, nil, {false}, nil, nil, nil, nil, nil, nil, nil,
+		nil, nil})
+}
+
+func TestArrowReaderDictionary(t *testing.T) {
+	records, err := readArrowFixture(t, "dictionary.arrows")
+	if err != nil {
+		t.Fatalf("ArrowReader.Next() failed: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("ArrowReader.Next() failed: nRecords = %d", len(records))
+	}
+	var types []string
+	for _, field := range records[0].Schema.Fields {
+		types = append(types, field.Name+":"+field.Type)
+	}
+	if !reflect.DeepEqual(types, []string{"tag:utf8", "tags:list<utf8>"}) {
+		t.Fatalf("ArrowReader.Next() failed: types = %v", types)
+	}
+	checkArrowColumns(t, records[0], []interface{}{
+		[]string{"b", "", "a"},
+		[][]string{{"y"}, {}, {"x", "x"}},
+	}, [][]bool{{true, false, true}, nil})
+	// The second record batch follows a delta dictionary batch.
+	checkArrowColumns(t, records[1], []interface{}{
+		[]string{"c"},
+		[][]string{{"x"}},
+	}, [][]bool{nil, nil})
+}
+
+func TestArrowReaderCompression(t *testing.T) {
+	records, err := readArrowFixture(t, "zstd.arrows")
+	if (err == nil) || !strings.Contains(err.Error(), "compression = zstd") ||
+		(len(records) != 0) {
+		t.Fatalf("ArrowReader.Next() succeeded for compression: err = %v", err)
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/groonga/grngo
+
+go 1.21
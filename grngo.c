@@ -1,4 +1,5 @@
 #include "grngo.h"
+#include "_cgo_export.h"
 
 #include <math.h>
 #include <string.h>
@@ -15,10 +16,6 @@
 
 #define GRNGO_MAX_BUILTIN_TYPE_ID GRN_DB_WGS84_GEO_POINT
 
-#define GRNGO_MAX_SHORT_TEXT_LEN 4095
-#define GRNGO_MAX_TEXT_LEN       65535
-#define GRNGO_MAX_LONG_TEXT_LEN  2147484647
-
 #define GRNGO_BOOL_DB_TYPE            grn_bool
 #define GRNGO_INT8_DB_TYPE            int8_t
 #define GRNGO_INT16_DB_TYPE           int16_t
@@ -69,6 +66,16 @@
   (((value) >= 0) && ((value) <= (int64_t)UINT32_MAX))
 #define GRNGO_TEST_UINT64(value)     ((value) >= 0)
 #define GRNGO_TEST_TIME(value)       (1)
+
+/* GRNGO_TEST_U* test uint64_t values. */
+#define GRNGO_TEST_UINT8_U(value)    ((value) <= UINT8_MAX)
+#define GRNGO_TEST_UINT16_U(value)   ((value) <= UINT16_MAX)
+#define GRNGO_TEST_UINT32_U(value)   ((value) <= UINT32_MAX)
+#define GRNGO_TEST_UINT64_U(value)   (1)
+#define GRNGO_TEST_INT8_U(value)     ((value) <= INT8_MAX)
+#define GRNGO_TEST_INT16_U(value)    ((value) <= INT16_MAX)
+#define GRNGO_TEST_INT32_U(value)    ((value) <= INT32_MAX)
+#define GRNGO_TEST_INT64_U(value)    ((value) <= INT64_MAX)
 #define GRNGO_TEST_FLOAT(value)      (!isnan(value))
 #define GRNGO_TEST_SHORT_TEXT(value) \
   (((value).ptr && ((value).size < GRNGO_MAX_SHORT_TEXT_LEN)) ||\
@@ -139,13 +146,14 @@ _grngo_new_db(void) {
   memset(db, 0, sizeof(*db));
   db->ctx = NULL;
   db->obj = NULL;
+  db->parent = NULL;
   db->estr = db->estr_buf;
   return db;
 }
 
 static void
 _grngo_delete_db(grngo_db *db) {
-  if (db->obj) {
+  if (db->obj && !db->parent) {
     grn_obj_close(db->ctx, db->obj);
   }
   if (db->ctx) {
@@ -186,6 +194,21 @@ _grngo_open_db(grngo_db *db, const char *path) {
   return GRN_SUCCESS;
 }
 
+static grn_rc
+_grngo_dup_db(grngo_db *db, grngo_db *parent) {
+  db->ctx = grn_ctx_open(0);
+  if (!db->ctx) {
+    return GRN_NO_MEMORY_AVAILABLE;
+  }
+  grn_rc rc = grn_ctx_use(db->ctx, parent->obj);
+  if (rc != GRN_SUCCESS) {
+    return rc;
+  }
+  db->obj = parent->obj;
+  db->parent = parent;
+  return GRN_SUCCESS;
+}
+
 grn_rc
 grngo_create_db(const char *path, size_t path_len, grngo_db **db) {
   if ((!path && path_len) || !db) {
@@ -246,6 +269,25 @@ grngo_open_db(const char *path, size_t path_len, grngo_db **db) {
   return rc;
 }
 
+grn_rc
+grngo_dup_db(grngo_db *db, grngo_db **new_db) {
+  if (!db || !new_db) {
+    return GRN_INVALID_ARGUMENT;
+  }
+  // Open a DB which shares the database object with the given DB.
+  grngo_db *dup_db = _grngo_new_db();
+  if (!dup_db) {
+    return GRN_NO_MEMORY_AVAILABLE;
+  }
+  grn_rc rc = _grngo_dup_db(dup_db, db->parent ? db->parent : db);
+  if (rc == GRN_SUCCESS) {
+    *new_db = dup_db;
+  } else {
+    _grngo_delete_db(dup_db);
+  }
+  return rc;
+}
+
 void
 grngo_close_db(grngo_db *db) {
   if (db) {
@@ -278,6 +320,53 @@ grngo_recv(grngo_db *db, char **res, unsigned int *res_len) {
   return db->ctx->rc;
 }
 
+// _grngo_recv_chunk passes the output of a command to grngoRecvChunk.
+static void
+_grngo_recv_chunk(grn_ctx *ctx, int flags, void *arg) {
+  char *res;
+  unsigned int res_len;
+  int recv_flags;
+  grn_ctx_recv(ctx, &res, &res_len, &recv_flags);
+  if (res_len != 0) {
+    grngoRecvChunk(*(uintptr_t *)arg, res, res_len);
+  }
+}
+
+grn_rc
+grngo_send_stream(grngo_db *db, const char *cmd, size_t cmd_len,
+                  uintptr_t handle) {
+  if (!db || (!cmd && cmd_len)) {
+    return GRN_INVALID_ARGUMENT;
+  }
+  grn_ctx_recv_handler_set(db->ctx, _grngo_recv_chunk, &handle);
+  grn_rc rc = grn_ctx_send(db->ctx, cmd, cmd_len, 0);
+  // Pass the rest of the output if any.
+  _grngo_recv_chunk(db->ctx, 0, &handle);
+  grn_ctx_recv_handler_set(db->ctx, NULL, NULL);
+  if (rc != GRN_SUCCESS) {
+    return rc;
+  }
+  return db->ctx->rc;
+}
+
+grn_rc
+grngo_get_obj_id(grngo_db *db, const char *name, size_t name_len,
+                 grn_id *id) {
+  if (!db || !name || (name_len == 0) || !id) {
+    return GRN_INVALID_ARGUMENT;
+  }
+  grn_obj *obj = grn_ctx_get(db->ctx, name, name_len);
+  if (!obj) {
+    if (db->ctx->rc != GRN_SUCCESS) {
+      return db->ctx->rc;
+    }
+    return GRN_INVALID_ARGUMENT;
+  }
+  *id = grn_obj_id(db->ctx, obj);
+  grn_obj_unlink(db->ctx, obj);
+  return GRN_SUCCESS;
+}
+
 // -- grngo_table --
 
 static grngo_table *
@@ -369,6 +458,69 @@ grngo_close_table(grngo_table *table) {
   }
 }
 
+grn_bool
+grngo_table_refers(grngo_table *table, grn_id id) {
+  size_t i;
+  for (i = 0; i < table->n_objs; i++) {
+    if (grn_obj_id(table->db->ctx, table->objs[i]) == id) {
+      return GRN_TRUE;
+    }
+  }
+  return GRN_FALSE;
+}
+
+static grn_rc
+_grngo_get_table(grngo_db *db, const char *name, size_t name_len,
+                 grn_obj **table) {
+  grn_ctx *ctx = db->ctx;
+  grn_obj *obj = grn_ctx_get(ctx, name, name_len);
+  if (!obj) {
+    if (ctx->rc != GRN_SUCCESS) {
+      return ctx->rc;
+    }
+    return GRN_INVALID_ARGUMENT;
+  }
+  if (!grn_obj_is_table(ctx, obj)) {
+    grn_obj_unlink(ctx, obj);
+    return GRN_INVALID_ARGUMENT;
+  }
+  *table = obj;
+  return GRN_SUCCESS;
+}
+
+grn_rc
+grngo_remove_table(grngo_db *db, const char *name, size_t name_len) {
+  if (!db || !name || (name_len == 0)) {
+    return GRN_INVALID_ARGUMENT;
+  }
+  grn_obj *table;
+  grn_rc rc = _grngo_get_table(db, name, name_len, &table);
+  if (rc != GRN_SUCCESS) {
+    return rc;
+  }
+  rc = grn_obj_remove(db->ctx, table);
+  if (rc != GRN_SUCCESS) {
+    grn_obj_unlink(db->ctx, table);
+  }
+  return rc;
+}
+
+grn_rc
+grngo_rename_table(grngo_db *db, const char *name, size_t name_len,
+                   const char *new_name, size_t new_name_len) {
+  if (!db || !name || (name_len == 0) || !new_name || (new_name_len == 0)) {
+    return GRN_INVALID_ARGUMENT;
+  }
+  grn_obj *table;
+  grn_rc rc = _grngo_get_table(db, name, name_len, &table);
+  if (rc != GRN_SUCCESS) {
+    return rc;
+  }
+  rc = grn_table_rename(db->ctx, table, new_name, new_name_len);
+  grn_obj_unlink(db->ctx, table);
+  return rc;
+}
+
 static grn_rc
 _grngo_insert_row(grngo_table *table, const void *key, size_t key_size,
                   grn_bool *inserted, grn_id *id) {
@@ -454,6 +606,36 @@ grngo_insert_int(grngo_table *table, int64_t key,
 }
 #undef GRNGO_INSERT_INT_CASE_BLOCK
 
+#define GRNGO_INSERT_UINT_CASE_BLOCK(type)\
+  case GRN_DB_ ## type: {\
+    if (!GRNGO_TEST_ ## type ## _U(key)) {\
+      return GRN_INVALID_ARGUMENT;\
+    }\
+    GRNGO_DB_TYPE(type) tmp_key = (GRNGO_DB_TYPE(type))key;\
+    return _grngo_insert_row(table, &tmp_key, sizeof(tmp_key), inserted, id);\
+  }
+grn_rc
+grngo_insert_uint(grngo_table *table, uint64_t key,
+                  grn_bool *inserted, grn_id *id) {
+  if (!table || !inserted || !id) {
+    return GRN_INVALID_ARGUMENT;
+  }
+  switch (table->key_type) {
+    GRNGO_INSERT_UINT_CASE_BLOCK(INT8)
+    GRNGO_INSERT_UINT_CASE_BLOCK(INT16)
+    GRNGO_INSERT_UINT_CASE_BLOCK(INT32)
+    GRNGO_INSERT_UINT_CASE_BLOCK(INT64)
+    GRNGO_INSERT_UINT_CASE_BLOCK(UINT8)
+    GRNGO_INSERT_UINT_CASE_BLOCK(UINT16)
+    GRNGO_INSERT_UINT_CASE_BLOCK(UINT32)
+    GRNGO_INSERT_UINT_CASE_BLOCK(UINT64)
+    default: {
+      return GRN_INVALID_ARGUMENT;
+    }
+  }
+}
+#undef GRNGO_INSERT_UINT_CASE_BLOCK
+
 grn_rc
 grngo_insert_float(grngo_table *table, double key,
                    grn_bool *inserted, grn_id *id) {
@@ -499,110 +681,502 @@ grngo_insert_geo_point(grngo_table *table, grn_geo_point key,
   return _grngo_insert_row(table, &key, sizeof(key), inserted, id);
 }
 
-// -- grngo_column --
+static grn_rc
+_grngo_find_row(grngo_table *table, size_t depth,
+                const void *key, size_t key_size, grn_id *id) {
+  grn_ctx *ctx = table->db->ctx;
+  size_t i = table->n_objs - 1;
+  grn_id tmp_id = grn_table_get(ctx, table->objs[i], key, key_size);
+  // Resolve table references.
+  while ((tmp_id != GRN_ID_NIL) && (i > depth)) {
+    i--;
+    tmp_id = grn_table_get(ctx, table->objs[i], &tmp_id, sizeof(tmp_id));
+  }
+  if (ctx->rc != GRN_SUCCESS) {
+    return ctx->rc;
+  }
+  *id = tmp_id;
+  return GRN_SUCCESS;
+}
 
-static grngo_column *
-_grngo_new_column(grngo_table *table) {
-  grngo_column *column = (grngo_column *)GRNGO_MALLOC(table->db,
-                                                      sizeof(*column));
-  if (!column) {
+grn_rc
+grngo_find_row(grngo_table *table, const void *key, size_t key_size,
+               grn_id *id) {
+  if (!table || (!key && key_size) || !id) {
+    return GRN_INVALID_ARGUMENT;
+  }
+  if (table->key_type == GRN_DB_VOID) {
+    return GRN_INVALID_ARGUMENT;
+  }
+  return _grngo_find_row(table, 0, key, key_size, id);
+}
+
+grn_rc
+grngo_delete_row(grngo_table *table, grn_id id, grn_bool *deleted) {
+  if (!table || !deleted) {
+    return GRN_INVALID_ARGUMENT;
+  }
+  grn_ctx *ctx = table->db->ctx;
+  *deleted = GRN_FALSE;
+  if ((id == GRN_ID_NIL) ||
+      (grn_table_at(ctx, table->objs[0], id) == GRN_ID_NIL)) {
+    return GRN_SUCCESS;
+  }
+  grn_rc rc = grn_table_delete_by_id(ctx, table->objs[0], id);
+  if (rc != GRN_SUCCESS) {
+    return rc;
+  }
+  *deleted = GRN_TRUE;
+  return GRN_SUCCESS;
+}
+
+grn_rc
+grngo_delete_row_by_key(grngo_table *table, const void *key, size_t key_size,
+                        grn_bool *deleted) {
+  if (!table || (!key && key_size) || !deleted) {
+    return GRN_INVALID_ARGUMENT;
+  }
+  if (table->key_type == GRN_DB_VOID) {
+    return GRN_INVALID_ARGUMENT;
+  }
+  grn_ctx *ctx = table->db->ctx;
+  *deleted = GRN_FALSE;
+  grn_rc rc;
+  if (table->n_objs == 1) {
+    if (grn_table_get(ctx, table->objs[0], key, key_size) == GRN_ID_NIL) {
+      return (ctx->rc != GRN_SUCCESS) ? ctx->rc : GRN_SUCCESS;
+    }
+    rc = grn_table_delete(ctx, table->objs[0], key, key_size);
+  } else {
+    // Resolve table references and delete the record in table->objs[0].
+    grn_id id;
+    rc = _grngo_find_row(table, 0, key, key_size, &id);
+    if ((rc != GRN_SUCCESS) || (id == GRN_ID_NIL)) {
+      return rc;
+    }
+    rc = grn_table_delete_by_id(ctx, table->objs[0], id);
+  }
+  if (rc != GRN_SUCCESS) {
+    return rc;
+  }
+  *deleted = GRN_TRUE;
+  return GRN_SUCCESS;
+}
+
+// -- grngo_cursor --
+
+static grngo_cursor *
+_grngo_new_cursor(grngo_table *table) {
+  grngo_cursor *cursor = (grngo_cursor *)GRNGO_MALLOC(table->db,
+                                                      sizeof(*cursor));
+  if (!cursor) {
     return NULL;
   }
-  memset(column, 0, sizeof(*column));
-  column->db = table->db;
-  column->table = table;
-  column->srcs = NULL;
-  column->src_bufs = NULL;
-  column->text_buf = NULL;
-  column->vector_buf = NULL;
-  return column;
+  memset(cursor, 0, sizeof(*cursor));
+  cursor->table = table;
+  cursor->obj = NULL;
+  return cursor;
 }
 
 static void
-_grngo_delete_column(grngo_column *column) {
-  grn_ctx *ctx = column->db->ctx;
-  if (column->srcs) {
-    size_t i;
-    for (i = 0; i < column->n_srcs; i++) {
-      grn_obj_unlink(ctx, column->srcs[i]);
-    }
-    GRNGO_FREE(column->db, column->srcs);
+_grngo_delete_cursor(grngo_cursor *cursor) {
+  if (cursor->obj) {
+    grn_table_cursor_close(cursor->table->db->ctx, cursor->obj);
   }
-  if (column->src_bufs) {
-    size_t i;
-    for (i = 0; i < column->n_srcs; i++) {
-      if (column->src_bufs[i]) {
-        grn_obj_close(ctx, column->src_bufs[i]);
-      }
-    }
-    GRNGO_FREE(column->db, column->src_bufs);
+  GRNGO_FREE(cursor->table->db, cursor);
+}
+
+// _grngo_resolve_bound converts a bound for the last table in table->objs
+// into a bound for table->objs[0].
+static grn_rc
+_grngo_resolve_bound(grngo_table *table, const void **bound,
+                     size_t *bound_size, grn_id *id) {
+  if (!*bound || (table->n_objs == 1)) {
+    return GRN_SUCCESS;
   }
-  if (column->text_buf) {
-    grn_obj_close(ctx, column->text_buf);
+  grn_rc rc = _grngo_find_row(table, 1, *bound, *bound_size, id);
+  if (rc != GRN_SUCCESS) {
+    return rc;
   }
-  if (column->vector_buf) {
-    grn_obj_close(ctx, column->vector_buf);
+  if (*id == GRN_ID_NIL) {
+    return GRN_INVALID_ARGUMENT;
   }
-  GRNGO_FREE(column->db, column);
+  *bound = id;
+  *bound_size = sizeof(*id);
+  return GRN_SUCCESS;
 }
 
 static grn_rc
-_grngo_open_src(grngo_db *db, grn_obj *table,
-                const char *name, size_t name_len, grn_obj **src) {
-  if ((name_len == GRN_COLUMN_NAME_KEY_LEN) &&
-      !memcmp(name, GRN_COLUMN_NAME_KEY, name_len) &&
-      (table->header.type == GRN_TABLE_NO_KEY)) {
+_grngo_open_cursor(grngo_cursor *cursor, const void *min, size_t min_size,
+                   const void *max, size_t max_size,
+                   int offset, int limit, int flags) {
+  grngo_table *table = cursor->table;
+  grn_ctx *ctx = table->db->ctx;
+  if ((flags & GRN_CURSOR_PREFIX) && (table->n_objs != 1)) {
     return GRN_INVALID_ARGUMENT;
   }
-  grn_obj *new_src;
-  if ((name_len == GRN_COLUMN_NAME_VALUE_LEN) &&
-      !memcmp(name, GRN_COLUMN_NAME_VALUE, name_len)) {
-    new_src = grn_ctx_at(db->ctx, grn_obj_id(db->ctx, table));
-  } else {
-    new_src = grn_obj_column(db->ctx, table, name, name_len);
+  grn_id min_id, max_id;
+  grn_rc rc = _grngo_resolve_bound(table, &min, &min_size, &min_id);
+  if (rc != GRN_SUCCESS) {
+    return rc;
   }
-  if (!new_src) {
-    if (db->ctx->rc != GRN_SUCCESS) {
-      return db->ctx->rc;
+  rc = _grngo_resolve_bound(table, &max, &max_size, &max_id);
+  if (rc != GRN_SUCCESS) {
+    return rc;
+  }
+  cursor->obj = grn_table_cursor_open(ctx, table->objs[0], min, min_size,
+                                      max, max_size, offset, limit, flags);
+  if (!cursor->obj) {
+    if (ctx->rc != GRN_SUCCESS) {
+      return ctx->rc;
     }
     return GRN_UNKNOWN_ERROR;
   }
-  *src = new_src;
   return GRN_SUCCESS;
 }
 
-static grn_rc
-_grngo_push_src(grngo_column *column, grn_obj *table,
-                const char *name, size_t name_len, grn_obj **next_table) {
-  grn_obj *src;
-  grn_rc rc = _grngo_open_src(column->db, table, name, name_len, &src);
-  if (rc != GRN_SUCCESS) {
-    return rc;
+grn_rc
+grngo_open_cursor(grngo_table *table, const void *min, size_t min_size,
+                  const void *max, size_t max_size,
+                  int offset, int limit, int flags, grngo_cursor **cursor) {
+  if (!table || (!min && min_size) || (!max && max_size) || !cursor) {
+    return GRN_INVALID_ARGUMENT;
   }
-  grn_ctx *ctx = column->db->ctx;
-  switch (src->header.type) {
-    case GRN_COLUMN_VAR_SIZE: {
-      grn_obj_flags type = src->header.flags & GRN_OBJ_COLUMN_TYPE_MASK;
-      if (type == GRN_OBJ_COLUMN_VECTOR) {
-        column->dimension++;
-      }
-      // Fallthrough.
+  grngo_cursor *new_cursor = _grngo_new_cursor(table);
+  grn_rc rc = new_cursor ? GRN_SUCCESS : GRN_NO_MEMORY_AVAILABLE;
+  if (rc == GRN_SUCCESS) {
+    rc = _grngo_open_cursor(new_cursor, min, min_size, max, max_size,
+                            offset, limit, flags);
+    if (rc == GRN_SUCCESS) {
+      *cursor = new_cursor;
+    } else {
+      _grngo_delete_cursor(new_cursor);
     }
-    case GRN_TABLE_HASH_KEY: // _value.
-    case GRN_TABLE_PAT_KEY:  // _value.
-    case GRN_TABLE_NO_KEY:   // _value.
-    case GRN_ACCESSOR:       // _id or _key.
-    case GRN_COLUMN_FIX_SIZE: {
-      grn_id range = grn_obj_get_range(ctx, src);
-      if (range == GRN_DB_VOID) {
-        grn_obj_unlink(ctx, src);
-        return GRN_INVALID_ARGUMENT;
-      } else if (range <= GRNGO_MAX_BUILTIN_TYPE_ID) {
-        column->value_type = range;
-        *next_table = NULL;
-      } else {
-        grn_obj *range_obj = grn_ctx_at(ctx, range);
-        if (!grn_obj_is_table(ctx, range_obj)) {
+  }
+  return rc;
+}
+
+void
+grngo_close_cursor(grngo_cursor *cursor) {
+  if (cursor) {
+    _grngo_delete_cursor(cursor);
+  }
+}
+
+grn_rc
+grngo_cursor_next(grngo_cursor *cursor, grn_id *id) {
+  if (!cursor || !id) {
+    return GRN_INVALID_ARGUMENT;
+  }
+  grn_ctx *ctx = cursor->table->db->ctx;
+  *id = grn_table_cursor_next(ctx, cursor->obj);
+  if ((*id == GRN_ID_NIL) && (ctx->rc != GRN_SUCCESS)) {
+    return ctx->rc;
+  }
+  return GRN_SUCCESS;
+}
+
+// -- grngo_search --
+
+// _grngo_open_index opens an index column whose source is table->objs[0].
+static grn_rc
+_grngo_open_index(grngo_table *table, const char *name, size_t name_len,
+                  grn_obj **index) {
+  grn_ctx *ctx = table->db->ctx;
+  grn_obj *obj = grn_ctx_get(ctx, name, name_len);
+  if (!obj) {
+    if (ctx->rc != GRN_SUCCESS) {
+      return ctx->rc;
+    }
+    return GRN_INVALID_ARGUMENT;
+  }
+  if ((obj->header.type != GRN_COLUMN_INDEX) ||
+      (grn_obj_get_range(ctx, obj) != grn_obj_id(ctx, table->objs[0]))) {
+    grn_obj_unlink(ctx, obj);
+    return GRN_INVALID_ARGUMENT;
+  }
+  *index = obj;
+  return GRN_SUCCESS;
+}
+
+// _grngo_sort_hits sorts a result table by score in descending order and
+// then by ID in ascending order, and keeps hits in [offset, offset + limit).
+static grn_rc
+_grngo_sort_hits(grngo_table *table, grn_obj *res, int offset, int limit,
+                 grn_obj **sorted) {
+  grn_ctx *ctx = table->db->ctx;
+  grn_obj *new_sorted = grn_table_create(ctx, NULL, 0, NULL,
+                                         GRN_OBJ_TABLE_NO_KEY, NULL, res);
+  if (!new_sorted) {
+    return (ctx->rc != GRN_SUCCESS) ? ctx->rc : GRN_UNKNOWN_ERROR;
+  }
+  int size = (int)grn_table_size(ctx, res);
+  if ((offset >= size) || (limit == 0)) {
+    *sorted = new_sorted;
+    return GRN_SUCCESS;
+  }
+  grn_table_sort_key keys[2];
+  memset(keys, 0, sizeof(keys));
+  keys[0].key = grn_obj_column(ctx, res, GRN_COLUMN_NAME_SCORE,
+                               GRN_COLUMN_NAME_SCORE_LEN);
+  keys[0].flags = GRN_TABLE_SORT_DESC;
+  // The key of res is the ID of a hit.
+  keys[1].key = grn_obj_column(ctx, res, GRN_COLUMN_NAME_KEY,
+                               GRN_COLUMN_NAME_KEY_LEN);
+  keys[1].flags = GRN_TABLE_SORT_ASC;
+  grn_rc rc = GRN_SUCCESS;
+  if (!keys[0].key || !keys[1].key) {
+    rc = (ctx->rc != GRN_SUCCESS) ? ctx->rc : GRN_UNKNOWN_ERROR;
+  } else {
+    grn_table_sort(ctx, res, offset, limit, new_sorted, keys, 2);
+    rc = ctx->rc;
+  }
+  int i;
+  for (i = 0; i < 2; i++) {
+    if (keys[i].key) {
+      grn_obj_unlink(ctx, keys[i].key);
+    }
+  }
+  if (rc != GRN_SUCCESS) {
+    grn_obj_close(ctx, new_sorted);
+    return rc;
+  }
+  *sorted = new_sorted;
+  return GRN_SUCCESS;
+}
+
+// _grngo_get_hits copies IDs and scores in a sorted result table.
+static grn_rc
+_grngo_get_hits(grngo_table *table, grn_obj *res, grn_obj *sorted,
+                grngo_hit **hits, size_t *n_hits) {
+  grn_ctx *ctx = table->db->ctx;
+  size_t size = grn_table_size(ctx, sorted);
+  grngo_hit *new_hits = NULL;
+  if (size != 0) {
+    new_hits = (grngo_hit *)GRNGO_MALLOC(table->db, sizeof(grngo_hit) * size);
+    if (!new_hits) {
+      return GRN_NO_MEMORY_AVAILABLE;
+    }
+  }
+  grn_obj *score = grn_obj_column(ctx, res, GRN_COLUMN_NAME_SCORE,
+                                  GRN_COLUMN_NAME_SCORE_LEN);
+  grn_table_cursor *cursor = grn_table_cursor_open(ctx, sorted, NULL, 0,
+                                                   NULL, 0, 0, -1, 0);
+  if (!score || !cursor) {
+    grn_rc rc = (ctx->rc != GRN_SUCCESS) ? ctx->rc : GRN_UNKNOWN_ERROR;
+    if (cursor) {
+      grn_table_cursor_close(ctx, cursor);
+    }
+    if (score) {
+      grn_obj_unlink(ctx, score);
+    }
+    GRNGO_FREE(table->db, new_hits);
+    return rc;
+  }
+  grn_obj buf;
+  GRN_VOID_INIT(&buf);
+  size_t i = 0;
+  while ((i < size) &&
+         (grn_table_cursor_next(ctx, cursor) != GRN_ID_NIL)) {
+    // A record of sorted refers to a record of res.
+    void *value;
+    grn_table_cursor_get_value(ctx, cursor, &value);
+    grn_id res_id = *(grn_id *)value;
+    grn_table_get_key(ctx, res, res_id, &new_hits[i].id, sizeof(grn_id));
+    GRN_BULK_REWIND(&buf);
+    grn_obj_get_value(ctx, score, res_id, &buf);
+    if (buf.header.domain == GRN_DB_FLOAT) {
+      new_hits[i].score = GRN_FLOAT_VALUE(&buf);
+    } else {
+      new_hits[i].score = GRN_INT32_VALUE(&buf);
+    }
+    i++;
+  }
+  GRN_OBJ_FIN(ctx, &buf);
+  grn_table_cursor_close(ctx, cursor);
+  grn_obj_unlink(ctx, score);
+  *hits = new_hits;
+  *n_hits = i;
+  return GRN_SUCCESS;
+}
+
+grn_rc
+grngo_search(grngo_table *table, const char *index_name, size_t name_len,
+             const char *query, size_t query_len, int mode,
+             int similarity_threshold, int max_interval,
+             int offset, int limit, grngo_hit **hits, size_t *n_hits) {
+  if (!table || !index_name || (name_len == 0) || (!query && query_len) ||
+      (offset < 0) || (limit < -1) || !hits || !n_hits) {
+    return GRN_INVALID_ARGUMENT;
+  }
+  switch (mode) {
+    case GRN_OP_EXACT:
+    case GRN_OP_PREFIX:
+    case GRN_OP_SUFFIX:
+    case GRN_OP_NEAR:
+    case GRN_OP_SIMILAR:
+    case GRN_OP_TERM_EXTRACT: {
+      break;
+    }
+    default: {
+      return GRN_INVALID_ARGUMENT;
+    }
+  }
+  grn_ctx *ctx = table->db->ctx;
+  grn_obj *index;
+  grn_rc rc = _grngo_open_index(table, index_name, name_len, &index);
+  if (rc != GRN_SUCCESS) {
+    return rc;
+  }
+  grn_obj *res = grn_table_create(ctx, NULL, 0, NULL,
+                                  GRN_OBJ_TABLE_HASH_KEY | GRN_OBJ_WITH_SUBREC,
+                                  table->objs[0], NULL);
+  if (!res) {
+    grn_obj_unlink(ctx, index);
+    return (ctx->rc != GRN_SUCCESS) ? ctx->rc : GRN_UNKNOWN_ERROR;
+  }
+  grn_obj query_buf;
+  GRN_TEXT_INIT(&query_buf, 0);
+  rc = grn_bulk_write(ctx, &query_buf, query, query_len);
+  if (rc == GRN_SUCCESS) {
+    grn_search_optarg optarg;
+    memset(&optarg, 0, sizeof(optarg));
+    optarg.mode = (grn_operator)mode;
+    optarg.similarity_threshold = similarity_threshold;
+    optarg.max_interval = max_interval;
+    rc = grn_obj_search(ctx, index, &query_buf, res, GRN_OP_OR, &optarg);
+  }
+  grn_obj *sorted = NULL;
+  if (rc == GRN_SUCCESS) {
+    rc = _grngo_sort_hits(table, res, offset, limit, &sorted);
+  }
+  if (rc == GRN_SUCCESS) {
+    rc = _grngo_get_hits(table, res, sorted, hits, n_hits);
+  }
+  if (sorted) {
+    grn_obj_close(ctx, sorted);
+  }
+  GRN_OBJ_FIN(ctx, &query_buf);
+  grn_obj_close(ctx, res);
+  grn_obj_unlink(ctx, index);
+  return rc;
+}
+
+void
+grngo_free_hits(grngo_table *table, grngo_hit *hits) {
+  if (table) {
+    GRNGO_FREE(table->db, hits);
+  }
+}
+
+// -- grngo_column --
+
+static grngo_column *
+_grngo_new_column(grngo_table *table) {
+  grngo_column *column = (grngo_column *)GRNGO_MALLOC(table->db,
+                                                      sizeof(*column));
+  if (!column) {
+    return NULL;
+  }
+  memset(column, 0, sizeof(*column));
+  column->db = table->db;
+  column->table = table;
+  column->srcs = NULL;
+  column->src_bufs = NULL;
+  column->text_buf = NULL;
+  column->vector_buf = NULL;
+  column->weight_buf = NULL;
+  return column;
+}
+
+static void
+_grngo_delete_column(grngo_column *column) {
+  grn_ctx *ctx = column->db->ctx;
+  if (column->srcs) {
+    size_t i;
+    for (i = 0; i < column->n_srcs; i++) {
+      grn_obj_unlink(ctx, column->srcs[i]);
+    }
+    GRNGO_FREE(column->db, column->srcs);
+  }
+  if (column->src_bufs) {
+    size_t i;
+    for (i = 0; i < column->n_srcs; i++) {
+      if (column->src_bufs[i]) {
+        grn_obj_close(ctx, column->src_bufs[i]);
+      }
+    }
+    GRNGO_FREE(column->db, column->src_bufs);
+  }
+  if (column->text_buf) {
+    grn_obj_close(ctx, column->text_buf);
+  }
+  if (column->vector_buf) {
+    grn_obj_close(ctx, column->vector_buf);
+  }
+  if (column->weight_buf) {
+    grn_obj_close(ctx, column->weight_buf);
+  }
+  GRNGO_FREE(column->db, column);
+}
+
+static grn_rc
+_grngo_open_src(grngo_db *db, grn_obj *table,
+                const char *name, size_t name_len, grn_obj **src) {
+  if ((name_len == GRN_COLUMN_NAME_KEY_LEN) &&
+      !memcmp(name, GRN_COLUMN_NAME_KEY, name_len) &&
+      (table->header.type == GRN_TABLE_NO_KEY)) {
+    return GRN_INVALID_ARGUMENT;
+  }
+  grn_obj *new_src;
+  if ((name_len == GRN_COLUMN_NAME_VALUE_LEN) &&
+      !memcmp(name, GRN_COLUMN_NAME_VALUE, name_len)) {
+    new_src = grn_ctx_at(db->ctx, grn_obj_id(db->ctx, table));
+  } else {
+    new_src = grn_obj_column(db->ctx, table, name, name_len);
+  }
+  if (!new_src) {
+    if (db->ctx->rc != GRN_SUCCESS) {
+      return db->ctx->rc;
+    }
+    return GRN_UNKNOWN_ERROR;
+  }
+  *src = new_src;
+  return GRN_SUCCESS;
+}
+
+static grn_rc
+_grngo_push_src(grngo_column *column, grn_obj *table,
+                const char *name, size_t name_len, grn_obj **next_table) {
+  grn_obj *src;
+  grn_rc rc = _grngo_open_src(column->db, table, name, name_len, &src);
+  if (rc != GRN_SUCCESS) {
+    return rc;
+  }
+  grn_ctx *ctx = column->db->ctx;
+  switch (src->header.type) {
+    case GRN_COLUMN_VAR_SIZE: {
+      grn_obj_flags type = src->header.flags & GRN_OBJ_COLUMN_TYPE_MASK;
+      if (type == GRN_OBJ_COLUMN_VECTOR) {
+        column->dimension++;
+      }
+      // Fallthrough.
+    }
+    case GRN_TABLE_HASH_KEY: // _value.
+    case GRN_TABLE_PAT_KEY:  // _value.
+    case GRN_TABLE_NO_KEY:   // _value.
+    case GRN_ACCESSOR:       // _id or _key.
+    case GRN_COLUMN_FIX_SIZE: {
+      grn_id range = grn_obj_get_range(ctx, src);
+      if (range == GRN_DB_VOID) {
+        grn_obj_unlink(ctx, src);
+        return GRN_INVALID_ARGUMENT;
+      } else if (range <= GRNGO_MAX_BUILTIN_TYPE_ID) {
+        column->value_type = range;
+        *next_table = NULL;
+      } else {
+        grn_obj *range_obj = grn_ctx_at(ctx, range);
+        if (!grn_obj_is_table(ctx, range_obj)) {
           grn_obj_unlink(ctx, range_obj);
           return GRN_INVALID_ARGUMENT;
         }
@@ -702,6 +1276,16 @@ _grngo_open_bufs(grngo_column *column) {
       return GRN_UNKNOWN_ERROR;
     }
   }
+  // Open a buffer for weights.
+  if (column->with_weight) {
+    column->weight_buf = grn_obj_open(ctx, GRN_BULK, 0, GRN_DB_UINT32);
+    if (!column->weight_buf) {
+      if (ctx->rc != GRN_SUCCESS) {
+        return ctx->rc;
+      }
+      return GRN_UNKNOWN_ERROR;
+    }
+  }
   return GRN_SUCCESS;
 }
 
@@ -749,6 +1333,31 @@ _grngo_open_column(grngo_table *table, grngo_column *column,
       }
     }
   }
+  // Check whether the column is a weight vector or not.
+  // Groonga stores an element of a weight uvector in 32 bits, so weights
+  // of 64-bit values are not supported.
+  if ((column->n_srcs == 1) && _grngo_is_vector(column->srcs[0]) &&
+      (column->srcs[0]->header.flags & GRN_OBJ_WITH_WEIGHT)) {
+    switch (column->value_type) {
+      case GRN_DB_VOID: // A reference vector, whose _key is not resolved yet.
+      case GRN_DB_BOOL:
+      case GRN_DB_INT8:
+      case GRN_DB_INT16:
+      case GRN_DB_INT32:
+      case GRN_DB_UINT8:
+      case GRN_DB_UINT16:
+      case GRN_DB_UINT32:
+      case GRN_DB_SHORT_TEXT:
+      case GRN_DB_TEXT:
+      case GRN_DB_LONG_TEXT: {
+        column->with_weight = GRN_TRUE;
+        break;
+      }
+      default: {
+        break;
+      }
+    }
+  }
   // Resolve the _key chain if _key is table reference.
   while (owner) {
     grn_obj *new_owner;
@@ -789,6 +1398,87 @@ grngo_close_column(grngo_column *column) {
   }
 }
 
+grn_bool
+grngo_column_refers(grngo_column *column, grn_id id) {
+  if (grngo_table_refers(column->table, id)) {
+    return GRN_TRUE;
+  }
+  grn_ctx *ctx = column->db->ctx;
+  size_t i;
+  for (i = 0; i < column->n_srcs; i++) {
+    grn_obj *src = column->srcs[i];
+    if (grn_obj_get_range(ctx, src) == id) {
+      return GRN_TRUE;
+    }
+    // An accessor (_id or _key) is not a persistent object.
+    if ((src->header.type != GRN_ACCESSOR) &&
+        ((grn_obj_id(ctx, src) == id) || (src->header.domain == id))) {
+      return GRN_TRUE;
+    }
+  }
+  return GRN_FALSE;
+}
+
+static grn_rc
+_grngo_get_column(grngo_table *table, const char *name, size_t name_len,
+                  grn_obj **column) {
+  grn_ctx *ctx = table->db->ctx;
+  grn_obj *obj = grn_obj_column(ctx, table->objs[0], name, name_len);
+  if (!obj) {
+    if (ctx->rc != GRN_SUCCESS) {
+      return ctx->rc;
+    }
+    return GRN_INVALID_ARGUMENT;
+  }
+  switch (obj->header.type) {
+    case GRN_COLUMN_FIX_SIZE:
+    case GRN_COLUMN_VAR_SIZE:
+    case GRN_COLUMN_INDEX: {
+      *column = obj;
+      return GRN_SUCCESS;
+    }
+    default: {
+      // Pseudo columns, such as _id and _key, cannot be removed or renamed.
+      grn_obj_unlink(ctx, obj);
+      return GRN_INVALID_ARGUMENT;
+    }
+  }
+}
+
+grn_rc
+grngo_remove_column(grngo_table *table, const char *name, size_t name_len) {
+  if (!table || !name || (name_len == 0)) {
+    return GRN_INVALID_ARGUMENT;
+  }
+  grn_obj *column;
+  grn_rc rc = _grngo_get_column(table, name, name_len, &column);
+  if (rc != GRN_SUCCESS) {
+    return rc;
+  }
+  rc = grn_obj_remove(table->db->ctx, column);
+  if (rc != GRN_SUCCESS) {
+    grn_obj_unlink(table->db->ctx, column);
+  }
+  return rc;
+}
+
+grn_rc
+grngo_rename_column(grngo_table *table, const char *name, size_t name_len,
+                    const char *new_name, size_t new_name_len) {
+  if (!table || !name || (name_len == 0) ||
+      !new_name || (new_name_len == 0)) {
+    return GRN_INVALID_ARGUMENT;
+  }
+  grn_obj *column;
+  grn_rc rc = _grngo_get_column(table, name, name_len, &column);
+  if (rc != GRN_SUCCESS) {
+    return rc;
+  }
+  rc = grn_column_rename(table->db->ctx, column, new_name, new_name_len);
+  grn_obj_unlink(table->db->ctx, column);
+  return rc;
+}
+
 grn_rc
 grngo_set_bool(grngo_column *column, grn_id id, grn_bool value) {
   if (!column || !column->writable || !GRNGO_TEST_BOOL(value)) {
@@ -851,6 +1541,48 @@ grngo_set_int(grngo_column *column, grn_id id, int64_t value) {
 }
 #undef GRNGO_SET_INT_CASE_BLOCK
 
+#define GRNGO_SET_UINT_CASE_BLOCK(type)\
+  case GRN_DB_ ## type: {\
+    if (!GRNGO_TEST_ ## type ## _U(value)) {\
+      return GRN_INVALID_ARGUMENT;\
+    }\
+    GRN_ ## type ## _INIT(&obj, 0);\
+    GRNGO_DB_TYPE(type) db_value = (GRNGO_DB_TYPE(type))value;\
+    rc = grn_bulk_write(ctx, &obj, (const char *)&db_value, sizeof(db_value));\
+    break;\
+  }
+grn_rc
+grngo_set_uint(grngo_column *column, grn_id id, uint64_t value) {
+  if (!column || !column->writable) {
+    return GRN_INVALID_ARGUMENT;
+  }
+  grn_ctx *ctx = column->db->ctx;
+  if (grn_table_at(ctx, column->table->objs[0], id) == GRN_ID_NIL) {
+    return GRN_INVALID_ARGUMENT;
+  }
+  grn_obj obj;
+  grn_rc rc;
+  switch (column->value_type) {
+    GRNGO_SET_UINT_CASE_BLOCK(INT8)
+    GRNGO_SET_UINT_CASE_BLOCK(INT16)
+    GRNGO_SET_UINT_CASE_BLOCK(INT32)
+    GRNGO_SET_UINT_CASE_BLOCK(INT64)
+    GRNGO_SET_UINT_CASE_BLOCK(UINT8)
+    GRNGO_SET_UINT_CASE_BLOCK(UINT16)
+    GRNGO_SET_UINT_CASE_BLOCK(UINT32)
+    GRNGO_SET_UINT_CASE_BLOCK(UINT64)
+    default: {
+      return GRN_INVALID_ARGUMENT;
+    }
+  }
+  if (rc == GRN_SUCCESS) {
+    rc = grn_obj_set_value(ctx, column->srcs[0], id, &obj, GRN_OBJ_SET);
+  }
+  GRN_OBJ_FIN(ctx, &obj);
+  return rc;
+}
+#undef GRNGO_SET_UINT_CASE_BLOCK
+
 grn_rc
 grngo_set_float(grngo_column *column, grn_id id, double value) {
   if (!column || !column->writable || !GRNGO_TEST_FLOAT(value)) {
@@ -945,21 +1677,74 @@ grngo_set_bool_vector(grngo_column *column, grn_id id, grngo_vector value) {
   if (grn_table_at(ctx, column->table->objs[0], id) == GRN_ID_NIL) {
     return GRN_INVALID_ARGUMENT;
   }
-  grn_obj obj;
-  GRN_BOOL_INIT(&obj, GRN_OBJ_VECTOR);
-  grn_rc rc = grn_bulk_write(ctx, &obj, (const char *)value.ptr,
-                             sizeof(grn_bool) * value.size);
+  grn_obj obj;
+  GRN_BOOL_INIT(&obj, GRN_OBJ_VECTOR);
+  grn_rc rc = grn_bulk_write(ctx, &obj, (const char *)value.ptr,
+                             sizeof(grn_bool) * value.size);
+  if (rc == GRN_SUCCESS) {
+    rc = grn_obj_set_value(ctx, column->srcs[0], id, &obj, GRN_OBJ_SET);
+  }
+  GRN_OBJ_FIN(ctx, &obj);
+  return rc;
+}
+
+#define GRNGO_SET_INT_VECTOR_CASE_BLOCK(type)\
+  case GRN_DB_ ## type: {\
+    for (i = 0; i < value.size; i++) {\
+      if (!GRNGO_TEST_ ## type(values[i])) {\
+        return GRN_INVALID_ARGUMENT;\
+      }\
+    }\
+    GRN_ ## type ## _INIT(&obj, GRN_OBJ_VECTOR);\
+    rc = grn_bulk_space(ctx, &obj, sizeof(GRNGO_DB_TYPE(type)) * value.size);\
+    if (rc != GRN_SUCCESS) {\
+      break;\
+    }\
+    GRNGO_DB_TYPE(type) *head = (GRNGO_DB_TYPE(type) *)GRN_BULK_HEAD(&obj);\
+    for (i = 0; i < value.size; i++) {\
+      head[i] = (GRNGO_DB_TYPE(type))values[i];\
+    }\
+    break;\
+  }
+grn_rc
+grngo_set_int_vector(grngo_column *column, grn_id id, grngo_vector value) {
+  if (!column || !column->writable || !GRNGO_TEST_VECTOR(value)) {
+    return GRN_INVALID_ARGUMENT;
+  }
+  grn_ctx *ctx = column->db->ctx;
+  if (grn_table_at(ctx, column->table->objs[0], id) == GRN_ID_NIL) {
+    return GRN_INVALID_ARGUMENT;
+  }
+  grn_obj obj;
+  size_t i;
+  const int64_t *values = (const int64_t *)value.ptr;
+  grn_rc rc = GRN_SUCCESS;
+  switch (column->value_type) {
+    GRNGO_SET_INT_VECTOR_CASE_BLOCK(INT8)
+    GRNGO_SET_INT_VECTOR_CASE_BLOCK(INT16)
+    GRNGO_SET_INT_VECTOR_CASE_BLOCK(INT32)
+    GRNGO_SET_INT_VECTOR_CASE_BLOCK(INT64)
+    GRNGO_SET_INT_VECTOR_CASE_BLOCK(UINT8)
+    GRNGO_SET_INT_VECTOR_CASE_BLOCK(UINT16)
+    GRNGO_SET_INT_VECTOR_CASE_BLOCK(UINT32)
+    GRNGO_SET_INT_VECTOR_CASE_BLOCK(UINT64)
+    GRNGO_SET_INT_VECTOR_CASE_BLOCK(TIME)
+    default: {
+      return GRN_INVALID_ARGUMENT;
+    }
+  }
   if (rc == GRN_SUCCESS) {
     rc = grn_obj_set_value(ctx, column->srcs[0], id, &obj, GRN_OBJ_SET);
   }
   GRN_OBJ_FIN(ctx, &obj);
   return rc;
 }
+#undef GRNGO_SET_INT_VECTOR_CASE_BLOCK
 
-#define GRNGO_SET_INT_VECTOR_CASE_BLOCK(type)\
+#define GRNGO_SET_UINT_VECTOR_CASE_BLOCK(type)\
   case GRN_DB_ ## type: {\
     for (i = 0; i < value.size; i++) {\
-      if (!GRNGO_TEST_ ## type(values[i])) {\
+      if (!GRNGO_TEST_ ## type ## _U(values[i])) {\
         return GRN_INVALID_ARGUMENT;\
       }\
     }\
@@ -975,7 +1760,7 @@ grngo_set_bool_vector(grngo_column *column, grn_id id, grngo_vector value) {
     break;\
   }
 grn_rc
-grngo_set_int_vector(grngo_column *column, grn_id id, grngo_vector value) {
+grngo_set_uint_vector(grngo_column *column, grn_id id, grngo_vector value) {
   if (!column || !column->writable || !GRNGO_TEST_VECTOR(value)) {
     return GRN_INVALID_ARGUMENT;
   }
@@ -985,18 +1770,17 @@ grngo_set_int_vector(grngo_column *column, grn_id id, grngo_vector value) {
   }
   grn_obj obj;
   size_t i;
-  const int64_t *values = (const int64_t *)value.ptr;
+  const uint64_t *values = (const uint64_t *)value.ptr;
   grn_rc rc = GRN_SUCCESS;
   switch (column->value_type) {
-    GRNGO_SET_INT_VECTOR_CASE_BLOCK(INT8)
-    GRNGO_SET_INT_VECTOR_CASE_BLOCK(INT16)
-    GRNGO_SET_INT_VECTOR_CASE_BLOCK(INT32)
-    GRNGO_SET_INT_VECTOR_CASE_BLOCK(INT64)
-    GRNGO_SET_INT_VECTOR_CASE_BLOCK(UINT8)
-    GRNGO_SET_INT_VECTOR_CASE_BLOCK(UINT16)
-    GRNGO_SET_INT_VECTOR_CASE_BLOCK(UINT32)
-    GRNGO_SET_INT_VECTOR_CASE_BLOCK(UINT64)
-    GRNGO_SET_INT_VECTOR_CASE_BLOCK(TIME)
+    GRNGO_SET_UINT_VECTOR_CASE_BLOCK(INT8)
+    GRNGO_SET_UINT_VECTOR_CASE_BLOCK(INT16)
+    GRNGO_SET_UINT_VECTOR_CASE_BLOCK(INT32)
+    GRNGO_SET_UINT_VECTOR_CASE_BLOCK(INT64)
+    GRNGO_SET_UINT_VECTOR_CASE_BLOCK(UINT8)
+    GRNGO_SET_UINT_VECTOR_CASE_BLOCK(UINT16)
+    GRNGO_SET_UINT_VECTOR_CASE_BLOCK(UINT32)
+    GRNGO_SET_UINT_VECTOR_CASE_BLOCK(UINT64)
     default: {
       return GRN_INVALID_ARGUMENT;
     }
@@ -1007,7 +1791,7 @@ grngo_set_int_vector(grngo_column *column, grn_id id, grngo_vector value) {
   GRN_OBJ_FIN(ctx, &obj);
   return rc;
 }
-#undef GRNGO_SET_INT_VECTOR_CASE_BLOCK
+#undef GRNGO_SET_UINT_VECTOR_CASE_BLOCK
 
 grn_rc
 grngo_set_float_vector(grngo_column *column, grn_id id, grngo_vector value) {
@@ -1073,6 +1857,63 @@ grngo_set_text_vector(grngo_column *column, grn_id id, grngo_vector value) {
   GRN_OBJ_FIN(ctx, &obj);
   return rc;
 }
+
+#define GRNGO_SET_WEIGHTED_VECTOR_CASE_BLOCK(type)\
+  case GRN_DB_ ## type: {\
+    for (i = 0; i < value.size; i++) {\
+      if (!values[i].ptr ||\
+          (values[i].size != sizeof(GRNGO_DB_TYPE(type)))) {\
+        return GRN_INVALID_ARGUMENT;\
+      }\
+    }\
+    GRN_OBJ_INIT(&obj, GRN_VECTOR, 0, GRN_DB_ ## type);\
+    break;\
+  }
+grn_rc
+grngo_set_weighted_vector(grngo_column *column, grn_id id,
+                          grngo_vector value) {
+  if (!column || !column->writable || !column->with_weight ||
+      (column->n_srcs != 1) || !GRNGO_TEST_VECTOR(value)) {
+    return GRN_INVALID_ARGUMENT;
+  }
+  grn_ctx *ctx = column->db->ctx;
+  if (grn_table_at(ctx, column->table->objs[0], id) == GRN_ID_NIL) {
+    return GRN_INVALID_ARGUMENT;
+  }
+  grn_obj obj;
+  size_t i;
+  const grngo_weighted_text *values = (const grngo_weighted_text *)value.ptr;
+  switch (column->value_type) {
+    GRNGO_SET_WEIGHTED_VECTOR_CASE_BLOCK(BOOL)
+    GRNGO_SET_WEIGHTED_VECTOR_CASE_BLOCK(INT8)
+    GRNGO_SET_WEIGHTED_VECTOR_CASE_BLOCK(INT16)
+    GRNGO_SET_WEIGHTED_VECTOR_CASE_BLOCK(INT32)
+    GRNGO_SET_WEIGHTED_VECTOR_CASE_BLOCK(UINT8)
+    GRNGO_SET_WEIGHTED_VECTOR_CASE_BLOCK(UINT16)
+    GRNGO_SET_WEIGHTED_VECTOR_CASE_BLOCK(UINT32)
+    GRNGO_SET_TEXT_VECTOR_CASE_BLOCK(SHORT_TEXT)
+    GRNGO_SET_TEXT_VECTOR_CASE_BLOCK(TEXT)
+    GRNGO_SET_TEXT_VECTOR_CASE_BLOCK(LONG_TEXT)
+    default: {
+      return GRN_UNKNOWN_ERROR;
+    }
+  }
+  grn_rc rc = GRN_SUCCESS;
+  for (i = 0; i < value.size; i++) {
+    rc = grn_vector_add_element(ctx, &obj, (const char *)values[i].ptr,
+                                values[i].size, values[i].weight,
+                                obj.header.domain);
+    if (rc != GRN_SUCCESS) {
+      break;
+    }
+  }
+  if (rc == GRN_SUCCESS) {
+    rc = grn_obj_set_value(ctx, column->srcs[0], id, &obj, GRN_OBJ_SET);
+  }
+  GRN_OBJ_FIN(ctx, &obj);
+  return rc;
+}
+#undef GRNGO_SET_WEIGHTED_VECTOR_CASE_BLOCK
 #undef GRNGO_SET_TEXT_VECTOR_CASE_BLOCK
 
 grn_rc
@@ -1108,6 +1949,290 @@ grngo_set_geo_point_vector(grngo_column *column, grn_id id,
   return rc;
 }
 
+// _grngo_is_ref_vector returns whether a column is a writable reference
+// vector column or not.
+static grn_bool
+_grngo_is_ref_vector(grngo_column *column) {
+  return column->writable && (column->n_srcs > 1) &&
+         _grngo_is_vector(column->srcs[0]);
+}
+
+// _grngo_insert_ref finds or inserts a row into the table referred to by
+// column->srcs[0] and returns its ID. Table references in _key are resolved
+// in the same way as _grngo_insert_row.
+static grn_rc
+_grngo_insert_ref(grngo_column *column, const void *key, size_t key_size,
+                  grn_id *id) {
+  grn_ctx *ctx = column->db->ctx;
+  grn_id tmp_id = GRN_ID_NIL;
+  size_t i = column->n_srcs - 1;
+  while (i > 0) {
+    i--;
+    grn_obj *table = grn_ctx_at(ctx, grn_obj_get_range(ctx, column->srcs[i]));
+    if (!table) {
+      if (ctx->rc != GRN_SUCCESS) {
+        return ctx->rc;
+      }
+      return GRN_UNKNOWN_ERROR;
+    }
+    tmp_id = grn_table_add(ctx, table, key, key_size, NULL);
+    grn_obj_unlink(ctx, table);
+    if (tmp_id == GRN_ID_NIL) {
+      if (ctx->rc != GRN_SUCCESS) {
+        return ctx->rc;
+      }
+      return GRN_UNKNOWN_ERROR;
+    }
+    key = &tmp_id;
+    key_size = sizeof(tmp_id);
+  }
+  *id = tmp_id;
+  return GRN_SUCCESS;
+}
+
+grn_rc
+grngo_set_ref_vector(grngo_column *column, grn_id id, grngo_vector value) {
+  if (!column || !_grngo_is_ref_vector(column) || !GRNGO_TEST_VECTOR(value)) {
+    return GRN_INVALID_ARGUMENT;
+  }
+  grn_ctx *ctx = column->db->ctx;
+  if (grn_table_at(ctx, column->table->objs[0], id) == GRN_ID_NIL) {
+    return GRN_INVALID_ARGUMENT;
+  }
+  const grngo_text *keys = (const grngo_text *)value.ptr;
+  size_t i;
+  for (i = 0; i < value.size; i++) {
+    if (!keys[i].ptr && keys[i].size) {
+      return GRN_INVALID_ARGUMENT;
+    }
+  }
+  grn_obj obj;
+  GRN_RECORD_INIT(&obj, GRN_OBJ_VECTOR,
+                  grn_obj_get_range(ctx, column->srcs[0]));
+  grn_rc rc = grn_bulk_space(ctx, &obj, sizeof(grn_id) * value.size);
+  if (rc == GRN_SUCCESS) {
+    grn_id *head = (grn_id *)GRN_BULK_HEAD(&obj);
+    for (i = 0; i < value.size; i++) {
+      rc = _grngo_insert_ref(column, keys[i].ptr, keys[i].size, &head[i]);
+      if (rc != GRN_SUCCESS) {
+        break;
+      }
+    }
+  }
+  if (rc == GRN_SUCCESS) {
+    rc = grn_obj_set_value(ctx, column->srcs[0], id, &obj, GRN_OBJ_SET);
+  }
+  GRN_OBJ_FIN(ctx, &obj);
+  return rc;
+}
+
+grn_rc
+grngo_set_ref_id_vector(grngo_column *column, grn_id id, grngo_vector value) {
+  if (!column || !_grngo_is_ref_vector(column) || !GRNGO_TEST_VECTOR(value)) {
+    return GRN_INVALID_ARGUMENT;
+  }
+  grn_ctx *ctx = column->db->ctx;
+  if (grn_table_at(ctx, column->table->objs[0], id) == GRN_ID_NIL) {
+    return GRN_INVALID_ARGUMENT;
+  }
+  grn_id range = grn_obj_get_range(ctx, column->srcs[0]);
+  grn_obj *table = grn_ctx_at(ctx, range);
+  if (!table) {
+    if (ctx->rc != GRN_SUCCESS) {
+      return ctx->rc;
+    }
+    return GRN_UNKNOWN_ERROR;
+  }
+  const grn_id *ids = (const grn_id *)value.ptr;
+  size_t i;
+  for (i = 0; i < value.size; i++) {
+    if (grn_table_at(ctx, table, ids[i]) == GRN_ID_NIL) {
+      grn_obj_unlink(ctx, table);
+      return GRN_INVALID_ARGUMENT;
+    }
+  }
+  grn_obj_unlink(ctx, table);
+  grn_obj obj;
+  GRN_RECORD_INIT(&obj, GRN_OBJ_VECTOR, range);
+  grn_rc rc = grn_bulk_write(ctx, &obj, (const char *)value.ptr,
+                             sizeof(grn_id) * value.size);
+  if (rc == GRN_SUCCESS) {
+    rc = grn_obj_set_value(ctx, column->srcs[0], id, &obj, GRN_OBJ_SET);
+  }
+  GRN_OBJ_FIN(ctx, &obj);
+  return rc;
+}
+
+grn_rc
+grngo_set_weighted_ref_vector(grngo_column *column, grn_id id,
+                              grngo_vector value) {
+  if (!column || !_grngo_is_ref_vector(column) || !column->with_weight ||
+      !GRNGO_TEST_VECTOR(value)) {
+    return GRN_INVALID_ARGUMENT;
+  }
+  grn_ctx *ctx = column->db->ctx;
+  if (grn_table_at(ctx, column->table->objs[0], id) == GRN_ID_NIL) {
+    return GRN_INVALID_ARGUMENT;
+  }
+  const grngo_weighted_text *keys = (const grngo_weighted_text *)value.ptr;
+  size_t i;
+  for (i = 0; i < value.size; i++) {
+    if (!keys[i].ptr && keys[i].size) {
+      return GRN_INVALID_ARGUMENT;
+    }
+  }
+  grn_obj obj;
+  GRN_RECORD_INIT(&obj, GRN_OBJ_VECTOR,
+                  grn_obj_get_range(ctx, column->srcs[0]));
+  obj.header.flags |= GRN_OBJ_WITH_WEIGHT;
+  grn_rc rc = GRN_SUCCESS;
+  for (i = 0; i < value.size; i++) {
+    grn_id ref_id;
+    rc = _grngo_insert_ref(column, keys[i].ptr, keys[i].size, &ref_id);
+    if (rc != GRN_SUCCESS) {
+      break;
+    }
+    rc = grn_uvector_add_element(ctx, &obj, ref_id, keys[i].weight);
+    if (rc != GRN_SUCCESS) {
+      break;
+    }
+  }
+  if (rc == GRN_SUCCESS) {
+    rc = grn_obj_set_value(ctx, column->srcs[0], id, &obj, GRN_OBJ_SET);
+  }
+  GRN_OBJ_FIN(ctx, &obj);
+  return rc;
+}
+
+grn_rc
+grngo_set_weighted_ref_id_vector(grngo_column *column, grn_id id,
+                                 grngo_vector value) {
+  if (!column || !_grngo_is_ref_vector(column) || !column->with_weight ||
+      !GRNGO_TEST_VECTOR(value)) {
+    return GRN_INVALID_ARGUMENT;
+  }
+  grn_ctx *ctx = column->db->ctx;
+  if (grn_table_at(ctx, column->table->objs[0], id) == GRN_ID_NIL) {
+    return GRN_INVALID_ARGUMENT;
+  }
+  grn_id range = grn_obj_get_range(ctx, column->srcs[0]);
+  grn_obj *table = grn_ctx_at(ctx, range);
+  if (!table) {
+    if (ctx->rc != GRN_SUCCESS) {
+      return ctx->rc;
+    }
+    return GRN_UNKNOWN_ERROR;
+  }
+  const grngo_weighted_id *ids = (const grngo_weighted_id *)value.ptr;
+  size_t i;
+  for (i = 0; i < value.size; i++) {
+    if (grn_table_at(ctx, table, ids[i].id) == GRN_ID_NIL) {
+      grn_obj_unlink(ctx, table);
+      return GRN_INVALID_ARGUMENT;
+    }
+  }
+  grn_obj_unlink(ctx, table);
+  grn_obj obj;
+  GRN_RECORD_INIT(&obj, GRN_OBJ_VECTOR, range);
+  obj.header.flags |= GRN_OBJ_WITH_WEIGHT;
+  grn_rc rc = GRN_SUCCESS;
+  for (i = 0; i < value.size; i++) {
+    rc = grn_uvector_add_element(ctx, &obj, ids[i].id, ids[i].weight);
+    if (rc != GRN_SUCCESS) {
+      break;
+    }
+  }
+  if (rc == GRN_SUCCESS) {
+    rc = grn_obj_set_value(ctx, column->srcs[0], id, &obj, GRN_OBJ_SET);
+  }
+  GRN_OBJ_FIN(ctx, &obj);
+  return rc;
+}
+
+#define GRNGO_SET_VALUES_FUNC(name, type)\
+  grn_rc\
+  grngo_set_ ## name ## _values(grngo_column *column,\
+                                const grn_id *ids, size_t n_ids,\
+                                const type *values, size_t *n_done) {\
+    if (!column || (!ids && n_ids) || (!values && n_ids) || !n_done) {\
+      return GRN_INVALID_ARGUMENT;\
+    }\
+    size_t i;\
+    for (i = 0; i < n_ids; i++) {\
+      grn_rc rc = grngo_set_ ## name(column, ids[i], values[i]);\
+      if (rc != GRN_SUCCESS) {\
+        *n_done = i;\
+        return rc;\
+      }\
+    }\
+    *n_done = n_ids;\
+    return GRN_SUCCESS;\
+  }
+GRNGO_SET_VALUES_FUNC(bool, grn_bool)
+GRNGO_SET_VALUES_FUNC(int, int64_t)
+GRNGO_SET_VALUES_FUNC(uint, uint64_t)
+GRNGO_SET_VALUES_FUNC(float, double)
+GRNGO_SET_VALUES_FUNC(text, grngo_text)
+GRNGO_SET_VALUES_FUNC(geo_point, grn_geo_point)
+GRNGO_SET_VALUES_FUNC(bool_vector, grngo_vector)
+GRNGO_SET_VALUES_FUNC(int_vector, grngo_vector)
+GRNGO_SET_VALUES_FUNC(uint_vector, grngo_vector)
+GRNGO_SET_VALUES_FUNC(float_vector, grngo_vector)
+GRNGO_SET_VALUES_FUNC(text_vector, grngo_vector)
+GRNGO_SET_VALUES_FUNC(weighted_vector, grngo_vector)
+GRNGO_SET_VALUES_FUNC(geo_point_vector, grngo_vector)
+GRNGO_SET_VALUES_FUNC(ref_vector, grngo_vector)
+GRNGO_SET_VALUES_FUNC(ref_id_vector, grngo_vector)
+GRNGO_SET_VALUES_FUNC(weighted_ref_vector, grngo_vector)
+GRNGO_SET_VALUES_FUNC(weighted_ref_id_vector, grngo_vector)
+#undef GRNGO_SET_VALUES_FUNC
+
+#define GRNGO_STRIP_WEIGHTS_CASE_BLOCK(type)\
+  case GRN_DB_ ## type: {\
+    GRNGO_DB_TYPE(type) *head = (GRNGO_DB_TYPE(type) *)GRN_BULK_HEAD(uvector);\
+    for (i = 0; i < n; i++) {\
+      head[i] = (GRNGO_DB_TYPE(type))grn_uvector_get_element(ctx, uvector, i,\
+                                                             &weights[i]);\
+    }\
+    break;\
+  }
+// _grngo_strip_weights moves the weights of n elements in a weight uvector
+// into column->weight_buf and packs the elements of type into the head of
+// the uvector. Each element moves to an offset not greater than its own.
+static grn_rc
+_grngo_strip_weights(grngo_column *column, grn_obj *uvector,
+                     grn_builtin_type type, size_t n) {
+  grn_ctx *ctx = column->db->ctx;
+  GRN_BULK_REWIND(column->weight_buf);
+  grn_rc rc = grn_bulk_space(ctx, column->weight_buf,
+                             sizeof(unsigned int) * n);
+  if (rc != GRN_SUCCESS) {
+    return rc;
+  }
+  unsigned int *weights = (unsigned int *)GRN_BULK_HEAD(column->weight_buf);
+  size_t i;
+  if (!(uvector->header.flags & GRN_OBJ_WITH_WEIGHT)) {
+    for (i = 0; i < n; i++) {
+      weights[i] = 0;
+    }
+    return GRN_SUCCESS;
+  }
+  switch (type) {
+    GRNGO_STRIP_WEIGHTS_CASE_BLOCK(BOOL)
+    GRNGO_STRIP_WEIGHTS_CASE_BLOCK(INT8)
+    GRNGO_STRIP_WEIGHTS_CASE_BLOCK(INT16)
+    GRNGO_STRIP_WEIGHTS_CASE_BLOCK(INT32)
+    GRNGO_STRIP_WEIGHTS_CASE_BLOCK(UINT8)
+    GRNGO_STRIP_WEIGHTS_CASE_BLOCK(UINT16)
+    GRNGO_STRIP_WEIGHTS_CASE_BLOCK(UINT32)
+    default: {
+      return GRN_UNKNOWN_ERROR;
+    }
+  }
+  return GRN_SUCCESS;
+}
+#undef GRNGO_STRIP_WEIGHTS_CASE_BLOCK
+
 static grn_rc
 _grngo_get_ref(grngo_column *column, size_t src_id,
                const grn_id *ids, size_t n_ids,
@@ -1133,6 +2258,16 @@ _grngo_get_ref(grngo_column *column, size_t src_id,
       }
       offset = size;
     }
+    if ((src_id == 0) && column->with_weight) {
+      // IDs are packed as UInt32 and grn_vector_size() is no longer valid.
+      grn_rc rc = _grngo_strip_weights(column, src_buf, GRN_DB_UINT32, offset);
+      if (rc != GRN_SUCCESS) {
+        return rc;
+      }
+      *next_ids = (const grn_id *)GRN_BULK_HEAD(src_buf);
+      *next_n_ids = offset;
+      return GRN_SUCCESS;
+    }
   } else {
     size_t i;
     for (i = 0; i < n_ids; i++) {
@@ -1174,16 +2309,30 @@ _grngo_get_value(grngo_column *column, const grn_id *ids, size_t n_ids) {
     }
     if (column->text_buf) {
       GRN_BULK_REWIND(column->text_buf);
+      if (column->with_weight) {
+        GRN_BULK_REWIND(column->weight_buf);
+      }
       for (i = 0; i < offset; i++) {
         grngo_text text;
+        unsigned int weight;
         text.size = grn_vector_get_element(ctx, src_buf, i,
-                                           &text.ptr, NULL, NULL);
+                                           &text.ptr, &weight, NULL);
         grn_rc rc = grn_bulk_write(ctx, column->text_buf,
                                    (char *)&text, sizeof(text));
+        if ((rc == GRN_SUCCESS) && column->with_weight) {
+          rc = grn_bulk_write(ctx, column->weight_buf,
+                              (char *)&weight, sizeof(weight));
+        }
         if (rc != GRN_SUCCESS) {
           return rc;
         }
       }
+    } else if (column->with_weight) {
+      grn_rc rc = _grngo_strip_weights(column, src_buf, column->value_type,
+                                       offset);
+      if (rc != GRN_SUCCESS) {
+        return rc;
+      }
     }
   } else if (column->text_buf) {
     GRN_BULK_REWIND(column->text_buf);
@@ -1234,10 +2383,10 @@ _grngo_get_value(grngo_column *column, const grn_id *ids, size_t n_ids) {
     break;\
   }
 static grn_rc
-_grngo_fill_vector(grngo_column *column) {
+_grngo_fill_vector(grngo_column *column, size_t n_ids) {
   // Fill pointers to next vectors.
   grngo_vector *src = (grngo_vector *)GRN_BULK_HEAD(column->vector_buf);
-  grngo_vector *dest = src + 1;
+  grngo_vector *dest = src + n_ids;
   size_t i, j;
   for (i = 1; i < column->dimension; i++) {
     size_t size = dest - src;
@@ -1282,20 +2431,27 @@ _grngo_fill_vector(grngo_column *column) {
 
 grn_rc
 grngo_get(grngo_column *column, grn_id id, void **value) {
-  if (!column || !value) {
+  return grngo_get_values(column, &id, 1, value);
+}
+
+grn_rc
+grngo_get_values(grngo_column *column, const grn_id *ids, size_t n_ids,
+                 void **values) {
+  if (!column || (!ids && n_ids) || !values) {
     return GRN_INVALID_ARGUMENT;
   }
   grn_ctx *ctx = column->db->ctx;
-  if (grn_table_at(ctx, column->table->objs[0], id) == GRN_ID_NIL) {
-    return GRN_INVALID_ARGUMENT;
+  size_t i;
+  for (i = 0; i < n_ids; i++) {
+    if (grn_table_at(ctx, column->table->objs[0], ids[i]) == GRN_ID_NIL) {
+      return GRN_INVALID_ARGUMENT;
+    }
   }
   // Get vectors and values.
   if (column->vector_buf) {
     GRN_BULK_REWIND(column->vector_buf);
   }
-  const grn_id *ids = &id;
-  size_t n_ids = 1;
-  size_t i;
+  size_t n_top_ids = n_ids;
   for (i = 0; i < (column->n_srcs - 1); i++) {
     grn_rc rc = _grngo_get_ref(column, i, ids, n_ids, &ids, &n_ids);
     if (rc != GRN_SUCCESS) {
@@ -1304,16 +2460,30 @@ grngo_get(grngo_column *column, grn_id id, void **value) {
   }
   grn_rc rc = _grngo_get_value(column, ids, n_ids);
   if (rc != GRN_SUCCESS) {
-    return GRN_SUCCESS;
+    return rc;
   }
   // Fill pointers.
   if (column->dimension != 0) {
-    _grngo_fill_vector(column);
-    *value = GRN_BULK_HEAD(column->vector_buf);
+    rc = _grngo_fill_vector(column, n_top_ids);
+    if (rc != GRN_SUCCESS) {
+      return rc;
+    }
+    *values = GRN_BULK_HEAD(column->vector_buf);
   } else if (column->text_buf) {
-    *value = GRN_BULK_HEAD(column->text_buf);
+    *values = GRN_BULK_HEAD(column->text_buf);
   } else {
-    *value = GRN_BULK_HEAD(column->src_bufs[i]);
+    *values = GRN_BULK_HEAD(column->src_bufs[i]);
+  }
+  return GRN_SUCCESS;
+}
+
+// grngo_get_weights returns the weights of the values got by the last
+// grngo_get or grngo_get_values of a weight vector column.
+grn_rc
+grngo_get_weights(grngo_column *column, const unsigned int **weights) {
+  if (!column || !column->with_weight || !weights) {
+    return GRN_INVALID_ARGUMENT;
   }
+  *weights = (const unsigned int *)GRN_BULK_HEAD(column->weight_buf);
   return GRN_SUCCESS;
 }
@@ -7,189 +7,363 @@ import "C"
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"reflect"
+	"runtime"
+	"runtime/cgo"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 	"unsafe"
 )
 
 // -- Errors --
 
-func rcString(rc C.grn_rc) string {
+// RC is a return code of Groonga (grn_rc).
+//
+// RC implements error, so the following constants can be used as sentinel
+// errors with errors.Is.
+// For example, errors.Is(err, ErrInvalidArgument) reports whether err is or
+// wraps an Error whose return code is GRN_INVALID_ARGUMENT.
+type RC int
+
+// Return codes of Groonga.
+//
+// See http://groonga.org/docs/reference/api/grn_ctx.html for details.
+const (
+	Success                            = RC(C.GRN_SUCCESS)                             // GRN_SUCCESS.
+	EndOfData                          = RC(C.GRN_END_OF_DATA)                         // GRN_END_OF_DATA.
+	ErrUnknown                         = RC(C.GRN_UNKNOWN_ERROR)                       // GRN_UNKNOWN_ERROR.
+	ErrOperationNotPermitted           = RC(C.GRN_OPERATION_NOT_PERMITTED)             // GRN_OPERATION_NOT_PERMITTED.
+	ErrNoSuchFileOrDirectory           = RC(C.GRN_NO_SUCH_FILE_OR_DIRECTORY)           // GRN_NO_SUCH_FILE_OR_DIRECTORY.
+	ErrNoSuchProcess                   = RC(C.GRN_NO_SUCH_PROCESS)                     // GRN_NO_SUCH_PROCESS.
+	ErrInterruptedFunctionCall         = RC(C.GRN_INTERRUPTED_FUNCTION_CALL)           // GRN_INTERRUPTED_FUNCTION_CALL.
+	ErrInputOutput                     = RC(C.GRN_INPUT_OUTPUT_ERROR)                  // GRN_INPUT_OUTPUT_ERROR.
+	ErrNoSuchDeviceOrAddress           = RC(C.GRN_NO_SUCH_DEVICE_OR_ADDRESS)           // GRN_NO_SUCH_DEVICE_OR_ADDRESS.
+	ErrArgListTooLong                  = RC(C.GRN_ARG_LIST_TOO_LONG)                   // GRN_ARG_LIST_TOO_LONG.
+	ErrExecFormat                      = RC(C.GRN_EXEC_FORMAT_ERROR)                   // GRN_EXEC_FORMAT_ERROR.
+	ErrBadFileDescriptor               = RC(C.GRN_BAD_FILE_DESCRIPTOR)                 // GRN_BAD_FILE_DESCRIPTOR.
+	ErrNoChildProcesses                = RC(C.GRN_NO_CHILD_PROCESSES)                  // GRN_NO_CHILD_PROCESSES.
+	ErrResourceTemporarilyUnavailable  = RC(C.GRN_RESOURCE_TEMPORARILY_UNAVAILABLE)    // GRN_RESOURCE_TEMPORARILY_UNAVAILABLE.
+	ErrNotEnoughSpace                  = RC(C.GRN_NOT_ENOUGH_SPACE)                    // GRN_NOT_ENOUGH_SPACE.
+	ErrPermissionDenied                = RC(C.GRN_PERMISSION_DENIED)                   // GRN_PERMISSION_DENIED.
+	ErrBadAddress                      = RC(C.GRN_BAD_ADDRESS)                         // GRN_BAD_ADDRESS.
+	ErrResourceBusy                    = RC(C.GRN_RESOURCE_BUSY)                       // GRN_RESOURCE_BUSY.
+	ErrFileExists                      = RC(C.GRN_FILE_EXISTS)                         // GRN_FILE_EXISTS.
+	ErrImproperLink                    = RC(C.GRN_IMPROPER_LINK)                       // GRN_IMPROPER_LINK.
+	ErrNoSuchDevice                    = RC(C.GRN_NO_SUCH_DEVICE)                      // GRN_NO_SUCH_DEVICE.
+	ErrNotADirectory                   = RC(C.GRN_NOT_A_DIRECTORY)                     // GRN_NOT_A_DIRECTORY.
+	ErrIsADirectory                    = RC(C.GRN_IS_A_DIRECTORY)                      // GRN_IS_A_DIRECTORY.
+	ErrInvalidArgument                 = RC(C.GRN_INVALID_ARGUMENT)                    // GRN_INVALID_ARGUMENT.
+	ErrTooManyOpenFilesInSystem        = RC(C.GRN_TOO_MANY_OPEN_FILES_IN_SYSTEM)       // GRN_TOO_MANY_OPEN_FILES_IN_SYSTEM.
+	ErrTooManyOpenFiles                = RC(C.GRN_TOO_MANY_OPEN_FILES)                 // GRN_TOO_MANY_OPEN_FILES.
+	ErrInappropriateIOControlOperation = RC(C.GRN_INAPPROPRIATE_I_O_CONTROL_OPERATION) // GRN_INAPPROPRIATE_I_O_CONTROL_OPERATION.
+	ErrFileTooLarge                    = RC(C.GRN_FILE_TOO_LARGE)                      // GRN_FILE_TOO_LARGE.
+	ErrNoSpaceLeftOnDevice             = RC(C.GRN_NO_SPACE_LEFT_ON_DEVICE)             // GRN_NO_SPACE_LEFT_ON_DEVICE.
+	ErrInvalidSeek                     = RC(C.GRN_INVALID_SEEK)                        // GRN_INVALID_SEEK.
+	ErrReadOnlyFileSystem              = RC(C.GRN_READ_ONLY_FILE_SYSTEM)               // GRN_READ_ONLY_FILE_SYSTEM.
+	ErrTooManyLinks                    = RC(C.GRN_TOO_MANY_LINKS)                      // GRN_TOO_MANY_LINKS.
+	ErrBrokenPipe                      = RC(C.GRN_BROKEN_PIPE)                         // GRN_BROKEN_PIPE.
+	ErrDomain                          = RC(C.GRN_DOMAIN_ERROR)                        // GRN_DOMAIN_ERROR.
+	ErrResultTooLarge                  = RC(C.GRN_RESULT_TOO_LARGE)                    // GRN_RESULT_TOO_LARGE.
+	ErrResourceDeadlockAvoided         = RC(C.GRN_RESOURCE_DEADLOCK_AVOIDED)           // GRN_RESOURCE_DEADLOCK_AVOIDED.
+	ErrNoMemory                        = RC(C.GRN_NO_MEMORY_AVAILABLE)                 // GRN_NO_MEMORY_AVAILABLE.
+	ErrFilenameTooLong                 = RC(C.GRN_FILENAME_TOO_LONG)                   // GRN_FILENAME_TOO_LONG.
+	ErrNoLocksAvailable                = RC(C.GRN_NO_LOCKS_AVAILABLE)                  // GRN_NO_LOCKS_AVAILABLE.
+	ErrFunctionNotImplemented          = RC(C.GRN_FUNCTION_NOT_IMPLEMENTED)            // GRN_FUNCTION_NOT_IMPLEMENTED.
+	ErrDirectoryNotEmpty               = RC(C.GRN_DIRECTORY_NOT_EMPTY)                 // GRN_DIRECTORY_NOT_EMPTY.
+	ErrIllegalByteSequence             = RC(C.GRN_ILLEGAL_BYTE_SEQUENCE)               // GRN_ILLEGAL_BYTE_SEQUENCE.
+	ErrSocketNotInitialized            = RC(C.GRN_SOCKET_NOT_INITIALIZED)              // GRN_SOCKET_NOT_INITIALIZED.
+	ErrOperationWouldBlock             = RC(C.GRN_OPERATION_WOULD_BLOCK)               // GRN_OPERATION_WOULD_BLOCK.
+	ErrAddressIsNotAvailable           = RC(C.GRN_ADDRESS_IS_NOT_AVAILABLE)            // GRN_ADDRESS_IS_NOT_AVAILABLE.
+	ErrNetworkIsDown                   = RC(C.GRN_NETWORK_IS_DOWN)                     // GRN_NETWORK_IS_DOWN.
+	ErrNoBuffer                        = RC(C.GRN_NO_BUFFER)                           // GRN_NO_BUFFER.
+	ErrSocketIsAlreadyConnected        = RC(C.GRN_SOCKET_IS_ALREADY_CONNECTED)         // GRN_SOCKET_IS_ALREADY_CONNECTED.
+	ErrSocketIsNotConnected            = RC(C.GRN_SOCKET_IS_NOT_CONNECTED)             // GRN_SOCKET_IS_NOT_CONNECTED.
+	ErrSocketIsAlreadyShutdowned       = RC(C.GRN_SOCKET_IS_ALREADY_SHUTDOWNED)        // GRN_SOCKET_IS_ALREADY_SHUTDOWNED.
+	ErrOperationTimeout                = RC(C.GRN_OPERATION_TIMEOUT)                   // GRN_OPERATION_TIMEOUT.
+	ErrConnectionRefused               = RC(C.GRN_CONNECTION_REFUSED)                  // GRN_CONNECTION_REFUSED.
+	ErrRange                           = RC(C.GRN_RANGE_ERROR)                         // GRN_RANGE_ERROR.
+	ErrTokenizer                       = RC(C.GRN_TOKENIZER_ERROR)                     // GRN_TOKENIZER_ERROR.
+	ErrFileCorrupt                     = RC(C.GRN_FILE_CORRUPT)                        // GRN_FILE_CORRUPT.
+	ErrInvalidFormat                   = RC(C.GRN_INVALID_FORMAT)                      // GRN_INVALID_FORMAT.
+	ErrObjectCorrupt                   = RC(C.GRN_OBJECT_CORRUPT)                      // GRN_OBJECT_CORRUPT.
+	ErrTooManySymbolicLinks            = RC(C.GRN_TOO_MANY_SYMBOLIC_LINKS)             // GRN_TOO_MANY_SYMBOLIC_LINKS.
+	ErrNotSocket                       = RC(C.GRN_NOT_SOCKET)                          // GRN_NOT_SOCKET.
+	ErrOperationNotSupported           = RC(C.GRN_OPERATION_NOT_SUPPORTED)             // GRN_OPERATION_NOT_SUPPORTED.
+	ErrAddressIsInUse                  = RC(C.GRN_ADDRESS_IS_IN_USE)                   // GRN_ADDRESS_IS_IN_USE.
+	ErrZlib                            = RC(C.GRN_ZLIB_ERROR)                          // GRN_ZLIB_ERROR.
+	ErrLZ4                             = RC(C.GRN_LZ4_ERROR)                           // GRN_LZ4_ERROR.
+	ErrStackOverFlow                   = RC(C.GRN_STACK_OVER_FLOW)                     // GRN_STACK_OVER_FLOW.
+	ErrSyntax                          = RC(C.GRN_SYNTAX_ERROR)                        // GRN_SYNTAX_ERROR.
+	ErrRetryMax                        = RC(C.GRN_RETRY_MAX)                           // GRN_RETRY_MAX.
+	ErrIncompatibleFileFormat          = RC(C.GRN_INCOMPATIBLE_FILE_FORMAT)            // GRN_INCOMPATIBLE_FILE_FORMAT.
+	ErrUpdateNotAllowed                = RC(C.GRN_UPDATE_NOT_ALLOWED)                  // GRN_UPDATE_NOT_ALLOWED.
+	ErrTooSmallOffset                  = RC(C.GRN_TOO_SMALL_OFFSET)                    // GRN_TOO_SMALL_OFFSET.
+	ErrTooLargeOffset                  = RC(C.GRN_TOO_LARGE_OFFSET)                    // GRN_TOO_LARGE_OFFSET.
+	ErrTooSmallLimit                   = RC(C.GRN_TOO_SMALL_LIMIT)                     // GRN_TOO_SMALL_LIMIT.
+	ErrCAS                             = RC(C.GRN_CAS_ERROR)                           // GRN_CAS_ERROR.
+	ErrUnsupportedCommandVersion       = RC(C.GRN_UNSUPPORTED_COMMAND_VERSION)         // GRN_UNSUPPORTED_COMMAND_VERSION.
+	ErrNormalizer                      = RC(C.GRN_NORMALIZER_ERROR)                    // GRN_NORMALIZER_ERROR.
+	ErrTokenFilter                     = RC(C.GRN_TOKEN_FILTER_ERROR)                  // GRN_TOKEN_FILTER_ERROR.
+	ErrCommand                         = RC(C.GRN_COMMAND_ERROR)                       // GRN_COMMAND_ERROR.
+	ErrPlugin                          = RC(C.GRN_PLUGIN_ERROR)                        // GRN_PLUGIN_ERROR.
+	ErrScorer                          = RC(C.GRN_SCORER_ERROR)                        // GRN_SCORER_ERROR.
+)
+
+// name returns the name of a return code, e.g. "GRN_INVALID_ARGUMENT".
+func (rc RC) name() string {
 	switch rc {
-	case C.GRN_SUCCESS:
-		return fmt.Sprintf("GRN_SUCCESS (%d)", rc)
-	case C.GRN_END_OF_DATA:
-		return fmt.Sprintf("GRN_END_OF_DATA (%d)", rc)
-	case C.GRN_UNKNOWN_ERROR:
-		return fmt.Sprintf("GRN_UNKNOWN_ERROR (%d)", rc)
-	case C.GRN_OPERATION_NOT_PERMITTED:
-		return fmt.Sprintf("GRN_OPERATION_NOT_PERMITTED (%d)", rc)
-	case C.GRN_NO_SUCH_FILE_OR_DIRECTORY:
-		return fmt.Sprintf("GRN_NO_SUCH_FILE_OR_DIRECTORY (%d)", rc)
-	case C.GRN_NO_SUCH_PROCESS:
-		return fmt.Sprintf("GRN_NO_SUCH_PROCESS (%d)", rc)
-	case C.GRN_INTERRUPTED_FUNCTION_CALL:
-		return fmt.Sprintf("GRN_INTERRUPTED_FUNCTION_CALL (%d)", rc)
-	case C.GRN_INPUT_OUTPUT_ERROR:
-		return fmt.Sprintf("GRN_INPUT_OUTPUT_ERROR (%d)", rc)
-	case C.GRN_NO_SUCH_DEVICE_OR_ADDRESS:
-		return fmt.Sprintf("GRN_NO_SUCH_DEVICE_OR_ADDRESS (%d)", rc)
-	case C.GRN_ARG_LIST_TOO_LONG:
-		return fmt.Sprintf("GRN_ARG_LIST_TOO_LONG (%d)", rc)
-	case C.GRN_EXEC_FORMAT_ERROR:
-		return fmt.Sprintf("GRN_EXEC_FORMAT_ERROR (%d)", rc)
-	case C.GRN_BAD_FILE_DESCRIPTOR:
-		return fmt.Sprintf("GRN_BAD_FILE_DESCRIPTOR (%d)", rc)
-	case C.GRN_NO_CHILD_PROCESSES:
-		return fmt.Sprintf("GRN_NO_CHILD_PROCESSES (%d)", rc)
-	case C.GRN_RESOURCE_TEMPORARILY_UNAVAILABLE:
-		return fmt.Sprintf("GRN_RESOURCE_TEMPORARILY_UNAVAILABLE (%d)", rc)
-	case C.GRN_NOT_ENOUGH_SPACE:
-		return fmt.Sprintf("GRN_NOT_ENOUGH_SPACE (%d)", rc)
-	case C.GRN_PERMISSION_DENIED:
-		return fmt.Sprintf("GRN_PERMISSION_DENIED (%d)", rc)
-	case C.GRN_BAD_ADDRESS:
-		return fmt.Sprintf("GRN_BAD_ADDRESS (%d)", rc)
-	case C.GRN_RESOURCE_BUSY:
-		return fmt.Sprintf("GRN_RESOURCE_BUSY (%d)", rc)
-	case C.GRN_FILE_EXISTS:
-		return fmt.Sprintf("GRN_FILE_EXISTS (%d)", rc)
-	case C.GRN_IMPROPER_LINK:
-		return fmt.Sprintf("GRN_IMPROPER_LINK (%d)", rc)
-	case C.GRN_NO_SUCH_DEVICE:
-		return fmt.Sprintf("GRN_NO_SUCH_DEVICE (%d)", rc)
-	case C.GRN_NOT_A_DIRECTORY:
-		return fmt.Sprintf("GRN_NOT_A_DIRECTORY (%d)", rc)
-	case C.GRN_IS_A_DIRECTORY:
-		return fmt.Sprintf("GRN_IS_A_DIRECTORY (%d)", rc)
-	case C.GRN_INVALID_ARGUMENT:
-		return fmt.Sprintf("GRN_INVALID_ARGUMENT (%d)", rc)
-	case C.GRN_TOO_MANY_OPEN_FILES_IN_SYSTEM:
-		return fmt.Sprintf("GRN_TOO_MANY_OPEN_FILES_IN_SYSTEM (%d)", rc)
-	case C.GRN_TOO_MANY_OPEN_FILES:
-		return fmt.Sprintf("GRN_TOO_MANY_OPEN_FILES (%d)", rc)
-	case C.GRN_INAPPROPRIATE_I_O_CONTROL_OPERATION:
-		return fmt.Sprintf("GRN_INAPPROPRIATE_I_O_CONTROL_OPERATION (%d)", rc)
-	case C.GRN_FILE_TOO_LARGE:
-		return fmt.Sprintf("GRN_FILE_TOO_LARGE (%d)", rc)
-	case C.GRN_NO_SPACE_LEFT_ON_DEVICE:
-		return fmt.Sprintf("GRN_NO_SPACE_LEFT_ON_DEVICE (%d)", rc)
-	case C.GRN_INVALID_SEEK:
-		return fmt.Sprintf("GRN_INVALID_SEEK (%d)", rc)
-	case C.GRN_READ_ONLY_FILE_SYSTEM:
-		return fmt.Sprintf("GRN_READ_ONLY_FILE_SYSTEM (%d)", rc)
-	case C.GRN_TOO_MANY_LINKS:
-		return fmt.Sprintf("GRN_TOO_MANY_LINKS (%d)", rc)
-	case C.GRN_BROKEN_PIPE:
-		return fmt.Sprintf("GRN_BROKEN_PIPE (%d)", rc)
-	case C.GRN_DOMAIN_ERROR:
-		return fmt.Sprintf("GRN_DOMAIN_ERROR (%d)", rc)
-	case C.GRN_RESULT_TOO_LARGE:
-		return fmt.Sprintf("GRN_RESULT_TOO_LARGE (%d)", rc)
-	case C.GRN_RESOURCE_DEADLOCK_AVOIDED:
-		return fmt.Sprintf("GRN_RESOURCE_DEADLOCK_AVOIDED (%d)", rc)
-	case C.GRN_NO_MEMORY_AVAILABLE:
-		return fmt.Sprintf("GRN_NO_MEMORY_AVAILABLE (%d)", rc)
-	case C.GRN_FILENAME_TOO_LONG:
-		return fmt.Sprintf("GRN_FILENAME_TOO_LONG (%d)", rc)
-	case C.GRN_NO_LOCKS_AVAILABLE:
-		return fmt.Sprintf("GRN_NO_LOCKS_AVAILABLE (%d)", rc)
-	case C.GRN_FUNCTION_NOT_IMPLEMENTED:
-		return fmt.Sprintf("GRN_FUNCTION_NOT_IMPLEMENTED (%d)", rc)
-	case C.GRN_DIRECTORY_NOT_EMPTY:
-		return fmt.Sprintf("GRN_DIRECTORY_NOT_EMPTY (%d)", rc)
-	case C.GRN_ILLEGAL_BYTE_SEQUENCE:
-		return fmt.Sprintf("GRN_ILLEGAL_BYTE_SEQUENCE (%d)", rc)
-	case C.GRN_SOCKET_NOT_INITIALIZED:
-		return fmt.Sprintf("GRN_SOCKET_NOT_INITIALIZED (%d)", rc)
-	case C.GRN_OPERATION_WOULD_BLOCK:
-		return fmt.Sprintf("GRN_OPERATION_WOULD_BLOCK (%d)", rc)
-	case C.GRN_ADDRESS_IS_NOT_AVAILABLE:
-		return fmt.Sprintf("GRN_ADDRESS_IS_NOT_AVAILABLE (%d)", rc)
-	case C.GRN_NETWORK_IS_DOWN:
-		return fmt.Sprintf("GRN_NETWORK_IS_DOWN (%d)", rc)
-	case C.GRN_NO_BUFFER:
-		return fmt.Sprintf("GRN_NO_BUFFER (%d)", rc)
-	case C.GRN_SOCKET_IS_ALREADY_CONNECTED:
-		return fmt.Sprintf("GRN_SOCKET_IS_ALREADY_CONNECTED (%d)", rc)
-	case C.GRN_SOCKET_IS_NOT_CONNECTED:
-		return fmt.Sprintf("GRN_SOCKET_IS_NOT_CONNECTED (%d)", rc)
-	case C.GRN_SOCKET_IS_ALREADY_SHUTDOWNED:
-		return fmt.Sprintf("GRN_SOCKET_IS_ALREADY_SHUTDOWNED (%d)", rc)
-	case C.GRN_OPERATION_TIMEOUT:
-		return fmt.Sprintf("GRN_OPERATION_TIMEOUT (%d)", rc)
-	case C.GRN_CONNECTION_REFUSED:
-		return fmt.Sprintf("GRN_CONNECTION_REFUSED (%d)", rc)
-	case C.GRN_RANGE_ERROR:
-		return fmt.Sprintf("GRN_RANGE_ERROR (%d)", rc)
-	case C.GRN_TOKENIZER_ERROR:
-		return fmt.Sprintf("GRN_TOKENIZER_ERROR (%d)", rc)
-	case C.GRN_FILE_CORRUPT:
-		return fmt.Sprintf("GRN_FILE_CORRUPT (%d)", rc)
-	case C.GRN_INVALID_FORMAT:
-		return fmt.Sprintf("GRN_INVALID_FORMAT (%d)", rc)
-	case C.GRN_OBJECT_CORRUPT:
-		return fmt.Sprintf("GRN_OBJECT_CORRUPT (%d)", rc)
-	case C.GRN_TOO_MANY_SYMBOLIC_LINKS:
-		return fmt.Sprintf("GRN_TOO_MANY_SYMBOLIC_LINKS (%d)", rc)
-	case C.GRN_NOT_SOCKET:
-		return fmt.Sprintf("GRN_NOT_SOCKET (%d)", rc)
-	case C.GRN_OPERATION_NOT_SUPPORTED:
-		return fmt.Sprintf("GRN_OPERATION_NOT_SUPPORTED (%d)", rc)
-	case C.GRN_ADDRESS_IS_IN_USE:
-		return fmt.Sprintf("GRN_ADDRESS_IS_IN_USE (%d)", rc)
-	case C.GRN_ZLIB_ERROR:
-		return fmt.Sprintf("GRN_ZLIB_ERROR (%d)", rc)
-	case C.GRN_LZ4_ERROR:
-		return fmt.Sprintf("GRN_LZ4_ERROR (%d)", rc)
-	case C.GRN_STACK_OVER_FLOW:
-		return fmt.Sprintf("GRN_STACK_OVER_FLOW (%d)", rc)
-	case C.GRN_SYNTAX_ERROR:
-		return fmt.Sprintf("GRN_SYNTAX_ERROR (%d)", rc)
-	case C.GRN_RETRY_MAX:
-		return fmt.Sprintf("GRN_RETRY_MAX (%d)", rc)
-	case C.GRN_INCOMPATIBLE_FILE_FORMAT:
-		return fmt.Sprintf("GRN_INCOMPATIBLE_FILE_FORMAT (%d)", rc)
-	case C.GRN_UPDATE_NOT_ALLOWED:
-		return fmt.Sprintf("GRN_UPDATE_NOT_ALLOWED (%d)", rc)
-	case C.GRN_TOO_SMALL_OFFSET:
-		return fmt.Sprintf("GRN_TOO_SMALL_OFFSET (%d)", rc)
-	case C.GRN_TOO_LARGE_OFFSET:
-		return fmt.Sprintf("GRN_TOO_LARGE_OFFSET (%d)", rc)
-	case C.GRN_TOO_SMALL_LIMIT:
-		return fmt.Sprintf("GRN_TOO_SMALL_LIMIT (%d)", rc)
-	case C.GRN_CAS_ERROR:
-		return fmt.Sprintf("GRN_CAS_ERROR (%d)", rc)
-	case C.GRN_UNSUPPORTED_COMMAND_VERSION:
-		return fmt.Sprintf("GRN_UNSUPPORTED_COMMAND_VERSION (%d)", rc)
-	case C.GRN_NORMALIZER_ERROR:
-		return fmt.Sprintf("GRN_NORMALIZER_ERROR (%d)", rc)
-	case C.GRN_TOKEN_FILTER_ERROR:
-		return fmt.Sprintf("GRN_TOKEN_FILTER_ERROR (%d)", rc)
-	case C.GRN_COMMAND_ERROR:
-		return fmt.Sprintf("GRN_COMMAND_ERROR (%d)", rc)
-	case C.GRN_PLUGIN_ERROR:
-		return fmt.Sprintf("GRN_PLUGIN_ERROR (%d)", rc)
-	case C.GRN_SCORER_ERROR:
-		return fmt.Sprintf("GRN_SCORER_ERROR (%d)", rc)
+	case Success:
+		return "GRN_SUCCESS"
+	case EndOfData:
+		return "GRN_END_OF_DATA"
+	case ErrUnknown:
+		return "GRN_UNKNOWN_ERROR"
+	case ErrOperationNotPermitted:
+		return "GRN_OPERATION_NOT_PERMITTED"
+	case ErrNoSuchFileOrDirectory:
+		return "GRN_NO_SUCH_FILE_OR_DIRECTORY"
+	case ErrNoSuchProcess:
+		return "GRN_NO_SUCH_PROCESS"
+	case ErrInterruptedFunctionCall:
+		return "GRN_INTERRUPTED_FUNCTION_CALL"
+	case ErrInputOutput:
+		return "GRN_INPUT_OUTPUT_ERROR"
+	case ErrNoSuchDeviceOrAddress:
+		return "GRN_NO_SUCH_DEVICE_OR_ADDRESS"
+	case ErrArgListTooLong:
+		return "GRN_ARG_LIST_TOO_LONG"
+	case ErrExecFormat:
+		return "GRN_EXEC_FORMAT_ERROR"
+	case ErrBadFileDescriptor:
+		return "GRN_BAD_FILE_DESCRIPTOR"
+	case ErrNoChildProcesses:
+		return "GRN_NO_CHILD_PROCESSES"
+	case ErrResourceTemporarilyUnavailable:
+		return "GRN_RESOURCE_TEMPORARILY_UNAVAILABLE"
+	case ErrNotEnoughSpace:
+		return "GRN_NOT_ENOUGH_SPACE"
+	case ErrPermissionDenied:
+		return "GRN_PERMISSION_DENIED"
+	case ErrBadAddress:
+		return "GRN_BAD_ADDRESS"
+	case ErrResourceBusy:
+		return "GRN_RESOURCE_BUSY"
+	case ErrFileExists:
+		return "GRN_FILE_EXISTS"
+	case ErrImproperLink:
+		return "GRN_IMPROPER_LINK"
+	case ErrNoSuchDevice:
+		return "GRN_NO_SUCH_DEVICE"
+	case ErrNotADirectory:
+		return "GRN_NOT_A_DIRECTORY"
+	case ErrIsADirectory:
+		return "GRN_IS_A_DIRECTORY"
+	case ErrInvalidArgument:
+		return "GRN_INVALID_ARGUMENT"
+	case ErrTooManyOpenFilesInSystem:
+		return "GRN_TOO_MANY_OPEN_FILES_IN_SYSTEM"
+	case ErrTooManyOpenFiles:
+		return "GRN_TOO_MANY_OPEN_FILES"
+	case ErrInappropriateIOControlOperation:
+		return "GRN_INAPPROPRIATE_I_O_CONTROL_OPERATION"
+	case ErrFileTooLarge:
+		return "GRN_FILE_TOO_LARGE"
+	case ErrNoSpaceLeftOnDevice:
+		return "GRN_NO_SPACE_LEFT_ON_DEVICE"
+	case ErrInvalidSeek:
+		return "GRN_INVALID_SEEK"
+	case ErrReadOnlyFileSystem:
+		return "GRN_READ_ONLY_FILE_SYSTEM"
+	case ErrTooManyLinks:
+		return "GRN_TOO_MANY_LINKS"
+	case ErrBrokenPipe:
+		return "GRN_BROKEN_PIPE"
+	case ErrDomain:
+		return "GRN_DOMAIN_ERROR"
+	case ErrResultTooLarge:
+		return "GRN_RESULT_TOO_LARGE"
+	case ErrResourceDeadlockAvoided:
+		return "GRN_RESOURCE_DEADLOCK_AVOIDED"
+	case ErrNoMemory:
+		return "GRN_NO_MEMORY_AVAILABLE"
+	case ErrFilenameTooLong:
+		return "GRN_FILENAME_TOO_LONG"
+	case ErrNoLocksAvailable:
+		return "GRN_NO_LOCKS_AVAILABLE"
+	case ErrFunctionNotImplemented:
+		return "GRN_FUNCTION_NOT_IMPLEMENTED"
+	case ErrDirectoryNotEmpty:
+		return "GRN_DIRECTORY_NOT_EMPTY"
+	case ErrIllegalByteSequence:
+		return "GRN_ILLEGAL_BYTE_SEQUENCE"
+	case ErrSocketNotInitialized:
+		return "GRN_SOCKET_NOT_INITIALIZED"
+	case ErrOperationWouldBlock:
+		return "GRN_OPERATION_WOULD_BLOCK"
+	case ErrAddressIsNotAvailable:
+		return "GRN_ADDRESS_IS_NOT_AVAILABLE"
+	case ErrNetworkIsDown:
+		return "GRN_NETWORK_IS_DOWN"
+	case ErrNoBuffer:
+		return "GRN_NO_BUFFER"
+	case ErrSocketIsAlreadyConnected:
+		return "GRN_SOCKET_IS_ALREADY_CONNECTED"
+	case ErrSocketIsNotConnected:
+		return "GRN_SOCKET_IS_NOT_CONNECTED"
+	case ErrSocketIsAlreadyShutdowned:
+		return "GRN_SOCKET_IS_ALREADY_SHUTDOWNED"
+	case ErrOperationTimeout:
+		return "GRN_OPERATION_TIMEOUT"
+	case ErrConnectionRefused:
+		return "GRN_CONNECTION_REFUSED"
+	case ErrRange:
+		return "GRN_RANGE_ERROR"
+	case ErrTokenizer:
+		return "GRN_TOKENIZER_ERROR"
+	case ErrFileCorrupt:
+		return "GRN_FILE_CORRUPT"
+	case ErrInvalidFormat:
+		return "GRN_INVALID_FORMAT"
+	case ErrObjectCorrupt:
+		return "GRN_OBJECT_CORRUPT"
+	case ErrTooManySymbolicLinks:
+		return "GRN_TOO_MANY_SYMBOLIC_LINKS"
+	case ErrNotSocket:
+		return "GRN_NOT_SOCKET"
+	case ErrOperationNotSupported:
+		return "GRN_OPERATION_NOT_SUPPORTED"
+	case ErrAddressIsInUse:
+		return "GRN_ADDRESS_IS_IN_USE"
+	case ErrZlib:
+		return "GRN_ZLIB_ERROR"
+	case ErrLZ4:
+		return "GRN_LZ4_ERROR"
+	case ErrStackOverFlow:
+		return "GRN_STACK_OVER_FLOW"
+	case ErrSyntax:
+		return "GRN_SYNTAX_ERROR"
+	case ErrRetryMax:
+		return "GRN_RETRY_MAX"
+	case ErrIncompatibleFileFormat:
+		return "GRN_INCOMPATIBLE_FILE_FORMAT"
+	case ErrUpdateNotAllowed:
+		return "GRN_UPDATE_NOT_ALLOWED"
+	case ErrTooSmallOffset:
+		return "GRN_TOO_SMALL_OFFSET"
+	case ErrTooLargeOffset:
+		return "GRN_TOO_LARGE_OFFSET"
+	case ErrTooSmallLimit:
+		return "GRN_TOO_SMALL_LIMIT"
+	case ErrCAS:
+		return "GRN_CAS_ERROR"
+	case ErrUnsupportedCommandVersion:
+		return "GRN_UNSUPPORTED_COMMAND_VERSION"
+	case ErrNormalizer:
+		return "GRN_NORMALIZER_ERROR"
+	case ErrTokenFilter:
+		return "GRN_TOKEN_FILTER_ERROR"
+	case ErrCommand:
+		return "GRN_COMMAND_ERROR"
+	case ErrPlugin:
+		return "GRN_PLUGIN_ERROR"
+	case ErrScorer:
+		return "GRN_SCORER_ERROR"
 	default:
-		return fmt.Sprintf("GRN_UNDEFINED_ERROR (%d)", rc)
+		return "GRN_UNDEFINED_ERROR"
+	}
+}
+
+// String returns the name and the value of a return code,
+// e.g. "GRN_INVALID_ARGUMENT (-22)".
+func (rc RC) String() string {
+	return fmt.Sprintf("%s (%d)", rc.name(), int(rc))
+}
+
+// Error returns the same string as String.
+func (rc RC) Error() string {
+	return rc.String()
+}
+
+// Error is an error related to a Groonga or Grngo operation.
+//
+// Error wraps its return code, so errors.Is(err, ErrFileCorrupt) and the like
+// work as expected. Use errors.As to get the details.
+type Error struct {
+	Op      string // The failed operation, e.g. "grngo_open_table()".
+	RC      RC     // The return code of the operation.
+	CtxRC   RC     // The return code stored in the context (ctx.rc).
+	Message string // The error message stored in the context (ctx.errbuf).
+	hasCtx  bool   // Whether CtxRC and Message are available or not.
+}
+
+// Error returns a string which describes the error.
+func (err *Error) Error() string {
+	if !err.hasCtx {
+		return fmt.Sprintf("%s failed: rc = %s", err.Op, err.RC)
 	}
+	if err.Message == "" {
+		return fmt.Sprintf("%s failed: rc = %s, ctx.rc = %s",
+			err.Op, err.RC, err.CtxRC)
+	}
+	return fmt.Sprintf("%s failed: rc = %s, ctx.rc = %s, ctx.errbuf = %s",
+		err.Op, err.RC, err.CtxRC, err.Message)
+}
+
+// Unwrap returns the return code of the error.
+// If the operation itself succeeded, Unwrap returns the return code stored in
+// the context instead.
+func (err *Error) Unwrap() error {
+	if (err.RC == Success) && err.hasCtx {
+		return err.CtxRC
+	}
+	return err.RC
 }
 
+// ErrClosed is returned when a closed DB, Table, Column or Cursor is used.
+var ErrClosed = errors.New("grngo: use of closed handle")
+
 // newCError returns an error related to a Groonga or Grngo operation.
 func newCError(opName string, rc C.grn_rc, db *DB) error {
-	if db == nil {
-		return fmt.Errorf("%s failed: rc = %s", opName, rcString(rc))
+	err := &Error{Op: opName, RC: RC(rc)}
+	if (db == nil) || (db.c == nil) {
+		return err
 	}
 	ctx := db.c.ctx
-	if ctx.errbuf[0] == 0 {
-		return fmt.Errorf("%s failed: rc = %s, ctx.rc = %s",
-			opName, rcString(rc), rcString(ctx.rc))
+	err.CtxRC = RC(ctx.rc)
+	if ctx.errbuf[0] != 0 {
+		err.Message = C.GoString(&ctx.errbuf[0])
 	}
-	return fmt.Errorf("%s failed: rc = %s, ctx.rc = %s, ctx.errbuf = %s",
-		opName, rcString(rc), rcString(ctx.rc), C.GoString(&ctx.errbuf[0]))
+	err.hasCtx = true
+	return err
+}
+
+// RowError is an error related to a row in a batch operation.
+//
+// RowError wraps the underlying error, so errors.Is and errors.As see
+// through it.
+type RowError struct {
+	Index int    // The position of the row in the given slices.
+	ID    uint32 // The ID of the row.
+	Err   error  // The underlying error.
+}
+
+// Error returns a string which describes the error.
+func (err *RowError) Error() string {
+	return fmt.Sprintf("row %d (id = %d): %v", err.Index, err.ID, err.Err)
+}
+
+// Unwrap returns the underlying error.
+func (err *RowError) Unwrap() error {
+	return err.Err
 }
 
 // -- Data types --
@@ -200,6 +374,30 @@ type GeoPoint struct {
 	Longitude int32 // Longitude in milliseconds.
 }
 
+// Weighted is an element of a weight vector, that is a vector column created
+// with WithWeight.
+type Weighted[T any] struct {
+	Value  T      // The value, or the key or the ID of a referred record.
+	Weight uint32 // The weight.
+}
+
+// WeightedText is an element of a weight vector of text or of a reference
+// weight vector whose key type is ShortText.
+type WeightedText = Weighted[[]byte]
+
+// isWeightedType returns whether a type is an instance of Weighted.
+func isWeightedType(valueType reflect.Type) bool {
+	return (valueType.Kind() == reflect.Struct) &&
+		(valueType.PkgPath() == reflect.TypeOf(WeightedText{}).PkgPath()) &&
+		strings.HasPrefix(valueType.Name(), "Weighted[")
+}
+
+// isWeightedSliceType returns whether a type is a slice of Weighted.
+func isWeightedSliceType(valueType reflect.Type) bool {
+	return (valueType != nil) && (valueType.Kind() == reflect.Slice) &&
+		isWeightedType(valueType.Elem())
+}
+
 // NilID is an invalid record ID.
 // Some functions return NilID if operations failed.
 const NilID = uint32(C.GRN_ID_NIL)
@@ -210,7 +408,8 @@ const NilID = uint32(C.GRN_ID_NIL)
 type DataType int
 
 // Time (int64) represents the number of microseconds elapsed since the Unix
-// epoch.
+// epoch. time.Time is also accepted and, if DB.SetParseTime is enabled,
+// returned for Time.
 //
 // See http://groonga.org/docs/reference/types.html for details.
 const (
@@ -223,7 +422,7 @@ const (
 	UInt8         = DataType(C.GRN_DB_UINT8)           // int64.
 	UInt16        = DataType(C.GRN_DB_UINT16)          // int64.
 	UInt32        = DataType(C.GRN_DB_UINT32)          // int64.
-	UInt64        = DataType(C.GRN_DB_UINT64)          // int64.
+	UInt64        = DataType(C.GRN_DB_UINT64)          // uint64.
 	Float         = DataType(C.GRN_DB_FLOAT)           // float64.
 	Time          = DataType(C.GRN_DB_TIME)            // int64.
 	ShortText     = DataType(C.GRN_DB_SHORT_TEXT)      // []byte.
@@ -235,6 +434,22 @@ const (
 	LazyGeoPoint                                       // GeoPoint.
 )
 
+// timeToMicros converts a time.Time into the number of microseconds elapsed
+// since the Unix epoch. The sub-microsecond part is truncated.
+func timeToMicros(t time.Time) int64 {
+	return t.Unix()*1000000 + int64(t.Nanosecond()/1000)
+}
+
+// microsToTime converts the number of microseconds elapsed since the Unix
+// epoch into a time.Time.
+func microsToTime(micros int64) time.Time {
+	sec, usec := micros/1000000, micros%1000000
+	if usec < 0 {
+		sec, usec = sec-1, usec+1000000
+	}
+	return time.Unix(sec, usec*1000)
+}
+
 func (dataType DataType) String() string {
 	switch dataType {
 	case Void:
@@ -344,677 +559,4272 @@ func NewColumnOptions() *ColumnOptions {
 	return options
 }
 
-// -- Groonga --
+// -- CursorOptions --
 
-// grnInitFinDisabled shows whther C.grn_init and C.grn_fin are disabled.
-var grnInitFinDisabled = false
+// Flags of CursorOptions accepts a combination of these constants.
+//
+// See http://groonga.org/docs/reference/api/grn_table_cursor.html for details.
+const (
+	CursorAscending  = C.GRN_CURSOR_ASCENDING  // CursorAscending is 0.
+	CursorDescending = C.GRN_CURSOR_DESCENDING // CursorDescending reverses the order.
+	CursorGE         = C.GRN_CURSOR_GE         // CursorGE is 0.
+	CursorGT         = C.GRN_CURSOR_GT         // CursorGT excludes Min.
+	CursorLE         = C.GRN_CURSOR_LE         // CursorLE is 0.
+	CursorLT         = C.GRN_CURSOR_LT         // CursorLT excludes Max.
+	CursorByKey      = C.GRN_CURSOR_BY_KEY     // CursorByKey is 0.
+	CursorByID       = C.GRN_CURSOR_BY_ID      // CursorByID iterates in ID order.
+	CursorPrefix     = C.GRN_CURSOR_PREFIX     // CursorPrefix uses Min as a prefix.
+)
 
-// grnInitCount is an internal counter used in GrnInit and GrnFin.
-var grnInitCount = 0
+// CursorOptions is a set of options for Table.Cursor.
+// Flags is CursorAscending|CursorByKey by default and Limit is -1 (unlimited)
+// by default.
+//
+// Min and Max accept the same key types as Table.InsertRow and nil means that
+// there is no bound. CursorByKey, CursorPrefix and key bounds are available
+// only for TABLE_PAT_KEY and TABLE_DAT_KEY. If CursorPrefix is given, Min is
+// used as the prefix and Max is ignored.
+//
+// See http://groonga.org/docs/reference/api/grn_table_cursor.html for details.
+type CursorOptions struct {
+	Flags  int         // Flags is associated with flags.
+	Min    interface{} // Min is associated with min.
+	Max    interface{} // Max is associated with max.
+	Offset int         // Offset is associated with offset.
+	Limit  int         // Limit is associated with limit.
+}
 
-// DisableGrnInitFin disables calls of C.grn_init and C.grn_fin in GrnInit()
-// and GrnFin().
-// DisableGrnInitFin should be used if you manually or another library
-// initialize and finalize Groonga.
-func DisableGrnInitFin() {
-	grnInitFinDisabled = true
+// NewCursorOptions returns a new CursorOptions with the default settings.
+func NewCursorOptions() *CursorOptions {
+	options := new(CursorOptions)
+	options.Flags = CursorAscending | CursorByKey
+	options.Limit = -1
+	return options
 }
 
-// GrnInit increments an internal counter grnInitCount and if it changes from
-// 0 to 1, calls C.grn_init to initialize Groonga.
+// -- SearchOptions --
+
+// Mode of SearchOptions accepts one of these constants.
 //
-// Note that CreateDB and OpenDB call GrnInit, so you should not manually call
-// GrnInit if not needed.
-func GrnInit() error {
-	if grnInitCount == 0 {
-		if !grnInitFinDisabled {
-			if rc := C.grn_init(); rc != C.GRN_SUCCESS {
-				return newCError("grn_init()", rc, nil)
-			}
-		}
+// See http://groonga.org/docs/reference/api/grn_obj_search.html for details.
+const (
+	SearchExact       = C.GRN_OP_EXACT        // SearchExact is associated with GRN_OP_EXACT.
+	SearchPrefix      = C.GRN_OP_PREFIX       // SearchPrefix is associated with GRN_OP_PREFIX.
+	SearchSuffix      = C.GRN_OP_SUFFIX       // SearchSuffix is associated with GRN_OP_SUFFIX.
+	SearchNear        = C.GRN_OP_NEAR         // SearchNear is associated with GRN_OP_NEAR.
+	SearchSimilar     = C.GRN_OP_SIMILAR      // SearchSimilar is associated with GRN_OP_SIMILAR.
+	SearchTermExtract = C.GRN_OP_TERM_EXTRACT // SearchTermExtract is associated with GRN_OP_TERM_EXTRACT.
+)
+
+// SearchOptions is a set of options for Table.Search.
+// Mode is SearchExact, SimilarityThreshold and MaxInterval are 10 and Limit is
+// -1 (unlimited) by default.
+type SearchOptions struct {
+	Mode                int // Mode is associated with mode.
+	SimilarityThreshold int // SimilarityThreshold is used by SearchSimilar.
+	MaxInterval         int // MaxInterval is used by SearchNear.
+	Offset              int // Offset skips the first hits.
+	Limit               int // Limit limits the number of hits.
+}
+
+// NewSearchOptions returns a new SearchOptions with the default settings.
+func NewSearchOptions() *SearchOptions {
+	options := new(SearchOptions)
+	options.Mode = SearchExact
+	options.SimilarityThreshold = 10
+	options.MaxInterval = 10
+	options.Limit = -1
+	return options
+}
+
+// SearchHit is a row found by Table.Search.
+type SearchHit struct {
+	ID    uint32  // The ID of the row.
+	Score float64 // The score of the row (_score).
+}
+
+// -- Schema --
+
+// ColumnType is an enumeration of column types.
+//
+// See http://groonga.org/docs/reference/commands/column_create.html#flags for details.
+type ColumnType int
+
+const (
+	ScalarColumn = ColumnType(C.GRN_OBJ_COLUMN_SCALAR) // COLUMN_SCALAR.
+	VectorColumn = ColumnType(C.GRN_OBJ_COLUMN_VECTOR) // COLUMN_VECTOR.
+	IndexColumn  = ColumnType(C.GRN_OBJ_COLUMN_INDEX)  // COLUMN_INDEX.
+)
+
+func (columnType ColumnType) String() string {
+	switch columnType {
+	case ScalarColumn:
+		return "COLUMN_SCALAR"
+	case VectorColumn:
+		return "COLUMN_VECTOR"
+	case IndexColumn:
+		return "COLUMN_INDEX"
+	default:
+		return fmt.Sprintf("ColumnType(%d)", columnType)
 	}
-	grnInitCount++
-	return nil
 }
 
-// GrnFin decrements an internal counter grnInitCount and if it changes from
-// 1 to 0, calls C.grn_fin to finalize Groonga.
+// TableInfo describes a table.
 //
-// Note that DB.Close calls GrnFin, so you should not manually call GrnFin if
-// not needed.
-func GrnFin() error {
-	switch grnInitCount {
-	case 0:
-		return fmt.Errorf("Groonga is not initialized yet")
-	case 1:
-		if !grnInitFinDisabled {
-			if rc := C.grn_fin(); rc != C.GRN_SUCCESS {
-				return newCError("grn_fin()", rc, nil)
-			}
+// The embedded TableOptions has the same meaning as the options for
+// CreateTable. KeyType is empty if the table is TABLE_NO_KEY.
+type TableInfo struct {
+	Name string // The table name.
+	TableOptions
+}
+
+// ColumnInfo describes a column.
+//
+// The embedded ColumnOptions has the same meaning as the options for
+// CreateColumn.
+type ColumnInfo struct {
+	Name      string     // The column name.
+	Table     string     // The owner table name.
+	Type      ColumnType // ScalarColumn, VectorColumn or IndexColumn.
+	ValueType string     // The value type name, e.g. "ShortText" or "Table".
+	Sources   []string   // The source column names of an index column.
+	ColumnOptions
+}
+
+// TypeString returns the valueType parameter of CreateColumn for the column,
+// e.g. "[]ShortText" or "Table.source".
+func (info *ColumnInfo) TypeString() string {
+	switch info.Type {
+	case VectorColumn:
+		return "[]" + info.ValueType
+	case IndexColumn:
+		if len(info.Sources) == 0 {
+			return info.ValueType
 		}
+		return info.ValueType + "." + strings.Join(info.Sources, ",")
+	default:
+		return info.ValueType
 	}
-	grnInitCount--
-	return nil
 }
 
-// -- DB --
+// schemaObject is a reference to an object in the result of schema.
+type schemaObject struct {
+	Name string `json:"name"`
+}
 
-// DB is associated with a Groonga database with its context.
-type DB struct {
-	c      *C.grngo_db       // The associated C object.
-	tables map[string]*Table // A cache to find tables by name.
+// schemaCommand is a command to create an object in the result of schema.
+type schemaCommand struct {
+	Arguments map[string]string `json:"arguments"`
 }
 
-// newDB returns a new DB.
-func newDB(c *C.grngo_db) *DB {
-	db := new(DB)
-	db.c = c
-	db.tables = make(map[string]*Table)
-	return db
+// schemaColumn is a column in the result of schema.
+type schemaColumn struct {
+	Name      string         `json:"name"`
+	Table     string         `json:"table"`
+	Type      string         `json:"type"`
+	ValueType *schemaObject  `json:"value_type"`
+	Compress  *string        `json:"compress"`
+	Section   bool           `json:"section"`
+	Weight    bool           `json:"weight"`
+	Position  bool           `json:"position"`
+	Sources   []schemaObject `json:"sources"`
 }
 
-// CreateDB creates a Groonga database and returns a new DB associated with it.
-// If path is empty, CreateDB creates a temporary database.
-//
-// Note that CreateDB initializes Groonga if the new DB will be the only one
-// and implicit initialization is not disabled.
-func CreateDB(path string) (*DB, error) {
-	if err := GrnInit(); err != nil {
-		return nil, err
+// schemaTable is a table in the result of schema.
+type schemaTable struct {
+	Name         string                   `json:"name"`
+	Type         string                   `json:"type"`
+	KeyType      *schemaObject            `json:"key_type"`
+	ValueType    *schemaObject            `json:"value_type"`
+	Tokenizer    *schemaObject            `json:"tokenizer"`
+	Normalizer   *schemaObject            `json:"normalizer"`
+	TokenFilters []schemaObject           `json:"token_filters"`
+	Command      *schemaCommand           `json:"command"`
+	Columns      map[string]*schemaColumn `json:"columns"`
+}
+
+// schemaResult is the result of schema.
+type schemaResult struct {
+	Tables map[string]*schemaTable `json:"tables"`
+}
+
+// tableInfo converts a table in the result of schema into a TableInfo.
+func (table *schemaTable) tableInfo() (*TableInfo, error) {
+	info := new(TableInfo)
+	info.Name = table.Name
+	switch table.Type {
+	case "array":
+		info.Flags = TableNoKey
+	case "hash table":
+		info.Flags = TableHashKey
+	case "patricia trie":
+		info.Flags = TablePatKey
+	case "double array trie":
+		info.Flags = TableDatKey
+	default:
+		return nil, fmt.Errorf("unknown table type: name = <%s>, type = <%s>",
+			table.Name, table.Type)
 	}
-	pathBytes := []byte(path)
-	var cPath *C.char
-	if len(pathBytes) != 0 {
-		cPath = (*C.char)(unsafe.Pointer(&pathBytes[0]))
+	if table.Command != nil {
+		for _, flag := range strings.Split(table.Command.Arguments["flags"], "|") {
+			if flag == "KEY_WITH_SIS" {
+				info.Flags |= KeyWithSIS
+			}
+		}
 	}
-	var c *C.grngo_db
-	rc := C.grngo_create_db(cPath, C.size_t(len(pathBytes)), &c)
-	if rc != C.GRN_SUCCESS {
-		GrnFin()
-		return nil, newCError("grngo_create_db()", rc, nil)
+	if table.KeyType != nil {
+		info.KeyType = table.KeyType.Name
 	}
-	return newDB(c), nil
-}
-
-// OpenDB opens an existing Groonga database and returns a new DB associated
-// with it.
-//
-// Note that CreateDB initializes Groonga if the new DB will be the only one
-// and implicit initialization is not disabled.
-func OpenDB(path string) (*DB, error) {
-	if err := GrnInit(); err != nil {
-		return nil, err
+	if table.ValueType != nil {
+		info.ValueType = table.ValueType.Name
 	}
-	pathBytes := []byte(path)
-	var cPath *C.char
-	if len(pathBytes) != 0 {
-		cPath = (*C.char)(unsafe.Pointer(&pathBytes[0]))
+	if table.Tokenizer != nil {
+		info.DefaultTokenizer = table.Tokenizer.Name
 	}
-	var c *C.grngo_db
-	rc := C.grngo_open_db(cPath, C.size_t(len(pathBytes)), &c)
-	if rc != C.GRN_SUCCESS {
-		GrnFin()
-		return nil, newCError("grngo_open_db()", rc, nil)
+	if table.Normalizer != nil {
+		info.Normalizer = table.Normalizer.Name
 	}
-	return newDB(c), nil
-}
-
-// Close finalizes a DB.
-func (db *DB) Close() error {
-	C.grngo_close_db(db.c)
-	return GrnFin()
+	for _, tokenFilter := range table.TokenFilters {
+		info.TokenFilters = append(info.TokenFilters, tokenFilter.Name)
+	}
+	return info, nil
 }
 
-// Refresh clears maps for Table and Column name resolution.
-//
-// If a table or column is renamed or removed, old maps can cause a name
-// resolution error. In such a case, you should use Refresh or reopen the
-// Groonga database to resolve it.
-func (db *DB) Refresh() error {
-	for _, table := range db.tables {
-		for _, column := range table.columns {
-			C.grngo_close_column(column.c)
+// columnInfo converts a column in the result of schema into a ColumnInfo.
+func (column *schemaColumn) columnInfo() (*ColumnInfo, error) {
+	info := new(ColumnInfo)
+	info.Name = column.Name
+	info.Table = column.Table
+	switch column.Type {
+	case "scalar":
+		info.Type = ScalarColumn
+	case "vector":
+		info.Type = VectorColumn
+	case "index":
+		info.Type = IndexColumn
+	default:
+		return nil, fmt.Errorf("unknown column type: name = <%s.%s>, type = <%s>",
+			column.Table, column.Name, column.Type)
+	}
+	if column.ValueType != nil {
+		info.ValueType = column.ValueType.Name
+	}
+	for _, source := range column.Sources {
+		info.Sources = append(info.Sources, source.Name)
+	}
+	info.Flags = CompressNone
+	if column.Compress != nil {
+		switch *column.Compress {
+		case "zlib":
+			info.Flags |= CompressZlib
+		case "lz4":
+			info.Flags |= CompressLZ4
 		}
-		table.columns = make(map[string]*Column)
-		C.grngo_close_table(table.c)
 	}
-	db.tables = make(map[string]*Table)
-	return nil
+	if column.Section {
+		info.Flags |= WithSection
+	}
+	if column.Weight {
+		info.Flags |= WithWeight
+	}
+	if column.Position {
+		info.Flags |= WithPosition
+	}
+	return info, nil
 }
 
-// Send executes a Groonga command.
-// The command must be well-formed.
+// querySchema executes schema and returns the result.
 //
-// See http://groonga.org/docs/reference/command.html for details.
-func (db *DB) Send(command string) error {
-	command = strings.TrimSpace(command)
-	if strings.HasPrefix(command, "table_remove") ||
-		strings.HasPrefix(command, "table_rename") ||
-		strings.HasPrefix(command, "column_remove") ||
-		strings.HasPrefix(command, "column_rename") {
-		db.Refresh()
-	}
-	commandBytes := []byte(command)
-	var cCommand *C.char
-	if len(commandBytes) != 0 {
-		cCommand = (*C.char)(unsafe.Pointer(&commandBytes[0]))
+// See http://groonga.org/docs/reference/commands/schema.html for details.
+func (db *DB) querySchema() (*schemaResult, error) {
+	bytes, err := db.Query("schema")
+	if err != nil {
+		return nil, err
 	}
-	rc := C.grngo_send(db.c, cCommand, C.size_t(len(commandBytes)))
-	if rc != C.GRN_SUCCESS {
-		return newCError("grngo_send()", rc, db)
+	result := new(schemaResult)
+	if err := json.Unmarshal(bytes, result); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal() failed: %v", err)
 	}
-	return nil
+	return result, nil
 }
 
-// SendEx executes a Groonga command with separated options.
-//
-// See http://groonga.org/docs/reference/command.html for details.
-func (db *DB) SendEx(name string, options map[string]string) error {
-	if name == "" {
-		return fmt.Errorf("invalid command: name = <%s>", name)
+// -- Schema from struct --
+
+// groongaTypeName returns the Groonga type name for a Go type.
+// text is the type name for string and []byte.
+func groongaTypeName(valueType reflect.Type, text string) (string, error) {
+	for valueType.Kind() == reflect.Ptr {
+		valueType = valueType.Elem()
 	}
-	for _, r := range name {
-		if (r != '_') && (r < 'a') && (r > 'z') {
-			return fmt.Errorf("invalid command: name = <%s>", name)
-		}
+	switch valueType {
+	case reflect.TypeOf(time.Time{}):
+		return "Time", nil
+	case reflect.TypeOf(GeoPoint{}):
+		return "WGS84GeoPoint", nil
 	}
-	commandParts := []string{name}
-	for key, value := range options {
-		if key == "" {
-			return fmt.Errorf("invalid option: key = <%s>", key)
+	switch valueType.Kind() {
+	case reflect.Bool:
+		return "Bool", nil
+	case reflect.Int8:
+		return "Int8", nil
+	case reflect.Int16:
+		return "Int16", nil
+	case reflect.Int32:
+		return "Int32", nil
+	case reflect.Int, reflect.Int64:
+		return "Int64", nil
+	case reflect.Uint8:
+		return "UInt8", nil
+	case reflect.Uint16:
+		return "UInt16", nil
+	case reflect.Uint32:
+		return "UInt32", nil
+	case reflect.Uint, reflect.Uint64:
+		return "UInt64", nil
+	case reflect.Float32, reflect.Float64:
+		return "Float", nil
+	case reflect.String:
+		return text, nil
+	case reflect.Slice:
+		if valueType.Elem().Kind() == reflect.Uint8 {
+			return text, nil
 		}
-		for _, r := range key {
-			if (r != '_') && (r < 'a') && (r > 'z') {
-				return fmt.Errorf("invalid option: key = <%s>", key)
-			}
+		elem := valueType.Elem()
+		if isWeightedType(elem) {
+			elem = elem.Field(0).Type
 		}
-		value = strings.Replace(value, "\\", "\\\\", -1)
-		value = strings.Replace(value, "'", "\\'", -1)
-		commandParts = append(commandParts, fmt.Sprintf("--%s '%s'", key, value))
+		elemType, err := groongaTypeName(elem, "ShortText")
+		if err != nil {
+			return "", err
+		}
+		if strings.HasPrefix(elemType, "[]") {
+			return "", fmt.Errorf("unsupported data type: %s: %w", valueType,
+				ErrInvalidArgument)
+		}
+		return "[]" + elemType, nil
 	}
-	return db.Send(strings.Join(commandParts, " "))
+	return "", fmt.Errorf("unsupported data type: %s: %w", valueType,
+		ErrInvalidArgument)
 }
 
-// Recv returns the result of Groonga commands executed by Send and SendEx.
-//
-// See http://groonga.org/docs/reference/command.html for details.
-func (db *DB) Recv() ([]byte, error) {
-	var res *C.char
-	var resLen C.uint
-	rc := C.grngo_recv(db.c, &res, &resLen)
-	if rc != C.GRN_SUCCESS {
-		return nil, newCError("grngo_recv()", rc, db)
+// isTextType returns whether a type name is a text type.
+func isTextType(typeName string) bool {
+	switch strings.TrimPrefix(typeName, "[]") {
+	case "ShortText", "Text", "LongText":
+		return true
 	}
-	return C.GoBytes(unsafe.Pointer(res), C.int(resLen)), nil
+	return false
 }
 
-// Query executes a Groonga command and returns the result.
-//
-// See http://groonga.org/docs/reference/command.html for details.
-func (db *DB) Query(command string) ([]byte, error) {
-	if err := db.Send(command); err != nil {
-		result, _ := db.Recv()
-		return result, err
+// schemaFromStruct returns the tables and the columns for a struct type.
+// The first table is the table for the struct and the others are lexicons.
+func schemaFromStruct(name string, valueType reflect.Type) ([]*TableInfo, []*ColumnInfo, error) {
+	if valueType == nil {
+		return nil, nil, fmt.Errorf("nil value: %w", ErrInvalidArgument)
 	}
-	return db.Recv()
-}
-
-// Query executes a Groonga command with separated options and returns the
-// result.
+	for valueType.Kind() == reflect.Ptr {
+		valueType = valueType.Elem()
+	}
+	if valueType.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("not a struct: %s: %w", valueType, ErrInvalidArgument)
+	}
+	fields, err := structFields(valueType)
+	if err != nil {
+		return nil, nil, err
+	}
+	table := &TableInfo{Name: name}
+	table.Flags = TableNoKey
+	tables := []*TableInfo{table}
+	var columns []*ColumnInfo
+	var indexes []*ColumnInfo
+	for _, field := range fields {
+		fieldType := valueType.Field(field.index).Type
+		typeName := field.options["type"]
+		switch field.name {
+		case "_id":
+			continue
+		case "_key":
+			if typeName == "" {
+				if typeName, err = groongaTypeName(fieldType, "ShortText"); err != nil {
+					return nil, nil, err
+				}
+			}
+			switch field.options["table"] {
+			case "", "hash":
+				table.Flags = TableHashKey
+			case "pat":
+				table.Flags = TablePatKey
+			case "dat":
+				table.Flags = TableDatKey
+			default:
+				return nil, nil, fmt.Errorf("unknown table type: %s: %w",
+					field.options["table"], ErrInvalidArgument)
+			}
+			table.KeyType = typeName
+			table.DefaultTokenizer = field.options["tokenizer"]
+			table.Normalizer = field.options["normalizer"]
+			continue
+		}
+		column := &ColumnInfo{Name: field.name, Table: name, Type: ScalarColumn}
+		if isWeightedSliceType(fieldType) {
+			column.Flags |= WithWeight
+		}
+		if typeName == "" {
+			if typeName, err = groongaTypeName(fieldType, "Text"); err != nil {
+				return nil, nil, fmt.Errorf("column = %s: type is required: %w",
+					field.name, err)
+			}
+		}
+		column.ValueType = typeName
+		if strings.HasPrefix(typeName, "[]") {
+			column.Type = VectorColumn
+			column.ValueType = typeName[2:]
+		}
+		switch field.options["compress"] {
+		case "":
+		case "zlib":
+			column.Flags |= CompressZlib
+		case "lz4":
+			column.Flags |= CompressLZ4
+		default:
+			return nil, nil, fmt.Errorf("unknown compression type: %s: %w",
+				field.options["compress"], ErrInvalidArgument)
+		}
+		columns = append(columns, column)
+		if index, ok := field.options["index"]; ok {
+			// index=Lexicon[:Tokenizer]
+			lexiconName, tokenizer := index, ""
+			if delimPos := strings.IndexByte(index, ':'); delimPos != -1 {
+				lexiconName, tokenizer = index[:delimPos], index[delimPos+1:]
+			}
+			if lexiconName == "" {
+				return nil, nil, fmt.Errorf("column = %s: invalid index: %s: %w",
+					field.name, index, ErrInvalidArgument)
+			}
+			lexicon := &TableInfo{Name: lexiconName}
+			lexicon.Flags = TablePatKey
+			lexicon.KeyType = column.ValueType
+			lexicon.DefaultTokenizer = tokenizer
+			if isTextType(column.ValueType) {
+				lexicon.KeyType = "ShortText"
+				lexicon.Normalizer = "NormalizerAuto"
+			}
+			tables = append(tables, lexicon)
+			indexColumn := &ColumnInfo{
+				Name:      name + "_" + field.name,
+				Table:     lexiconName,
+				Type:      IndexColumn,
+				ValueType: name,
+				Sources:   []string{field.name},
+			}
+			if tokenizer != "" {
+				indexColumn.Flags |= WithPosition
+			}
+			indexes = append(indexes, indexColumn)
+		}
+	}
+	return tables, append(columns, indexes...), nil
+}
+
+// tableInfoEqual returns whether two tables have the same definition.
+func tableInfoEqual(lhs, rhs *TableInfo) bool {
+	return (lhs.Name == rhs.Name) && (lhs.Flags == rhs.Flags) &&
+		(lhs.KeyType == rhs.KeyType) && (lhs.ValueType == rhs.ValueType) &&
+		(lhs.DefaultTokenizer == rhs.DefaultTokenizer) &&
+		(lhs.Normalizer == rhs.Normalizer) &&
+		(strings.Join(lhs.TokenFilters, ",") == strings.Join(rhs.TokenFilters, ","))
+}
+
+// columnInfoEqual returns whether two columns have the same definition.
+// The sources of index columns are compared in order.
+func columnInfoEqual(lhs, rhs *ColumnInfo) bool {
+	return (lhs.Name == rhs.Name) && (lhs.Table == rhs.Table) &&
+		(lhs.Type == rhs.Type) && (lhs.ValueType == rhs.ValueType) &&
+		(strings.Join(lhs.Sources, ",") == strings.Join(rhs.Sources, ",")) &&
+		(lhs.Flags == rhs.Flags)
+}
+
+// CreateSchemaFromStruct creates a table and its columns from the tagged
+// fields of a struct type, given as a value of the type or a pointer to it.
 //
-// See http://groonga.org/docs/reference/command.html for details.
-func (db *DB) QueryEx(name string, options map[string]string) (
-	[]byte, error) {
-	if err := db.SendEx(name, options); err != nil {
-		result, _ := db.Recv()
-		return result, err
+// The "_key" field determines the key type, or the table is TABLE_NO_KEY
+// without it. Its tag accepts "table=hash|pat|dat", "type=...",
+// "tokenizer=..." and "normalizer=...". The other tagged fields are columns.
+// Their tags accept "type=...", required for references, "compress=zlib|lz4"
+// and "index=Lexicon[:Tokenizer]", e.g.
+//
+//	Title string `grngo:"title,type=Text,index=Terms:TokenBigram"`
+//
+// creates a column title and an index column Terms.Table_title, and also
+// a lexicon Terms if it does not exist. Types are derived from the field types
+// if not given, e.g. string is Text and []string is []ShortText.
+//
+// Existing tables and columns are kept if they have the same definitions, or
+// CreateSchemaFromStruct fails without creating anything otherwise.
+func (db *DB) CreateSchemaFromStruct(name string, value interface{}) (*Table, error) {
+	tables, columns, err := schemaFromStruct(name, reflect.TypeOf(value))
+	if err != nil {
+		return nil, err
 	}
-	return db.Recv()
+	schema, err := db.querySchema()
+	if err != nil {
+		return nil, err
+	}
+	existingTables := make(map[string]*TableInfo)
+	existingColumns := make(map[string]*ColumnInfo)
+	for _, schemaTable := range schema.Tables {
+		info, err := schemaTable.tableInfo()
+		if err != nil {
+			return nil, err
+		}
+		existingTables[info.Name] = info
+		for _, schemaColumn := range schemaTable.Columns {
+			info, err := schemaColumn.columnInfo()
+			if err != nil {
+				return nil, err
+			}
+			existingColumns[info.Table+"."+info.Name] = info
+		}
+	}
+	// Check all the conflicts before creating anything.
+	var newTables []*TableInfo
+	var newColumns []*ColumnInfo
+	for _, info := range tables {
+		if existing, ok := existingTables[info.Name]; ok {
+			if !tableInfoEqual(existing, info) {
+				return nil, fmt.Errorf("table conflicts: name = <%s>: %w",
+					info.Name, ErrInvalidArgument)
+			}
+			continue
+		}
+		newTables = append(newTables, info)
+		existingTables[info.Name] = info
+	}
+	for _, info := range columns {
+		if existing, ok := existingColumns[info.Table+"."+info.Name]; ok {
+			if !columnInfoEqual(existing, info) {
+				return nil, fmt.Errorf("column conflicts: name = <%s.%s>: %w",
+					info.Table, info.Name, ErrInvalidArgument)
+			}
+			continue
+		}
+		newColumns = append(newColumns, info)
+		existingColumns[info.Table+"."+info.Name] = info
+	}
+	for _, info := range newTables {
+		options := info.TableOptions
+		if _, err := db.CreateTable(info.Name, &options); err != nil {
+			return nil, err
+		}
+	}
+	for _, info := range newColumns {
+		options := info.ColumnOptions
+		if _, err := db.CreateColumn(info.Table, info.Name, info.TypeString(), &options); err != nil {
+			return nil, err
+		}
+	}
+	return db.FindTable(name)
 }
 
-// createTableOptionsMap creates an options map for table_create.
+// -- Schema migration --
+
+// Schema is a set of table and column definitions.
 //
-// See http://groonga.org/docs/reference/commands/table_create.html#parameters for details.
-func (db *DB) createTableOptionsMap(name string, options *TableOptions) (map[string]string, error) {
-	optionsMap := make(map[string]string)
-	// http://groonga.org/docs/reference/commands/table_create.html#name
-	optionsMap["name"] = name
-	// http://groonga.org/docs/reference/commands/table_create.html#flags
-	if options.KeyType == "" {
-		optionsMap["flags"] = "TABLE_NO_KEY"
-	} else {
-		switch options.Flags & TableTypeMask {
-		case TableNoKey:
-			optionsMap["flags"] = "TABLE_NO_KEY"
-		case TableHashKey:
-			optionsMap["flags"] = "TABLE_HASH_KEY"
-		case TablePatKey:
-			optionsMap["flags"] = "TABLE_PAT_KEY"
-		case TableDatKey:
-			optionsMap["flags"] = "TABLE_DAT_KEY"
-		default:
-			return nil, fmt.Errorf("undefined table type: options = %+v", options)
+// RenamedColumns maps "Table.new_name" to an old column name so that Diff
+// renames the old column instead of removing it and adding a new one.
+type Schema struct {
+	Tables         []*TableInfo      // Tables.
+	Columns        []*ColumnInfo     // Columns, including index columns.
+	RenamedColumns map[string]string // Renamed columns for Diff.
+}
+
+// Schema returns the current schema.
+func (db *DB) Schema() (*Schema, error) {
+	result, err := db.querySchema()
+	if err != nil {
+		return nil, err
+	}
+	schema := new(Schema)
+	for _, schemaTable := range result.Tables {
+		info, err := schemaTable.tableInfo()
+		if err != nil {
+			return nil, err
+		}
+		schema.Tables = append(schema.Tables, info)
+		for _, schemaColumn := range schemaTable.Columns {
+			info, err := schemaColumn.columnInfo()
+			if err != nil {
+				return nil, err
+			}
+			schema.Columns = append(schema.Columns, info)
 		}
 	}
-	if (options.Flags & KeyWithSIS) == KeyWithSIS {
-		optionsMap["flags"] += "|KEY_WITH_SIS"
+	sort.Slice(schema.Tables, func(i, j int) bool {
+		return schema.Tables[i].Name < schema.Tables[j].Name
+	})
+	sort.Slice(schema.Columns, func(i, j int) bool {
+		lhs, rhs := schema.Columns[i], schema.Columns[j]
+		return (lhs.Table < rhs.Table) || ((lhs.Table == rhs.Table) && (lhs.Name < rhs.Name))
+	})
+	return schema, nil
+}
+
+// SchemaFromStruct returns the schema that CreateSchemaFromStruct creates.
+func SchemaFromStruct(name string, value interface{}) (*Schema, error) {
+	tables, columns, err := schemaFromStruct(name, reflect.TypeOf(value))
+	if err != nil {
+		return nil, err
 	}
-	// http://groonga.org/docs/reference/commands/table_create.html#key-type
-	switch options.KeyType {
-	case "":
-	case "Bool", "Int8", "Int16", "Int32", "Int64", "UInt8", "UInt16",
-		"UInt32", "UInt64", "Float", "Time", "ShortText", "TokyoGeoPoint",
-		"WGS84GeoPoint":
-		optionsMap["key_type"] = options.KeyType
-	default:
-		if _, err := db.FindTable(options.KeyType); err != nil {
-			return nil, fmt.Errorf("invalid key type: options = %+v", options)
+	return &Schema{Tables: tables, Columns: columns}, nil
+}
+
+// Merge adds the tables, the columns and the renamed columns of other.
+// Merge fails if the same table or column has different definitions.
+func (schema *Schema) Merge(other *Schema) error {
+	tables := make(map[string]*TableInfo)
+	for _, info := range schema.Tables {
+		tables[info.Name] = info
+	}
+	for _, info := range other.Tables {
+		if existing, ok := tables[info.Name]; ok {
+			if !tableInfoEqual(existing, info) {
+				return fmt.Errorf("table conflicts: name = <%s>: %w",
+					info.Name, ErrInvalidArgument)
+			}
+			continue
 		}
-		optionsMap["key_type"] = options.KeyType
+		tables[info.Name] = info
+		schema.Tables = append(schema.Tables, info)
 	}
-	// http://groonga.org/docs/reference/commands/table_create.html#value-type
-	switch options.ValueType {
-	case "":
-	case "Bool", "Int8", "Int16", "Int32", "Int64", "UInt8", "UInt16",
-		"UInt32", "UInt64", "Float", "Time", "TokyoGeoPoint", "WGS84GeoPoint":
-		optionsMap["value_type"] = options.ValueType
+	columns := make(map[string]*ColumnInfo)
+	for _, info := range schema.Columns {
+		columns[info.Table+"."+info.Name] = info
+	}
+	for _, info := range other.Columns {
+		if existing, ok := columns[info.Table+"."+info.Name]; ok {
+			if !columnInfoEqual(existing, info) {
+				return fmt.Errorf("column conflicts: name = <%s.%s>: %w",
+					info.Table, info.Name, ErrInvalidArgument)
+			}
+			continue
+		}
+		columns[info.Table+"."+info.Name] = info
+		schema.Columns = append(schema.Columns, info)
+	}
+	for key, value := range other.RenamedColumns {
+		if schema.RenamedColumns == nil {
+			schema.RenamedColumns = make(map[string]string)
+		}
+		schema.RenamedColumns[key] = value
+	}
+	return nil
+}
+
+// SchemaChangeType is a type of schema changes.
+type SchemaChangeType int
+
+// Schema change types.
+const (
+	SchemaAddTable     = SchemaChangeType(iota) // table_create.
+	SchemaRemoveTable                           // table_remove.
+	SchemaChangeTable                           // table_remove and table_create.
+	SchemaAddColumn                             // column_create.
+	SchemaRemoveColumn                          // column_remove.
+	SchemaChangeColumn                          // column_create, column_copy, column_remove and column_rename.
+	SchemaRenameColumn                          // column_rename.
+)
+
+func (changeType SchemaChangeType) String() string {
+	switch changeType {
+	case SchemaAddTable:
+		return "AddTable"
+	case SchemaRemoveTable:
+		return "RemoveTable"
+	case SchemaChangeTable:
+		return "ChangeTable"
+	case SchemaAddColumn:
+		return "AddColumn"
+	case SchemaRemoveColumn:
+		return "RemoveColumn"
+	case SchemaChangeColumn:
+		return "ChangeColumn"
+	case SchemaRenameColumn:
+		return "RenameColumn"
 	default:
-		if _, err := db.FindTable(options.ValueType); err != nil {
-			return nil, fmt.Errorf("invalid value type: options = %+v", options)
+		return fmt.Sprintf("SchemaChangeType(%d)", changeType)
+	}
+}
+
+// SchemaChange is a schema change.
+//
+// OldTable and OldColumn are the current definitions, and NewTable and
+// NewColumn are the desired definitions. Unused ones are nil.
+type SchemaChange struct {
+	Type      SchemaChangeType
+	OldTable  *TableInfo
+	NewTable  *TableInfo
+	OldColumn *ColumnInfo
+	NewColumn *ColumnInfo
+	DataLoss  bool // Whether the change loses records or values.
+}
+
+func (change *SchemaChange) String() string {
+	switch change.Type {
+	case SchemaAddTable:
+		return fmt.Sprintf("%s %s", change.Type, change.NewTable.Name)
+	case SchemaRemoveTable, SchemaChangeTable:
+		return fmt.Sprintf("%s %s", change.Type, change.OldTable.Name)
+	case SchemaAddColumn:
+		return fmt.Sprintf("%s %s.%s", change.Type,
+			change.NewColumn.Table, change.NewColumn.Name)
+	case SchemaRemoveColumn:
+		return fmt.Sprintf("%s %s.%s", change.Type,
+			change.OldColumn.Table, change.OldColumn.Name)
+	case SchemaChangeColumn:
+		return fmt.Sprintf("%s %s.%s: %s -> %s", change.Type,
+			change.OldColumn.Table, change.OldColumn.Name,
+			change.OldColumn.TypeString(), change.NewColumn.TypeString())
+	case SchemaRenameColumn:
+		return fmt.Sprintf("%s %s.%s -> %s", change.Type,
+			change.OldColumn.Table, change.OldColumn.Name, change.NewColumn.Name)
+	default:
+		return change.Type.String()
+	}
+}
+
+// sortTablesByDependency sorts tables so that a table comes after the
+// tables referred to by its key type or value type.
+func sortTablesByDependency(infos []*TableInfo) []*TableInfo {
+	pending := make(map[string]*TableInfo)
+	for _, info := range infos {
+		pending[info.Name] = info
+	}
+	var sorted []*TableInfo
+	for len(sorted) < len(infos) {
+		progress := false
+		for _, info := range infos {
+			if _, ok := pending[info.Name]; !ok {
+				continue
+			}
+			_, keyPending := pending[info.KeyType]
+			_, valuePending := pending[info.ValueType]
+			if (keyPending && (info.KeyType != info.Name)) ||
+				(valuePending && (info.ValueType != info.Name)) {
+				continue
+			}
+			delete(pending, info.Name)
+			sorted = append(sorted, info)
+			progress = true
+		}
+		if !progress {
+			// Circular references are left as they are.
+			for _, info := range infos {
+				if _, ok := pending[info.Name]; ok {
+					delete(pending, info.Name)
+					sorted = append(sorted, info)
+				}
+			}
 		}
-		optionsMap["value_type"] = options.ValueType
 	}
-	// http://groonga.org/docs/reference/commands/table_create.html#default-tokenizer
-	if options.DefaultTokenizer != "" {
-		optionsMap["default_tokenizer"] = options.DefaultTokenizer
+	return sorted
+}
+
+// SchemaDiffOptions is a set of options for DiffSchema.
+// By default, tables not in desired are left as they are and changes that
+// lose data are rejected.
+type SchemaDiffOptions struct {
+	RemoveTables  bool // Whether to remove tables not in desired.
+	AllowDataLoss bool // Whether to allow changes that lose data.
+}
+
+// DiffSchema returns the changes from current to desired in the order to
+// apply them.
+//
+// Columns are removed before tables, tables are created before columns, and
+// index columns are created last. A table with a different definition, such
+// as a lexicon with a different tokenizer or normalizer, is recreated with
+// its columns, and index columns whose lexicons or sources are recreated,
+// changed or removed are recreated, which means that the indexes are rebuilt.
+// A column with a different definition is changed by copying values.
+//
+// Only the tables in desired and their columns are compared unless
+// options.RemoveTables is true. Index columns of other tables are kept, or
+// rebuilt as they are if needed.
+//
+// Removing or recreating a table other than a lexicon, that is a table with
+// keys and only index columns, and removing a data column lose data. Such
+// changes have DataLoss, and DiffSchema returns the changes with an error
+// wrapping ErrOperationNotPermitted unless options.AllowDataLoss is true.
+func DiffSchema(current, desired *Schema, options *SchemaDiffOptions) ([]*SchemaChange, error) {
+	if options == nil {
+		options = new(SchemaDiffOptions)
 	}
-	// http://groonga.org/docs/reference/commands/table_create.html#normalizer
-	if options.Normalizer != "" {
-		optionsMap["normalizer"] = options.Normalizer
+	curTables := make(map[string]*TableInfo)
+	for _, info := range current.Tables {
+		curTables[info.Name] = info
+	}
+	desTables := make(map[string]*TableInfo)
+	for _, info := range desired.Tables {
+		desTables[info.Name] = info
+	}
+	curColumns := make(map[string]*ColumnInfo)
+	for _, info := range current.Columns {
+		curColumns[info.Table+"."+info.Name] = info
+	}
+	desColumns := make(map[string]*ColumnInfo)
+	for _, info := range desired.Columns {
+		desColumns[info.Table+"."+info.Name] = info
+	}
+	inScope := func(name string) bool {
+		return options.RemoveTables || (desTables[name] != nil)
+	}
+	// An index column is out of scope if its source table is.
+	columnInScope := func(info *ColumnInfo) bool {
+		return inScope(info.Table) && ((info.Type != IndexColumn) || inScope(info.ValueType))
+	}
+	hasIndex := make(map[string]bool)
+	hasData := make(map[string]bool)
+	for _, info := range current.Columns {
+		if info.Type == IndexColumn {
+			hasIndex[info.Table] = true
+		} else {
+			hasData[info.Table] = true
+		}
+	}
+	isLexicon := func(info *TableInfo) bool {
+		return (info.KeyType != "") && hasIndex[info.Name] && !hasData[info.Name]
+	}
+
+	// Tables to be removed or recreated.
+	var removedTables, addedTables []*TableInfo
+	changedTables := make(map[string]*TableInfo)
+	droppedTables := make(map[string]bool)
+	for _, info := range current.Tables {
+		if !inScope(info.Name) {
+			continue
+		}
+		if desInfo, ok := desTables[info.Name]; !ok {
+			removedTables = append(removedTables, info)
+			droppedTables[info.Name] = true
+		} else if !tableInfoEqual(info, desInfo) {
+			changedTables[info.Name] = info
+			droppedTables[info.Name] = true
+		}
+	}
+	for _, info := range desired.Tables {
+		if _, ok := curTables[info.Name]; !ok || (changedTables[info.Name] != nil) {
+			addedTables = append(addedTables, info)
+		}
+	}
+
+	// Data columns to be renamed, changed, removed or added.
+	var renamed, changed, removed, added []*SchemaChange
+	renamedFrom := make(map[string]bool)
+	touched := make(map[string]bool) // Sources to rebuild indexes.
+	for _, desInfo := range desired.Columns {
+		key := desInfo.Table + "." + desInfo.Name
+		if (desInfo.Type == IndexColumn) || droppedTables[desInfo.Table] {
+			continue
+		}
+		curInfo, ok := curColumns[key]
+		if !ok {
+			oldName := desired.RenamedColumns[key]
+			oldKey := desInfo.Table + "." + oldName
+			if oldInfo, ok := curColumns[oldKey]; ok && (oldName != "") &&
+				(desColumns[oldKey] == nil) && (oldInfo.Type != IndexColumn) {
+				renamedFrom[oldKey] = true
+				touched[oldKey] = true
+				renamed = append(renamed, &SchemaChange{Type: SchemaRenameColumn,
+					OldColumn: oldInfo, NewColumn: desInfo})
+				newInfo := *oldInfo
+				newInfo.Name = desInfo.Name
+				curInfo = &newInfo
+			}
+		}
+		switch {
+		case curInfo == nil:
+			added = append(added, &SchemaChange{Type: SchemaAddColumn, NewColumn: desInfo})
+		case droppedTables[curInfo.ValueType]:
+			// A reference to a recreated table must be recreated.
+			touched[key] = true
+			removed = append(removed, &SchemaChange{Type: SchemaRemoveColumn, OldColumn: curInfo})
+			added = append(added, &SchemaChange{Type: SchemaAddColumn, NewColumn: desInfo})
+		case !columnInfoEqual(curInfo, desInfo):
+			touched[key] = true
+			changed = append(changed, &SchemaChange{Type: SchemaChangeColumn,
+				OldColumn: curInfo, NewColumn: desInfo})
+		}
+	}
+	for _, curInfo := range current.Columns {
+		key := curInfo.Table + "." + curInfo.Name
+		if (curInfo.Type == IndexColumn) || droppedTables[curInfo.Table] ||
+			renamedFrom[key] {
+			continue
+		}
+		if !columnInScope(curInfo) {
+			if droppedTables[curInfo.ValueType] {
+				// A reference to a recreated table must be recreated.
+				touched[key] = true
+				removed = append(removed, &SchemaChange{Type: SchemaRemoveColumn, OldColumn: curInfo})
+				added = append(added, &SchemaChange{Type: SchemaAddColumn, NewColumn: curInfo})
+			}
+			continue
+		}
+		if desColumns[key] != nil {
+			continue
+		}
+		touched[key] = true
+		removed = append(removed, &SchemaChange{Type: SchemaRemoveColumn, OldColumn: curInfo})
+	}
+
+	// Index columns to be removed or added.
+	var removedIndexes, addedIndexes []*SchemaChange
+	needsRebuild := func(info *ColumnInfo) bool {
+		if droppedTables[info.ValueType] {
+			return true
+		}
+		for _, source := range info.Sources {
+			if touched[info.ValueType+"."+source] {
+				return true
+			}
+		}
+		return false
+	}
+	for _, curInfo := range current.Columns {
+		if curInfo.Type != IndexColumn {
+			continue
+		}
+		if !columnInScope(curInfo) {
+			// An index column out of scope is rebuilt as it is.
+			if droppedTables[curInfo.Table] {
+				addedIndexes = append(addedIndexes,
+					&SchemaChange{Type: SchemaAddColumn, NewColumn: curInfo})
+			} else if needsRebuild(curInfo) {
+				removedIndexes = append(removedIndexes,
+					&SchemaChange{Type: SchemaRemoveColumn, OldColumn: curInfo})
+				addedIndexes = append(addedIndexes,
+					&SchemaChange{Type: SchemaAddColumn, NewColumn: curInfo})
+			}
+			continue
+		}
+		if droppedTables[curInfo.Table] {
+			continue
+		}
+		desInfo := desColumns[curInfo.Table+"."+curInfo.Name]
+		if (desInfo == nil) || !columnInfoEqual(curInfo, desInfo) || needsRebuild(curInfo) {
+			removedIndexes = append(removedIndexes,
+				&SchemaChange{Type: SchemaRemoveColumn, OldColumn: curInfo})
+		}
+	}
+	for _, desInfo := range desired.Columns {
+		if desInfo.Type != IndexColumn {
+			continue
+		}
+		curInfo := curColumns[desInfo.Table+"."+desInfo.Name]
+		if (curInfo == nil) || droppedTables[desInfo.Table] ||
+			!columnInfoEqual(curInfo, desInfo) || needsRebuild(curInfo) {
+			addedIndexes = append(addedIndexes,
+				&SchemaChange{Type: SchemaAddColumn, NewColumn: desInfo})
+		}
+	}
+	// Columns of recreated tables must be added.
+	for _, desInfo := range desired.Columns {
+		if (desInfo.Type != IndexColumn) && (changedTables[desInfo.Table] != nil) {
+			added = append(added, &SchemaChange{Type: SchemaAddColumn, NewColumn: desInfo})
+		}
+	}
+
+	var changes []*SchemaChange
+	changes = append(changes, removedIndexes...)
+	changes = append(changes, removed...)
+	sortedRemovedTables := sortTablesByDependency(removedTables)
+	for i := len(sortedRemovedTables) - 1; i >= 0; i-- {
+		changes = append(changes, &SchemaChange{Type: SchemaRemoveTable,
+			OldTable: sortedRemovedTables[i]})
+	}
+	for _, info := range sortTablesByDependency(addedTables) {
+		if oldInfo, ok := changedTables[info.Name]; ok {
+			changes = append(changes, &SchemaChange{Type: SchemaChangeTable,
+				OldTable: oldInfo, NewTable: info})
+		} else {
+			changes = append(changes, &SchemaChange{Type: SchemaAddTable, NewTable: info})
+		}
+	}
+	changes = append(changes, renamed...)
+	changes = append(changes, changed...)
+	changes = append(changes, added...)
+	changes = append(changes, addedIndexes...)
+
+	var lossyChange *SchemaChange
+	for _, change := range changes {
+		switch change.Type {
+		case SchemaRemoveTable, SchemaChangeTable:
+			change.DataLoss = !isLexicon(change.OldTable)
+		case SchemaRemoveColumn:
+			change.DataLoss = change.OldColumn.Type != IndexColumn
+		}
+		if change.DataLoss && (lossyChange == nil) {
+			lossyChange = change
+		}
+	}
+	if (lossyChange != nil) && !options.AllowDataLoss {
+		return changes, fmt.Errorf("%v loses data: %w", lossyChange, ErrOperationNotPermitted)
+	}
+	return changes, nil
+}
+
+// DiffSchema returns the changes from the current schema to desired.
+// See DiffSchema for details.
+func (db *DB) DiffSchema(desired *Schema, options *SchemaDiffOptions) ([]*SchemaChange, error) {
+	current, err := db.Schema()
+	if err != nil {
+		return nil, err
+	}
+	return DiffSchema(current, desired, options)
+}
+
+// execSchemaCommand executes a command that returns true on success.
+func (db *DB) execSchemaCommand(name string, options map[string]string) error {
+	bytes, err := db.queryJSON(name, options)
+	if err != nil {
+		return err
+	}
+	if string(bytes) != "true" {
+		return fmt.Errorf("%s failed: options = %v", name, options)
+	}
+	return nil
+}
+
+// applySchemaChange applies a schema change.
+func (db *DB) applySchemaChange(change *SchemaChange) error {
+	switch change.Type {
+	case SchemaAddTable:
+		options := change.NewTable.TableOptions
+		_, err := db.CreateTable(change.NewTable.Name, &options)
+		return err
+	case SchemaRemoveTable:
+		return db.RemoveTable(change.OldTable.Name)
+	case SchemaChangeTable:
+		if err := db.RemoveTable(change.OldTable.Name); err != nil {
+			return err
+		}
+		options := change.NewTable.TableOptions
+		_, err := db.CreateTable(change.NewTable.Name, &options)
+		return err
+	case SchemaAddColumn:
+		options := change.NewColumn.ColumnOptions
+		_, err := db.CreateColumn(change.NewColumn.Table, change.NewColumn.Name,
+			change.NewColumn.TypeString(), &options)
+		return err
+	}
+	table, err := db.FindTable(change.OldColumn.Table)
+	if err != nil {
+		return err
+	}
+	switch change.Type {
+	case SchemaRemoveColumn:
+		return table.RemoveColumn(change.OldColumn.Name)
+	case SchemaRenameColumn:
+		return table.RenameColumn(change.OldColumn.Name, change.NewColumn.Name)
+	case SchemaChangeColumn:
+		// Values are copied via a temporary column.
+		info := change.NewColumn
+		tmpName := info.Name + "_grngo_tmp"
+		options := info.ColumnOptions
+		if _, err := table.CreateColumn(tmpName, info.TypeString(), &options); err != nil {
+			return err
+		}
+		if err := db.execSchemaCommand("column_copy", map[string]string{
+			"from_table": change.OldColumn.Table, "from_name": change.OldColumn.Name,
+			"to_table": info.Table, "to_name": tmpName}); err != nil {
+			return err
+		}
+		if err := table.RemoveColumn(change.OldColumn.Name); err != nil {
+			return err
+		}
+		return table.RenameColumn(tmpName, info.Name)
+	default:
+		return fmt.Errorf("unknown schema change: %v: %w", change.Type, ErrInvalidArgument)
+	}
+}
+
+// ApplySchema applies schema changes in order and refreshes db.
+// ApplySchema stops at the first failure.
+func (db *DB) ApplySchema(changes []*SchemaChange) error {
+	defer db.Refresh()
+	for _, change := range changes {
+		if err := db.applySchemaChange(change); err != nil {
+			return fmt.Errorf("%v: %w", change, err)
+		}
+	}
+	return nil
+}
+
+// -- Select --
+
+// Select is a set of parameters for the select command.
+// Empty strings are not passed to select.
+//
+// See http://groonga.org/docs/reference/commands/select.html#parameters for details.
+type Select struct {
+	Table                  string // Table is associated with table.
+	Filter                 string // Filter is associated with filter.
+	Query                  string // Query is associated with query.
+	MatchColumns           string // MatchColumns is associated with match_columns.
+	SortBy                 string // SortBy is associated with sortby.
+	OutputColumns          string // OutputColumns is associated with output_columns.
+	Offset                 int    // Offset is associated with offset.
+	Limit                  int    // Limit is associated with limit.
+	Scorer                 string // Scorer is associated with scorer.
+	Drilldown              string // Drilldown is associated with drilldown.
+	DrilldownSortBy        string // DrilldownSortBy is associated with drilldown_sortby.
+	DrilldownOutputColumns string // DrilldownOutputColumns is associated with drilldown_output_columns.
+	DrilldownOffset        int    // DrilldownOffset is associated with drilldown_offset.
+	DrilldownLimit         int    // DrilldownLimit is associated with drilldown_limit.
+}
+
+// NewSelect returns a new Select for a table with the default settings.
+// Limit and DrilldownLimit are 10 by default.
+func NewSelect(table string) *Select {
+	s := new(Select)
+	s.Table = table
+	s.Limit = 10
+	s.DrilldownLimit = 10
+	return s
+}
+
+// optionsMap creates an options map for select.
+func (s *Select) optionsMap() map[string]string {
+	optionsMap := map[string]string{
+		"table":  s.Table,
+		"offset": strconv.Itoa(s.Offset),
+		"limit":  strconv.Itoa(s.Limit),
+	}
+	options := []struct {
+		key   string
+		value string
+	}{
+		{"filter", s.Filter},
+		{"query", s.Query},
+		{"match_columns", s.MatchColumns},
+		{"sortby", s.SortBy},
+		{"output_columns", s.OutputColumns},
+		{"scorer", s.Scorer},
+		{"drilldown", s.Drilldown},
+		{"drilldown_sortby", s.DrilldownSortBy},
+		{"drilldown_output_columns", s.DrilldownOutputColumns},
+	}
+	for _, option := range options {
+		if option.value != "" {
+			optionsMap[option.key] = option.value
+		}
+	}
+	if s.Drilldown != "" {
+		optionsMap["drilldown_offset"] = strconv.Itoa(s.DrilldownOffset)
+		optionsMap["drilldown_limit"] = strconv.Itoa(s.DrilldownLimit)
+	}
+	return optionsMap
+}
+
+// SelectColumn describes a column in the result of select.
+type SelectColumn struct {
+	Name string // The column name, e.g. "_key".
+	Type string // The type name, e.g. "ShortText" or "Table".
+}
+
+// SelectResult is the result of select or a drilldown.
+//
+// Values in Rows have the same types as Column.GetValue returns, e.g. int64
+// for Int32 and [][]byte for a []ShortText column. A reference is converted
+// into the key of the referred row. A value of an unknown type is left as
+// decoded by encoding/json with json.Number for numbers.
+type SelectResult struct {
+	NHits      int             // The number of hits.
+	Columns    []SelectColumn  // The output columns.
+	Rows       [][]interface{} // The output rows.
+	Drilldowns []*SelectResult // The drilldown results in the order of Drilldown.
+}
+
+// dataTypeByName returns the builtin data type associated with a name.
+func dataTypeByName(name string) (DataType, bool) {
+	for dataType := Bool; dataType <= WGS84GeoPoint; dataType++ {
+		if dataType.String() == name {
+			return dataType, true
+		}
+	}
+	return Void, false
+}
+
+// selectValueType returns the builtin data type for a type name in the result
+// of select. If the name is a table, the key type of the table is used.
+func (db *DB) selectValueType(name string) (DataType, bool) {
+	if dataType, ok := dataTypeByName(name); ok {
+		return dataType, true
+	}
+	table, err := db.FindTable(name)
+	if err != nil {
+		return Void, false
+	}
+	return DataType(table.c.key_type), true
+}
+
+// rawInt64 returns a decoded number as int64.
+func rawInt64(raw interface{}) (int64, bool) {
+	switch raw := raw.(type) {
+	case int64:
+		return raw, true
+	case uint64:
+		if raw <= math.MaxInt64 {
+			return int64(raw), true
+		}
+	}
+	return 0, false
+}
+
+// rawUint64 returns a decoded number as uint64.
+func rawUint64(raw interface{}) (uint64, bool) {
+	switch raw := raw.(type) {
+	case int64:
+		if raw >= 0 {
+			return uint64(raw), true
+		}
+	case uint64:
+		return raw, true
+	}
+	return 0, false
+}
+
+// rawFloat64 returns a decoded number as float64.
+func rawFloat64(raw interface{}) (float64, bool) {
+	switch raw := raw.(type) {
+	case int64:
+		return float64(raw), true
+	case uint64:
+		return float64(raw), true
+	case float64:
+		return raw, true
+	}
+	return 0, false
+}
+
+// convertSelectScalar converts a scalar value in the result of select.
+func (db *DB) convertSelectScalar(dataType DataType, raw interface{}) (interface{}, error) {
+	invalidValue := func() error {
+		return fmt.Errorf("invalid value: type = %s, value = %v", dataType, raw)
+	}
+	switch dataType {
+	case Bool:
+		if value, ok := raw.(bool); ok {
+			return value, nil
+		}
+	case Int8, Int16, Int32, Int64, UInt8, UInt16, UInt32:
+		if value, ok := rawInt64(raw); ok {
+			return value, nil
+		}
+	case UInt64:
+		if value, ok := rawUint64(raw); ok {
+			return value, nil
+		}
+	case Float:
+		if value, ok := rawFloat64(raw); ok {
+			return value, nil
+		}
+	case Time:
+		if seconds, ok := rawFloat64(raw); ok {
+			micros := int64(math.Round(seconds * 1000000))
+			if db.parseTime {
+				return microsToTime(micros), nil
+			}
+			return micros, nil
+		}
+	case ShortText, Text, LongText:
+		if value, ok := raw.(string); ok {
+			return []byte(value), nil
+		}
+	case TokyoGeoPoint, WGS84GeoPoint:
+		if value, ok := raw.(string); ok {
+			var geoPoint GeoPoint
+			if _, err := fmt.Sscanf(value, "%dx%d",
+				&geoPoint.Latitude, &geoPoint.Longitude); err != nil {
+				return nil, invalidValue()
+			}
+			return geoPoint, nil
+		}
+	}
+	return nil, invalidValue()
+}
+
+// convertSelectValue converts a value in the result of select.
+func (db *DB) convertSelectValue(typeName string, raw interface{}) (interface{}, error) {
+	dataType, ok := db.selectValueType(typeName)
+	if !ok || (raw == nil) {
+		return raw, nil
+	}
+	switch raw := raw.(type) {
+	case []interface{}:
+		elemType, err := db.selectElemType(dataType)
+		if err != nil {
+			return nil, err
+		}
+		values := reflect.MakeSlice(reflect.SliceOf(elemType), len(raw), len(raw))
+		for i := range raw {
+			value, err := db.convertSelectScalar(dataType, raw[i])
+			if err != nil {
+				return nil, err
+			}
+			values.Index(i).Set(reflect.ValueOf(value))
+		}
+		return values.Interface(), nil
+	case *selectObject:
+		// A weight vector is {"value": weight, ...}.
+		values := make([]WeightedText, len(raw.keys))
+		for i, key := range raw.keys {
+			weight, err := db.convertSelectScalar(UInt32, raw.values[i])
+			if err != nil {
+				return nil, err
+			}
+			values[i] = WeightedText{[]byte(key), uint32(weight.(int64))}
+		}
+		return values, nil
+	default:
+		return db.convertSelectScalar(dataType, raw)
+	}
+}
+
+// selectElemType returns the Go type of an element of a vector.
+func (db *DB) selectElemType(dataType DataType) (reflect.Type, error) {
+	switch dataType {
+	case Bool:
+		return reflect.TypeOf(false), nil
+	case Int8, Int16, Int32, Int64, UInt8, UInt16, UInt32:
+		return reflect.TypeOf(int64(0)), nil
+	case UInt64:
+		return reflect.TypeOf(uint64(0)), nil
+	case Float:
+		return reflect.TypeOf(float64(0)), nil
+	case Time:
+		if db.parseTime {
+			return reflect.TypeOf(time.Time{}), nil
+		}
+		return reflect.TypeOf(int64(0)), nil
+	case ShortText, Text, LongText:
+		return reflect.TypeOf([]byte(nil)), nil
+	case TokyoGeoPoint, WGS84GeoPoint:
+		return reflect.TypeOf(GeoPoint{}), nil
+	default:
+		return nil, fmt.Errorf("unsupported data type: %s", dataType)
+	}
+}
+
+// parseSelectResult parses a result set, [[N], [[name, type], ...], rows...].
+func (db *DB) parseSelectResult(raw interface{}) (*SelectResult, error) {
+	invalidResult := func() error {
+		return fmt.Errorf("invalid result: %v", raw)
+	}
+	items, ok := raw.([]interface{})
+	if !ok || (len(items) < 2) {
+		return nil, invalidResult()
+	}
+	nHits, ok := items[0].([]interface{})
+	if !ok || (len(nHits) != 1) {
+		return nil, invalidResult()
+	}
+	n, ok := rawInt64(nHits[0])
+	if !ok {
+		return nil, invalidResult()
+	}
+	result := &SelectResult{NHits: int(n)}
+	columns, ok := items[1].([]interface{})
+	if !ok {
+		return nil, invalidResult()
+	}
+	for _, column := range columns {
+		pair, ok := column.([]interface{})
+		if !ok || (len(pair) != 2) {
+			return nil, invalidResult()
+		}
+		name, ok1 := pair[0].(string)
+		typeName, ok2 := pair[1].(string)
+		if !ok1 || !ok2 {
+			return nil, invalidResult()
+		}
+		result.Columns = append(result.Columns, SelectColumn{name, typeName})
+	}
+	for _, item := range items[2:] {
+		rawRow, ok := item.([]interface{})
+		if !ok || (len(rawRow) != len(result.Columns)) {
+			return nil, invalidResult()
+		}
+		row := make([]interface{}, len(rawRow))
+		for i := range rawRow {
+			value, err := db.convertSelectValue(result.Columns[i].Type, rawRow[i])
+			if err != nil {
+				return nil, err
+			}
+			row[i] = value
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return result, nil
+}
+
+// selectObject is an object in the result of select.
+// The keys are kept in order because a weight vector is an object.
+type selectObject struct {
+	keys   []string      // The keys in order.
+	values []interface{} // The values of keys.
+}
+
+// toMap converts an object into a map.
+func (object *selectObject) toMap() map[string]interface{} {
+	values := make(map[string]interface{}, len(object.keys))
+	for i, key := range object.keys {
+		values[key] = object.values[i]
+	}
+	return values
+}
+
+// decodeSelect decodes the output of select like Decode but decodes an
+// object into *selectObject.
+func decodeSelect(outputType OutputType, output []byte) (interface{}, error) {
+	switch outputType {
+	case "", OutputJSON:
+		return decodeJSON(output, true)
+	case OutputMessagePack:
+		decoder := msgpackDecoder{buf: output, ordered: true}
+		return decoder.decodeAll()
+	default:
+		return nil, fmt.Errorf("unsupported output type: %s: %w",
+			outputType, ErrInvalidArgument)
+	}
+}
+
+// parseSelect parses the output of select.
+func (db *DB) parseSelect(outputType OutputType, output []byte) (*SelectResult, error) {
+	decoded, err := decodeSelect(outputType, output)
+	if err != nil {
+		return nil, err
+	}
+	raw, ok := decoded.([]interface{})
+	if !ok || (len(raw) == 0) {
+		return nil, fmt.Errorf("invalid result: %v", decoded)
+	}
+	result, err := db.parseSelectResult(raw[0])
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range raw[1:] {
+		drilldown, err := db.parseSelectResult(item)
+		if err != nil {
+			return nil, err
+		}
+		result.Drilldowns = append(result.Drilldowns, drilldown)
+	}
+	return result, nil
+}
+
+// Select executes select and returns the decoded result.
+// Select uses MessagePack if the output type of db is OutputMessagePack and
+// JSON otherwise.
+func (db *DB) Select(s *Select) (*SelectResult, error) {
+	if s == nil || s.Table == "" {
+		return nil, fmt.Errorf("table is not specified: %w", ErrInvalidArgument)
+	}
+	outputType := OutputJSON
+	if db.outputType == OutputMessagePack {
+		outputType = OutputMessagePack
+	}
+	optionsMap := s.optionsMap()
+	optionsMap["output_type"] = string(outputType)
+	output, err := db.QueryEx("select", optionsMap)
+	if err != nil {
+		return nil, err
+	}
+	return db.parseSelect(outputType, output)
+}
+
+// -- Output types --
+
+// OutputType is an output type of Groonga commands.
+//
+// See http://groonga.org/docs/reference/command/output_format.html for details.
+type OutputType string
+
+// Output types.
+const (
+	OutputJSON        = OutputType("json")
+	OutputMessagePack = OutputType("msgpack")
+	OutputXML         = OutputType("xml")
+	OutputTSV         = OutputType("tsv")
+	OutputArrow       = OutputType("apache-arrow")
+)
+
+// Decode decodes the output of a Groonga command.
+// Only OutputJSON and OutputMessagePack are supported.
+// Use NewArrowReader for OutputArrow.
+func Decode(outputType OutputType, output []byte) (interface{}, error) {
+	switch outputType {
+	case "", OutputJSON:
+		return DecodeJSON(output)
+	case OutputMessagePack:
+		return DecodeMessagePack(output)
+	default:
+		return nil, fmt.Errorf("unsupported output type: %s: %w",
+			outputType, ErrInvalidArgument)
+	}
+}
+
+// DecodeJSON decodes JSON output.
+//
+// A value is decoded into nil, bool, int64, uint64, float64, string,
+// []interface{} or map[string]interface{}. An integer is decoded into int64
+// unless it overflows int64.
+func DecodeJSON(output []byte) (interface{}, error) {
+	return decodeJSON(output, false)
+}
+
+// decodeJSON decodes JSON output.
+// If ordered is true, an object is decoded into *selectObject.
+func decodeJSON(output []byte, ordered bool) (interface{}, error) {
+	decoder := jsonDecoder{decoder: json.NewDecoder(bytes.NewReader(output)),
+		ordered: ordered}
+	decoder.decoder.UseNumber()
+	return decoder.decode()
+}
+
+// jsonDecoder is a JSON decoder.
+type jsonDecoder struct {
+	decoder *json.Decoder // The tokenizer.
+	ordered bool          // Whether an object is decoded into *selectObject.
+}
+
+// token reads the next token.
+func (decoder *jsonDecoder) token() (json.Token, error) {
+	token, err := decoder.decoder.Token()
+	if err != nil {
+		return nil, fmt.Errorf("json.Decoder.Token() failed: %v", err)
+	}
+	return token, nil
+}
+
+// decode decodes a value.
+func (decoder *jsonDecoder) decode() (interface{}, error) {
+	token, err := decoder.token()
+	if err != nil {
+		return nil, err
+	}
+	switch token := token.(type) {
+	case json.Delim:
+		if token == '[' {
+			return decoder.decodeArray()
+		}
+		return decoder.decodeObject()
+	case json.Number:
+		if value, err := strconv.ParseInt(token.String(), 10, 64); err == nil {
+			return value, nil
+		}
+		if value, err := strconv.ParseUint(token.String(), 10, 64); err == nil {
+			return value, nil
+		}
+		value, err := token.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("invalid number: %s", token)
+		}
+		return value, nil
+	default:
+		return token, nil
+	}
+}
+
+// decodeArray decodes the rest of an array.
+func (decoder *jsonDecoder) decodeArray() (interface{}, error) {
+	values := make([]interface{}, 0)
+	for decoder.decoder.More() {
+		value, err := decoder.decode()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	if _, err := decoder.token(); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// decodeObject decodes the rest of an object.
+func (decoder *jsonDecoder) decodeObject() (interface{}, error) {
+	object := &selectObject{keys: make([]string, 0), values: make([]interface{}, 0)}
+	for decoder.decoder.More() {
+		key, err := decoder.token()
+		if err != nil {
+			return nil, err
+		}
+		value, err := decoder.decode()
+		if err != nil {
+			return nil, err
+		}
+		object.keys = append(object.keys, key.(string))
+		object.values = append(object.values, value)
+	}
+	if _, err := decoder.token(); err != nil {
+		return nil, err
+	}
+	if decoder.ordered {
+		return object, nil
+	}
+	return object.toMap(), nil
+}
+
+// -- Groonga --
+
+// grnMutex is a mutex to protect grnInitFinDisabled and grnInitCount.
+var grnMutex sync.Mutex
+
+// grnInitFinDisabled shows whther C.grn_init and C.grn_fin are disabled.
+var grnInitFinDisabled = false
+
+// grnInitCount is an internal counter used in GrnInit and GrnFin.
+var grnInitCount = 0
+
+// DisableGrnInitFin disables calls of C.grn_init and C.grn_fin in GrnInit()
+// and GrnFin().
+// DisableGrnInitFin should be used if you manually or another library
+// initialize and finalize Groonga.
+func DisableGrnInitFin() {
+	grnMutex.Lock()
+	defer grnMutex.Unlock()
+	grnInitFinDisabled = true
+}
+
+// GrnInit increments an internal counter grnInitCount and if it changes from
+// 0 to 1, calls C.grn_init to initialize Groonga.
+//
+// Note that CreateDB and OpenDB call GrnInit, so you should not manually call
+// GrnInit if not needed.
+//
+// GrnInit is goroutine-safe.
+func GrnInit() error {
+	grnMutex.Lock()
+	defer grnMutex.Unlock()
+	if grnInitCount == 0 {
+		if !grnInitFinDisabled {
+			if rc := C.grn_init(); rc != C.GRN_SUCCESS {
+				return newCError("grn_init()", rc, nil)
+			}
+		}
+	}
+	grnInitCount++
+	return nil
+}
+
+// GrnFin decrements an internal counter grnInitCount and if it changes from
+// 1 to 0, calls C.grn_fin to finalize Groonga.
+//
+// Note that DB.Close calls GrnFin, so you should not manually call GrnFin if
+// not needed.
+//
+// GrnFin is goroutine-safe.
+func GrnFin() error {
+	grnMutex.Lock()
+	defer grnMutex.Unlock()
+	switch grnInitCount {
+	case 0:
+		return fmt.Errorf("Groonga is not initialized yet: %w",
+			ErrOperationNotPermitted)
+	case 1:
+		if !grnInitFinDisabled {
+			if rc := C.grn_fin(); rc != C.GRN_SUCCESS {
+				return newCError("grn_fin()", rc, nil)
+			}
+		}
+	}
+	grnInitCount--
+	return nil
+}
+
+// GrnVersion returns the version of Groonga, e.g. "7.1.0".
+func GrnVersion() string {
+	return C.GoString(C.grn_get_version())
+}
+
+// GrnInitStatus returns whether Groonga is initialized via GrnInit and the
+// internal counter grnInitCount, that is the number of GrnInit calls (including
+// implicit ones by CreateDB, OpenDB and OpenDBPool) not yet paired with GrnFin.
+//
+// If DisableGrnInitFin has been called, initialized only means that the
+// counter is not zero.
+func GrnInitStatus() (initialized bool, count int) {
+	grnMutex.Lock()
+	defer grnMutex.Unlock()
+	return grnInitCount != 0, grnInitCount
+}
+
+// -- DB --
+
+// DB is associated with a Groonga database with its context.
+type DB struct {
+	c      *C.grngo_db       // The associated C object.
+	tables map[string]*Table // A cache to find tables by name.
+	pool   *DBPool           // The owner DBPool or nil.
+	// Whether Time values are returned as time.Time or not.
+	parseTime bool
+	// The default output type of SendEx and QueryEx.
+	outputType OutputType
+}
+
+// newDB returns a new DB.
+func newDB(c *C.grngo_db) *DB {
+	db := new(DB)
+	db.c = c
+	db.tables = make(map[string]*Table)
+	return db
+}
+
+// CreateDB creates a Groonga database and returns a new DB associated with it.
+// If path is empty, CreateDB creates a temporary database.
+//
+// Note that CreateDB initializes Groonga if the new DB will be the only one
+// and implicit initialization is not disabled.
+func CreateDB(path string) (*DB, error) {
+	if err := GrnInit(); err != nil {
+		return nil, err
+	}
+	pathBytes := []byte(path)
+	var cPath *C.char
+	if len(pathBytes) != 0 {
+		cPath = (*C.char)(unsafe.Pointer(&pathBytes[0]))
+	}
+	var c *C.grngo_db
+	rc := C.grngo_create_db(cPath, C.size_t(len(pathBytes)), &c)
+	if rc != C.GRN_SUCCESS {
+		GrnFin()
+		return nil, newCError("grngo_create_db()", rc, nil)
+	}
+	return newDB(c), nil
+}
+
+// OpenDB opens an existing Groonga database and returns a new DB associated
+// with it.
+//
+// Note that CreateDB initializes Groonga if the new DB will be the only one
+// and implicit initialization is not disabled.
+func OpenDB(path string) (*DB, error) {
+	if err := GrnInit(); err != nil {
+		return nil, err
+	}
+	pathBytes := []byte(path)
+	var cPath *C.char
+	if len(pathBytes) != 0 {
+		cPath = (*C.char)(unsafe.Pointer(&pathBytes[0]))
+	}
+	var c *C.grngo_db
+	rc := C.grngo_open_db(cPath, C.size_t(len(pathBytes)), &c)
+	if rc != C.GRN_SUCCESS {
+		GrnFin()
+		return nil, newCError("grngo_open_db()", rc, nil)
+	}
+	return newDB(c), nil
+}
+
+// Close finalizes a DB.
+// Close also closes the Table and Column handles obtained from the DB.
+//
+// Note that a DB obtained from DBPool.Get must be returned with DBPool.Put
+// instead of being closed.
+func (db *DB) Close() error {
+	if err := db.checkOpen("DB.Close()"); err != nil {
+		return err
+	}
+	if db.pool != nil {
+		return fmt.Errorf("DB.Close() failed: DB is owned by DBPool: %w",
+			ErrOperationNotPermitted)
+	}
+	db.Refresh()
+	C.grngo_close_db(db.c)
+	db.c = nil
+	return GrnFin()
+}
+
+// checkOpen returns an error if the DB is closed.
+func (db *DB) checkOpen(op string) error {
+	if db.c == nil {
+		return fmt.Errorf("%s failed: DB is closed: %w", op, ErrClosed)
+	}
+	return nil
+}
+
+// Refresh clears maps for Table and Column name resolution.
+//
+// If a table or column is renamed or removed, old maps can cause a name
+// resolution error. In such a case, you should use Refresh or reopen the
+// Groonga database to resolve it.
+// Note that DB.RemoveTable, DB.RenameTable, Table.RemoveColumn and
+// Table.RenameColumn update the maps without Refresh.
+func (db *DB) Refresh() error {
+	for _, table := range db.tables {
+		table.close()
+	}
+	db.tables = make(map[string]*Table)
+	return nil
+}
+
+// objectID returns the ID of a table or column.
+// A column is specified by "Table.column".
+func (db *DB) objectID(name string) (uint32, error) {
+	if err := db.checkOpen("DB.objectID()"); err != nil {
+		return 0, err
+	}
+	nameBytes := []byte(name)
+	var cName *C.char
+	if len(nameBytes) != 0 {
+		cName = (*C.char)(unsafe.Pointer(&nameBytes[0]))
+	}
+	var id C.grn_id
+	rc := C.grngo_get_obj_id(db.c, cName, C.size_t(len(nameBytes)), &id)
+	if rc != C.GRN_SUCCESS {
+		return 0, newCError("grngo_get_obj_id()", rc, db)
+	}
+	return uint32(id), nil
+}
+
+// invalidate closes cached Table and Column handles that refer to the object
+// and returns the closed handles, including the cached columns of the closed
+// tables. A Column handle refers to the objects in its reference chain.
+func (db *DB) invalidate(id uint32) (tables []*Table, columns []*Column) {
+	for tableName, table := range db.tables {
+		if C.grngo_table_refers(table.c, C.grn_id(id)) == C.GRN_TRUE {
+			for _, column := range table.columns {
+				columns = append(columns, column)
+			}
+			table.close()
+			delete(db.tables, tableName)
+			tables = append(tables, table)
+			continue
+		}
+		for columnName, column := range table.columns {
+			if C.grngo_column_refers(column.c, C.grn_id(id)) == C.GRN_TRUE {
+				column.close()
+				delete(table.columns, columnName)
+				columns = append(columns, column)
+			}
+		}
+	}
+	return tables, columns
+}
+
+// reopen opens the handles closed by invalidate again and caches them.
+// A handle that cannot be opened is left closed.
+func (db *DB) reopen(tables []*Table, columns []*Column) {
+	for _, table := range tables {
+		if c, err := db.openTable(table.name); err == nil {
+			table.c = c
+			db.tables[table.name] = table
+		}
+	}
+	for _, column := range columns {
+		if column.table.c == nil {
+			continue
+		}
+		if c, err := column.table.openColumn(column.name); err == nil {
+			column.c = c
+			column.table.columns[column.name] = column
+		}
+	}
+}
+
+// Send executes a Groonga command.
+// The command must be well-formed.
+//
+// See http://groonga.org/docs/reference/command.html for details.
+func (db *DB) Send(command string) error {
+	if err := db.checkOpen("DB.Send()"); err != nil {
+		return err
+	}
+	return db.send(command, 0)
+}
+
+// send executes a Groonga command.
+// If handle is not 0, the output is streamed to the *io.PipeWriter of handle.
+func (db *DB) send(command string, handle cgo.Handle) error {
+	command = strings.TrimSpace(command)
+	if strings.HasPrefix(command, "table_remove") ||
+		strings.HasPrefix(command, "table_rename") ||
+		strings.HasPrefix(command, "column_remove") ||
+		strings.HasPrefix(command, "column_rename") {
+		db.Refresh()
+	}
+	commandBytes := []byte(command)
+	var cCommand *C.char
+	if len(commandBytes) != 0 {
+		cCommand = (*C.char)(unsafe.Pointer(&commandBytes[0]))
+	}
+	if handle != 0 {
+		rc := C.grngo_send_stream(db.c, cCommand, C.size_t(len(commandBytes)),
+			C.uintptr_t(handle))
+		if rc != C.GRN_SUCCESS {
+			return newCError("grngo_send_stream()", rc, db)
+		}
+		return nil
+	}
+	rc := C.grngo_send(db.c, cCommand, C.size_t(len(commandBytes)))
+	if rc != C.GRN_SUCCESS {
+		return newCError("grngo_send()", rc, db)
+	}
+	return nil
+}
+
+//export grngoRecvChunk
+func grngoRecvChunk(handle C.uintptr_t, res *C.char, resLen C.uint) {
+	w := cgo.Handle(handle).Value().(*io.PipeWriter)
+	// Write fails if the reader is closed and then the output is discarded.
+	w.Write(C.GoBytes(unsafe.Pointer(res), C.int(resLen)))
+}
+
+// SendEx executes a Groonga command with separated options.
+//
+// See http://groonga.org/docs/reference/command.html for details.
+func (db *DB) SendEx(name string, options map[string]string) error {
+	command, err := db.commandEx(name, options)
+	if err != nil {
+		return err
+	}
+	return db.Send(command)
+}
+
+// commandEx builds a Groonga command from separated options.
+func (db *DB) commandEx(name string, options map[string]string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("invalid command: name = <%s>: %w", name, ErrInvalidArgument)
+	}
+	for _, r := range name {
+		if (r != '_') && (r < 'a') && (r > 'z') {
+			return "", fmt.Errorf("invalid command: name = <%s>: %w", name, ErrInvalidArgument)
+		}
+	}
+	commandParts := []string{name}
+	for key, value := range options {
+		if key == "" {
+			return "", fmt.Errorf("invalid option: key = <%s>: %w", key, ErrInvalidArgument)
+		}
+		for _, r := range key {
+			if (r != '_') && (r < 'a') && (r > 'z') {
+				return "", fmt.Errorf("invalid option: key = <%s>: %w", key, ErrInvalidArgument)
+			}
+		}
+		value = strings.Replace(value, "\\", "\\\\", -1)
+		value = strings.Replace(value, "'", "\\'", -1)
+		commandParts = append(commandParts, fmt.Sprintf("--%s '%s'", key, value))
+	}
+	if _, ok := options["output_type"]; !ok && (db.outputType != "") {
+		commandParts = append(commandParts, "--output_type "+string(db.outputType))
+	}
+	return strings.Join(commandParts, " "), nil
+}
+
+// Recv returns the result of Groonga commands executed by Send and SendEx.
+//
+// See http://groonga.org/docs/reference/command.html for details.
+func (db *DB) Recv() ([]byte, error) {
+	if err := db.checkOpen("DB.Recv()"); err != nil {
+		return nil, err
+	}
+	var res *C.char
+	var resLen C.uint
+	rc := C.grngo_recv(db.c, &res, &resLen)
+	if rc != C.GRN_SUCCESS {
+		return nil, newCError("grngo_recv()", rc, db)
+	}
+	return C.GoBytes(unsafe.Pointer(res), C.int(resLen)), nil
+}
+
+// Query executes a Groonga command and returns the result.
+//
+// See http://groonga.org/docs/reference/command.html for details.
+func (db *DB) Query(command string) ([]byte, error) {
+	if err := db.Send(command); err != nil {
+		result, _ := db.Recv()
+		return result, err
+	}
+	return db.Recv()
+}
+
+// Query executes a Groonga command with separated options and returns the
+// result.
+//
+// See http://groonga.org/docs/reference/command.html for details.
+func (db *DB) QueryEx(name string, options map[string]string) (
+	[]byte, error) {
+	if err := db.SendEx(name, options); err != nil {
+		result, _ := db.Recv()
+		return result, err
+	}
+	return db.Recv()
+}
+
+// SetOutputType sets the default output type of SendEx and QueryEx.
+// An "output_type" option overrides it and an empty outputType restores
+// Groonga's default. Send and Query are not affected.
+func (db *DB) SetOutputType(outputType OutputType) {
+	db.outputType = outputType
+}
+
+// OutputType returns the default output type of SendEx and QueryEx.
+func (db *DB) OutputType() OutputType {
+	return db.outputType
+}
+
+// queryJSON executes a Groonga command with JSON output.
+func (db *DB) queryJSON(name string, options map[string]string) ([]byte, error) {
+	if (db.outputType != "") && (db.outputType != OutputJSON) {
+		jsonOptions := map[string]string{"output_type": string(OutputJSON)}
+		for key, value := range options {
+			jsonOptions[key] = value
+		}
+		options = jsonOptions
+	}
+	return db.QueryEx(name, options)
+}
+
+// QueryArrow executes a Groonga command with Apache Arrow output and returns
+// a reader of the record batches.
+//
+// The output is streamed to the reader while the command runs in another
+// goroutine. db must not be used until Next returns false or Close is called.
+// An error of the command is returned by Err.
+func (db *DB) QueryArrow(name string, options map[string]string) (*ArrowReader, error) {
+	if err := db.checkOpen("DB.QueryArrow()"); err != nil {
+		return nil, err
+	}
+	arrowOptions := map[string]string{"output_type": string(OutputArrow)}
+	for key, value := range options {
+		if key != "output_type" {
+			arrowOptions[key] = value
+		}
+	}
+	command, err := db.commandEx(name, arrowOptions)
+	if err != nil {
+		return nil, err
+	}
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		handle := cgo.NewHandle(pw)
+		defer handle.Delete()
+		err := db.send(command, handle)
+		pw.Close()
+		done <- err
+	}()
+	reader := NewArrowReader(pr)
+	reader.wait = func() error {
+		pr.Close()
+		return <-done
+	}
+	return reader, nil
+}
+
+// Response is a response of a Groonga command.
+//
+// If the output has an envelope, i.e. [[return_code, start_time, elapsed,
+// error_message, ...], body] or {"header": ..., "body": ...}, the header is
+// decoded and Body is the body in the envelope. Otherwise, the fields are
+// filled by the context and the local clock, and Body is the whole output.
+type Response struct {
+	ReturnCode   RC            // The return code.
+	StartTime    time.Time     // The time when the command started.
+	Elapsed      time.Duration // The time spent by the command.
+	ErrorMessage string        // The error message if the command failed.
+	Body         []byte        // The body.
+}
+
+// responseHeader is the header of command version 3 output.
+type responseHeader struct {
+	ReturnCode  int     `json:"return_code"`
+	StartTime   float64 `json:"start_time"`
+	ElapsedTime float64 `json:"elapsed_time"`
+	Error       *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// secondsToTime converts the number of seconds since the Unix epoch into a
+// time.Time.
+func secondsToTime(seconds float64) time.Time {
+	return microsToTime(int64(math.Round(seconds * 1000000)))
+}
+
+// parseEnvelope decodes an envelope if output has one.
+func (response *Response) parseEnvelope(output []byte) bool {
+	output = bytes.TrimSpace(output)
+	if bytes.HasPrefix(output, []byte("{")) {
+		var envelope struct {
+			Header *responseHeader `json:"header"`
+			Body   json.RawMessage `json:"body"`
+		}
+		if err := json.Unmarshal(output, &envelope); err != nil ||
+			(envelope.Header == nil) {
+			return false
+		}
+		header := envelope.Header
+		response.ReturnCode = RC(header.ReturnCode)
+		response.StartTime = secondsToTime(header.StartTime)
+		response.Elapsed = time.Duration(header.ElapsedTime * float64(time.Second))
+		if header.Error != nil {
+			response.ErrorMessage = header.Error.Message
+		}
+		response.Body = envelope.Body
+		return true
+	}
+	var envelope []json.RawMessage
+	if err := json.Unmarshal(output, &envelope); err != nil ||
+		(len(envelope) == 0) || (len(envelope) > 2) {
+		return false
+	}
+	decoder := json.NewDecoder(bytes.NewReader(envelope[0]))
+	decoder.UseNumber()
+	var header []interface{}
+	if err := decoder.Decode(&header); err != nil || (len(header) < 3) {
+		return false
+	}
+	var numbers [3]float64
+	for i := range numbers {
+		number, ok := header[i].(json.Number)
+		if !ok {
+			return false
+		}
+		value, err := number.Float64()
+		if err != nil {
+			return false
+		}
+		numbers[i] = value
+	}
+	response.ReturnCode = RC(numbers[0])
+	response.StartTime = secondsToTime(numbers[1])
+	response.Elapsed = time.Duration(numbers[2] * float64(time.Second))
+	response.ErrorMessage = ""
+	if len(header) >= 4 {
+		if message, ok := header[3].(string); ok {
+			response.ErrorMessage = message
+		}
+	}
+	response.Body = nil
+	if len(envelope) == 2 {
+		response.Body = envelope[1]
+	}
+	return true
+}
+
+// QueryResponse executes a Groonga command and returns the response.
+//
+// If the command fails, QueryResponse returns both the response and an error.
+func (db *DB) QueryResponse(command string) (*Response, error) {
+	response := &Response{StartTime: time.Now()}
+	err := db.Send(command)
+	output, recvErr := db.Recv()
+	response.Elapsed = time.Since(response.StartTime)
+	if err == nil {
+		err = recvErr
+	}
+	var cErr *Error
+	if errors.As(err, &cErr) {
+		response.ReturnCode = cErr.RC
+		if cErr.hasCtx && (cErr.CtxRC != Success) {
+			response.ReturnCode = cErr.CtxRC
+		}
+		response.ErrorMessage = cErr.Message
+	}
+	if !response.parseEnvelope(output) {
+		response.Body = output
+	}
+	if (err == nil) && (response.ReturnCode != Success) {
+		op := command
+		if fields := strings.Fields(command); len(fields) != 0 {
+			op = fields[0]
+		}
+		err = &Error{Op: op, RC: response.ReturnCode,
+			CtxRC: response.ReturnCode, Message: response.ErrorMessage,
+			hasCtx: true}
+	}
+	return response, err
+}
+
+// createTableOptionsMap creates an options map for table_create.
+//
+// See http://groonga.org/docs/reference/commands/table_create.html#parameters for details.
+func (db *DB) createTableOptionsMap(name string, options *TableOptions) (map[string]string, error) {
+	optionsMap := make(map[string]string)
+	// http://groonga.org/docs/reference/commands/table_create.html#name
+	optionsMap["name"] = name
+	// http://groonga.org/docs/reference/commands/table_create.html#flags
+	if options.KeyType == "" {
+		optionsMap["flags"] = "TABLE_NO_KEY"
+	} else {
+		switch options.Flags & TableTypeMask {
+		case TableNoKey:
+			optionsMap["flags"] = "TABLE_NO_KEY"
+		case TableHashKey:
+			optionsMap["flags"] = "TABLE_HASH_KEY"
+		case TablePatKey:
+			optionsMap["flags"] = "TABLE_PAT_KEY"
+		case TableDatKey:
+			optionsMap["flags"] = "TABLE_DAT_KEY"
+		default:
+			return nil, fmt.Errorf("undefined table type: options = %+v", options)
+		}
+	}
+	if (options.Flags & KeyWithSIS) == KeyWithSIS {
+		optionsMap["flags"] += "|KEY_WITH_SIS"
+	}
+	// http://groonga.org/docs/reference/commands/table_create.html#key-type
+	switch options.KeyType {
+	case "":
+	case "Bool", "Int8", "Int16", "Int32", "Int64", "UInt8", "UInt16",
+		"UInt32", "UInt64", "Float", "Time", "ShortText", "TokyoGeoPoint",
+		"WGS84GeoPoint":
+		optionsMap["key_type"] = options.KeyType
+	default:
+		if _, err := db.FindTable(options.KeyType); err != nil {
+			return nil, fmt.Errorf("invalid key type: options = %+v", options)
+		}
+		optionsMap["key_type"] = options.KeyType
+	}
+	// http://groonga.org/docs/reference/commands/table_create.html#value-type
+	switch options.ValueType {
+	case "":
+	case "Bool", "Int8", "Int16", "Int32", "Int64", "UInt8", "UInt16",
+		"UInt32", "UInt64", "Float", "Time", "TokyoGeoPoint", "WGS84GeoPoint":
+		optionsMap["value_type"] = options.ValueType
+	default:
+		if _, err := db.FindTable(options.ValueType); err != nil {
+			return nil, fmt.Errorf("invalid value type: options = %+v", options)
+		}
+		optionsMap["value_type"] = options.ValueType
+	}
+	// http://groonga.org/docs/reference/commands/table_create.html#default-tokenizer
+	if options.DefaultTokenizer != "" {
+		optionsMap["default_tokenizer"] = options.DefaultTokenizer
+	}
+	// http://groonga.org/docs/reference/commands/table_create.html#normalizer
+	if options.Normalizer != "" {
+		optionsMap["normalizer"] = options.Normalizer
+	}
+	// http://groonga.org/docs/reference/commands/table_create.html#token-filters
+	if len(options.TokenFilters) != 0 {
+		optionsMap["token_filters"] = strings.Join(options.TokenFilters, ",")
+	}
+	return optionsMap, nil
+}
+
+// CreateTable creates a Groonga table and returns a new Table associated with
+// it.
+//
+// If options is nil, the default parameters are used.
+//
+// See http://groonga.org/docs/reference/commands/table_create.html for details.
+func (db *DB) CreateTable(name string, options *TableOptions) (*Table, error) {
+	if options == nil {
+		options = NewTableOptions()
+	}
+	optionsMap, err := db.createTableOptionsMap(name, options)
+	if err != nil {
+		return nil, err
+	}
+	bytes, err := db.queryJSON("table_create", optionsMap)
+	if err != nil {
+		return nil, err
+	}
+	if string(bytes) != "true" {
+		return nil, fmt.Errorf("table_create failed: name = <%s>", name)
+	}
+	return db.FindTable(name)
+}
+
+// FindTable finds a table.
+func (db *DB) FindTable(name string) (*Table, error) {
+	if err := db.checkOpen("DB.FindTable()"); err != nil {
+		return nil, err
+	}
+	if table, ok := db.tables[name]; ok {
+		return table, nil
+	}
+	c, err := db.openTable(name)
+	if err != nil {
+		return nil, err
+	}
+	table := newTable(db, c, name)
+	db.tables[name] = table
+	return table, nil
+}
+
+// openTable opens a table.
+func (db *DB) openTable(name string) (*C.grngo_table, error) {
+	nameBytes := []byte(name)
+	var cName *C.char
+	if len(nameBytes) != 0 {
+		cName = (*C.char)(unsafe.Pointer(&nameBytes[0]))
+	}
+	var c *C.grngo_table
+	rc := C.grngo_open_table(db.c, cName, C.size_t(len(nameBytes)), &c)
+	if rc != C.GRN_SUCCESS {
+		return nil, newCError("grngo_find_table()", rc, db)
+	}
+	return c, nil
+}
+
+// RemoveTable removes a table and its columns.
+//
+// Cached Table and Column handles that refer to the table are closed. They
+// are closed before the removal because the removal frees the objects, and
+// opened again if the removal fails. Open cursors of the tables are closed
+// in any case.
+func (db *DB) RemoveTable(name string) error {
+	id, err := db.objectID(name)
+	if err != nil {
+		return err
+	}
+	tables, columns := db.invalidate(id)
+	nameBytes := []byte(name)
+	rc := C.grngo_remove_table(db.c, (*C.char)(unsafe.Pointer(&nameBytes[0])),
+		C.size_t(len(nameBytes)))
+	if rc != C.GRN_SUCCESS {
+		err := newCError("grngo_remove_table()", rc, db)
+		db.reopen(tables, columns)
+		return err
+	}
+	return nil
+}
+
+// RenameTable renames a table.
+//
+// Cached Table and Column handles that refer to the table are closed on
+// success.
+func (db *DB) RenameTable(name, newName string) error {
+	if newName == "" {
+		return fmt.Errorf("invalid name: newName = <%s>: %w", newName, ErrInvalidArgument)
+	}
+	id, err := db.objectID(name)
+	if err != nil {
+		return err
+	}
+	nameBytes := []byte(name)
+	newNameBytes := []byte(newName)
+	rc := C.grngo_rename_table(db.c, (*C.char)(unsafe.Pointer(&nameBytes[0])),
+		C.size_t(len(nameBytes)), (*C.char)(unsafe.Pointer(&newNameBytes[0])),
+		C.size_t(len(newNameBytes)))
+	if rc != C.GRN_SUCCESS {
+		return newCError("grngo_rename_table()", rc, db)
+	}
+	db.invalidate(id)
+	return nil
+}
+
+// Tables returns descriptions of all the tables in the database in
+// alphabetical order.
+func (db *DB) Tables() ([]*TableInfo, error) {
+	schema, err := db.querySchema()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(schema.Tables))
+	for name := range schema.Tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	infos := make([]*TableInfo, len(names))
+	for i, name := range names {
+		info, err := schema.Tables[name].tableInfo()
+		if err != nil {
+			return nil, err
+		}
+		infos[i] = info
+	}
+	return infos, nil
+}
+
+// LoadOptions is a set of options for load.
+// BatchSize and ChunkSize are used by LoadStream and LoadJSON, and they are
+// 1000 and 65536 by default. Zero also means the default.
+type LoadOptions struct {
+	IfExists  string
+	BatchSize int // The maximum number of records per load command.
+	ChunkSize int // The approximate number of bytes per send.
+}
+
+// NewLoadOptions returns a new LoadOptions with the default settings.
+func NewLoadOptions() *LoadOptions {
+	options := new(LoadOptions)
+	options.BatchSize = 1000
+	options.ChunkSize = 65536
+	return options
+}
+
+// LoadBatch is the result of a load command executed by LoadStream or
+// LoadJSON.
+type LoadBatch struct {
+	Loaded int   // The number of loaded records.
+	Failed int   // The number of records not loaded.
+	Err    error // The error reported by Groonga, if any.
+}
+
+// (Experimental) Load loads values.
+func (db *DB) Load(tableName string, values interface{}, options *LoadOptions) (*LoadResult, error) {
+	table, err := db.FindTable(tableName)
+	if err != nil {
+		return nil, err
+	}
+	return table.Load(values, options)
+}
+
+// LoadStream loads values from a channel or an iterator.
+func (db *DB) LoadStream(ctx context.Context, tableName string, values interface{}, options *LoadOptions) ([]LoadBatch, error) {
+	table, err := db.FindTable(tableName)
+	if err != nil {
+		return nil, err
+	}
+	return table.LoadStream(ctx, values, options)
+}
+
+// LoadJSON loads a JSON array of records.
+func (db *DB) LoadJSON(tableName string, r io.Reader, options *LoadOptions) ([]LoadBatch, error) {
+	table, err := db.FindTable(tableName)
+	if err != nil {
+		return nil, err
+	}
+	return table.LoadJSON(r, options)
+}
+
+// InsertRow finds or inserts a row.
+func (db *DB) InsertRow(tableName string, key interface{}) (inserted bool, id uint32, err error) {
+	table, err := db.FindTable(tableName)
+	if err != nil {
+		return false, NilID, err
+	}
+	return table.InsertRow(key)
+}
+
+// FindRow finds a row.
+func (db *DB) FindRow(tableName string, key interface{}) (id uint32, found bool, err error) {
+	table, err := db.FindTable(tableName)
+	if err != nil {
+		return NilID, false, err
+	}
+	return table.FindRow(key)
+}
+
+// DeleteRow deletes a row by ID.
+func (db *DB) DeleteRow(tableName string, id uint32) (deleted bool, err error) {
+	table, err := db.FindTable(tableName)
+	if err != nil {
+		return false, err
+	}
+	return table.DeleteRow(id)
+}
+
+// DeleteRowByKey deletes a row by key.
+func (db *DB) DeleteRowByKey(tableName string, key interface{}) (deleted bool, err error) {
+	table, err := db.FindTable(tableName)
+	if err != nil {
+		return false, err
+	}
+	return table.DeleteRowByKey(key)
+}
+
+// Search searches a table with an index column.
+// See Table.Search for details.
+func (db *DB) Search(tableName, indexName, query string, options *SearchOptions) ([]SearchHit, error) {
+	table, err := db.FindTable(tableName)
+	if err != nil {
+		return nil, err
+	}
+	return table.Search(indexName, query, options)
+}
+
+// CreateColumn creates a Groonga column and returns a new Column associated
+// with it.
+//
+// If valueType starts with "[]", COLUMN_VECTOR is enabled and the rest is used
+// as the type parameter.
+// If valueType contains a dot ('.'), COLUMN_INDEX is enabled and valueType is
+// split by the first dot. Then, the former part is used as the type parameter
+// and the latter part is used as the source parameter.
+// Otherwise, COLUMN_SCALAR is enabled and valueType is used as the type
+// parameter.
+//
+// If options is nil, the default parameters are used.
+//
+// See http://groonga.org/docs/reference/commands/column_create.html for details.
+func (db *DB) CreateColumn(tableName, columnName string, valueType string, options *ColumnOptions) (*Column, error) {
+	table, err := db.FindTable(tableName)
+	if err != nil {
+		return nil, err
+	}
+	return table.CreateColumn(columnName, valueType, options)
+}
+
+// FindColumn finds a column.
+func (db *DB) FindColumn(tableName, columnName string) (*Column, error) {
+	table, err := db.FindTable(tableName)
+	if err != nil {
+		return nil, err
+	}
+	return table.FindColumn(columnName)
+}
+
+// SetParseTime specifies whether GetValue and GetValues return time.Time
+// (true) or int64 microseconds (false) for Time columns and keys.
+// The default is false.
+func (db *DB) SetParseTime(parseTime bool) {
+	db.parseTime = parseTime
+}
+
+// SetValue assigns a value.
+func (db *DB) SetValue(tableName, columnName string, id uint32, value interface{}) error {
+	table, err := db.FindTable(tableName)
+	if err != nil {
+		return err
+	}
+	return table.SetValue(columnName, id, value)
+}
+
+// GetValue gets a value.
+func (db *DB) GetValue(tableName, columnName string, id uint32) (interface{}, error) {
+	table, err := db.FindTable(tableName)
+	if err != nil {
+		return nil, err
+	}
+	return table.GetValue(columnName, id)
+}
+
+// SetValues assigns values to multiple rows in one call.
+// See Column.SetValues for details.
+func (db *DB) SetValues(tableName, columnName string, ids []uint32, values interface{}) error {
+	table, err := db.FindTable(tableName)
+	if err != nil {
+		return err
+	}
+	return table.SetValues(columnName, ids, values)
+}
+
+// GetValues gets values of multiple rows in one call.
+func (db *DB) GetValues(tableName, columnName string, ids []uint32) (interface{}, error) {
+	table, err := db.FindTable(tableName)
+	if err != nil {
+		return nil, err
+	}
+	return table.GetValues(columnName, ids)
+}
+
+// Get reads a record into dst.
+func (db *DB) Get(tableName string, id uint32, dst interface{}) error {
+	table, err := db.FindTable(tableName)
+	if err != nil {
+		return err
+	}
+	return table.Get(id, dst)
+}
+
+// Put inserts a record if it does not exist and writes src into it.
+func (db *DB) Put(tableName string, key interface{}, src interface{}) (inserted bool, id uint32, err error) {
+	table, err := db.FindTable(tableName)
+	if err != nil {
+		return false, NilID, err
+	}
+	return table.Put(key, src)
+}
+
+// -- DBPool --
+
+// DBPool is a goroutine-safe pool of DBs associated with the same Groonga
+// database.
+//
+// Each DB in a DBPool has its own context and its own caches of Table and
+// Column, so a DB checked out with Get can be used by one goroutine without
+// synchronization until it is returned with Put.
+// Note that Send and Recv must be called on the same DB before returning it.
+type DBPool struct {
+	dbs    []*DB         // All the DBs. dbs[0] owns the Groonga database.
+	idle   chan *DB      // A queue of idle DBs.
+	done   chan struct{} // A channel closed by Close.
+	busy   map[*DB]bool  // DBs checked out with Get.
+	closed bool          // Whether Close has closed the DBs or not.
+	mutex  sync.Mutex    // A mutex to protect done, busy and closed.
+}
+
+// OpenDBPool opens an existing Groonga database and returns a new DBPool which
+// has size DBs associated with it.
+//
+// Note that OpenDBPool initializes Groonga if the new DBPool will be the only
+// one and implicit initialization is not disabled.
+func OpenDBPool(path string, size int) (*DBPool, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("invalid pool size: size = %d: %w",
+			size, ErrInvalidArgument)
+	}
+	db, err := OpenDB(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := new(DBPool)
+	pool.dbs = make([]*DB, 0, size)
+	pool.idle = make(chan *DB, size)
+	pool.done = make(chan struct{})
+	pool.busy = make(map[*DB]bool)
+	db.pool = pool
+	pool.dbs = append(pool.dbs, db)
+	for i := 1; i < size; i++ {
+		var c *C.grngo_db
+		rc := C.grngo_dup_db(db.c, &c)
+		if rc != C.GRN_SUCCESS {
+			err := newCError("grngo_dup_db()", rc, db)
+			pool.closeDBs()
+			return nil, err
+		}
+		newDB := newDB(c)
+		newDB.pool = pool
+		pool.dbs = append(pool.dbs, newDB)
+	}
+	for _, db := range pool.dbs {
+		pool.idle <- db
+	}
+	return pool, nil
+}
+
+// closeDBs closes all the DBs in a DBPool.
+func (pool *DBPool) closeDBs() error {
+	// The owner of the Groonga database must be closed last.
+	for i := len(pool.dbs) - 1; i > 0; i-- {
+		pool.dbs[i].Refresh()
+		C.grngo_close_db(pool.dbs[i].c)
+		pool.dbs[i].c = nil
+	}
+	db := pool.dbs[0]
+	db.pool = nil
+	db.Refresh()
+	return db.Close()
+}
+
+// Size returns the number of DBs in a DBPool.
+func (pool *DBPool) Size() int {
+	return len(pool.dbs)
+}
+
+// Get checks out an idle DB.
+// If there are no idle DBs, Get blocks until another goroutine calls Put.
+//
+// The DB must be returned with Put and must not be used after that.
+func (pool *DBPool) Get() (*DB, error) {
+	select {
+	case <-pool.done:
+		return nil, fmt.Errorf("DBPool.Get() failed: DBPool is closed: %w",
+			ErrOperationNotPermitted)
+	default:
+	}
+	select {
+	case db := <-pool.idle:
+		pool.mutex.Lock()
+		pool.busy[db] = true
+		pool.mutex.Unlock()
+		return db, nil
+	case <-pool.done:
+		return nil, fmt.Errorf("DBPool.Get() failed: DBPool is closed: %w",
+			ErrOperationNotPermitted)
+	}
+}
+
+// Put returns a DB checked out with Get.
+// Put fails if the DB is not checked out, e.g. if it is already returned.
+func (pool *DBPool) Put(db *DB) error {
+	if (db == nil) || (db.pool != pool) {
+		return fmt.Errorf("DBPool.Put() failed: DB is not owned by DBPool: %w",
+			ErrInvalidArgument)
+	}
+	pool.mutex.Lock()
+	if pool.closed {
+		pool.mutex.Unlock()
+		return fmt.Errorf("DBPool.Put() failed: DBPool is closed: %w",
+			ErrOperationNotPermitted)
+	}
+	if !pool.busy[db] {
+		pool.mutex.Unlock()
+		return fmt.Errorf("DBPool.Put() failed: DB is not checked out: %w",
+			ErrOperationNotPermitted)
+	}
+	delete(pool.busy, db)
+	pool.mutex.Unlock()
+	pool.idle <- db
+	return nil
+}
+
+// Do checks out an idle DB, calls f with it, and returns it.
+func (pool *DBPool) Do(f func(db *DB) error) error {
+	db, err := pool.Get()
+	if err != nil {
+		return err
+	}
+	defer pool.Put(db)
+	return f(db)
+}
+
+// Close waits until all the DBs are returned and closes them.
+//
+// Note that Close finalizes Groonga if the DBPool is the last one and implicit
+// finalization is not disabled.
+func (pool *DBPool) Close() error {
+	pool.mutex.Lock()
+	select {
+	case <-pool.done:
+		pool.mutex.Unlock()
+		return fmt.Errorf("DBPool.Close() failed: DBPool is already closed: %w",
+			ErrOperationNotPermitted)
+	default:
+		close(pool.done)
+	}
+	pool.mutex.Unlock()
+	for i := 0; i < len(pool.dbs); i++ {
+		<-pool.idle
+	}
+	pool.mutex.Lock()
+	pool.closed = true
+	pool.mutex.Unlock()
+	return pool.closeDBs()
+}
+
+// -- Table --
+
+// Table is associated with a Groonga table.
+type Table struct {
+	db      *DB                // The owner DB.
+	c       *C.grngo_table     // The associated C object.
+	name    string             // The table name.
+	columns map[string]*Column // A cache to find columns by name.
+	cursors map[*Cursor]bool   // Open cursors.
+}
+
+// newTable returns a new Table.
+func newTable(db *DB, c *C.grngo_table, name string) *Table {
+	var table Table
+	table.db = db
+	table.c = c
+	table.name = name
+	table.columns = make(map[string]*Column)
+	table.cursors = make(map[*Cursor]bool)
+	return &table
+}
+
+// close closes the table and its cached columns and open cursors.
+func (table *Table) close() {
+	for cursor := range table.cursors {
+		cursor.Close()
+	}
+	for _, column := range table.columns {
+		column.close()
+	}
+	table.columns = make(map[string]*Column)
+	C.grngo_close_table(table.c)
+	table.c = nil
+}
+
+// checkOpen returns an error if the table is closed.
+func (table *Table) checkOpen(op string) error {
+	if table.c == nil {
+		return fmt.Errorf("%s failed: table is closed: %w", op, ErrClosed)
+	}
+	return nil
+}
+
+// Close closes a table and its columns and cursors.
+// Close does nothing if the table is already closed.
+//
+// Note that DB.FindTable returns the same Table for the same name, so Close
+// affects all of its users. DB.FindTable opens the table again.
+func (table *Table) Close() error {
+	if table.c == nil {
+		return nil
+	}
+	if table.db.tables[table.name] == table {
+		delete(table.db.tables, table.name)
+	}
+	table.close()
+	return nil
+}
+
+// genLoadHead generates the head line of a load command.
+func (table *Table) genLoadHead(options *LoadOptions) (string, error) {
+	line := fmt.Sprintf("load --table %s", table.name)
+	if options.IfExists != "" {
+		value := strings.Replace(options.IfExists, "\\", "\\\\", -1)
+		value = strings.Replace(value, "\"", "\\\"", -1)
+		line += fmt.Sprintf(" --ifexists '%s'", value)
+	}
+	return line, nil
+}
+
+// structField is a tagged field of a struct.
+type structField struct {
+	index     int               // The field index.
+	name      string            // The column name.
+	omitEmpty bool              // Whether an empty field is omitted.
+	options   map[string]string // The key=value options, e.g. "type=Text".
+}
+
+// parseFieldTag parses a grngo tag, "name[,option...]".
+func parseFieldTag(tag string) (name string, options []string) {
+	parts := strings.Split(tag, ",")
+	return strings.TrimSpace(parts[0]), parts[1:]
+}
+
+// structFieldsCache is a cache of structFields, reflect.Type -> []structField.
+var structFieldsCache sync.Map
+
+// structFields returns the tagged fields of a struct type.
+// The result is cached per type.
+func structFields(valueType reflect.Type) ([]structField, error) {
+	if fields, ok := structFieldsCache.Load(valueType); ok {
+		return fields.([]structField), nil
+	}
+	var fields []structField
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		name, options := parseFieldTag(field.Tag.Get("grngo"))
+		if (name == "") || (name == "-") {
+			continue
+		}
+		if field.PkgPath != "" {
+			return nil, fmt.Errorf("unexported field: name = %s: %w",
+				field.Name, ErrInvalidArgument)
+		}
+		structField := structField{index: i, name: name}
+		for _, option := range options {
+			option = strings.TrimSpace(option)
+			if option == "omitempty" {
+				structField.omitEmpty = true
+			} else if delimPos := strings.IndexByte(option, '='); delimPos != -1 {
+				if structField.options == nil {
+					structField.options = make(map[string]string)
+				}
+				structField.options[option[:delimPos]] = option[delimPos+1:]
+			}
+		}
+		fields = append(fields, structField)
+	}
+	structFieldsCache.Store(valueType, fields)
+	return fields, nil
+}
+
+// writeLoadString writes a string as a JSON string.
+func writeLoadString(buf *bytes.Buffer, str string) {
+	buf.WriteByte('"')
+	for i := 0; i < len(str); i++ {
+		switch c := str[i]; {
+		case (c == '"') || (c == '\\'):
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		case c < 0x20:
+			fmt.Fprintf(buf, "\\u%04x", c)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	buf.WriteByte('"')
+}
+
+// writeLoadTime writes a time.Time as seconds with microseconds.
+func writeLoadTime(buf *bytes.Buffer, t time.Time) {
+	micros := timeToMicros(t)
+	if micros < 0 {
+		buf.WriteByte('-')
+		micros = -micros
+	}
+	fmt.Fprintf(buf, "%d.%06d", micros/1000000, micros%1000000)
+}
+
+// isEmptyValue reports whether a value is empty as in encoding/json, that is,
+// false, 0, "", a nil pointer or interface, or an empty array, slice or map.
+// A struct is never empty.
+func isEmptyValue(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return value.Len() == 0
+	case reflect.Bool:
+		return !value.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Uintptr:
+		return value.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return value.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return value.IsNil()
+	}
+	return false
+}
+
+// writeLoadValue writes a record of a load command as an object.
+func (table *Table) writeLoadValue(buf *bytes.Buffer, value *reflect.Value, fields []structField) error {
+	buf.WriteByte('{')
+	needsDelimiter := false
+	for _, field := range fields {
+		fieldValue := value.Field(field.index)
+		if field.omitEmpty && isEmptyValue(fieldValue) {
+			continue
+		}
+		if needsDelimiter {
+			buf.WriteByte(',')
+		} else {
+			needsDelimiter = true
+		}
+		writeLoadString(buf, field.name)
+		buf.WriteByte(':')
+		if err := table.writeLoadElem(buf, fieldValue); err != nil {
+			return fmt.Errorf("column = %s: %w", field.name, err)
+		}
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+// writeLoadElem writes a value of a column.
+// A nil pointer is written as null and a struct other than time.Time and
+// GeoPoint is written as the value of its field tagged "_key".
+func (table *Table) writeLoadElem(buf *bytes.Buffer, value reflect.Value) error {
+	if value.CanInterface() {
+		switch special := value.Interface().(type) {
+		case time.Time:
+			writeLoadTime(buf, special)
+			return nil
+		case GeoPoint:
+			fmt.Fprintf(buf, "\"%dx%d\"", special.Latitude, special.Longitude)
+			return nil
+		}
+	}
+	if isWeightedSliceType(value.Type()) {
+		return table.writeLoadWeighted(buf, value)
+	}
+	switch value.Kind() {
+	case reflect.Bool:
+		buf.WriteString(strconv.FormatBool(value.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		buf.WriteString(strconv.FormatInt(value.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		buf.WriteString(strconv.FormatUint(value.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		f := value.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return fmt.Errorf("unsupported value: %v: %w", f, ErrInvalidArgument)
+		}
+		bitSize := 64
+		if value.Kind() == reflect.Float32 {
+			bitSize = 32
+		}
+		buf.WriteString(strconv.FormatFloat(f, 'g', -1, bitSize))
+	case reflect.String:
+		writeLoadString(buf, value.String())
+	case reflect.Ptr, reflect.Interface:
+		if value.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		return table.writeLoadElem(buf, value.Elem())
+	case reflect.Slice, reflect.Array:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			if value.Kind() == reflect.Slice {
+				writeLoadString(buf, string(value.Bytes()))
+			} else {
+				bytes := make([]byte, value.Len())
+				reflect.Copy(reflect.ValueOf(bytes), value)
+				writeLoadString(buf, string(bytes))
+			}
+			return nil
+		}
+		buf.WriteByte('[')
+		for i := 0; i < value.Len(); i++ {
+			if i != 0 {
+				buf.WriteByte(',')
+			}
+			if err := table.writeLoadElem(buf, value.Index(i)); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case reflect.Struct:
+		fields, err := structFields(value.Type())
+		if err != nil {
+			return err
+		}
+		for _, field := range fields {
+			if field.name == "_key" {
+				return table.writeLoadElem(buf, value.Field(field.index))
+			}
+		}
+		return fmt.Errorf("unsupported data type: %s has no _key field: %w",
+			value.Type(), ErrInvalidArgument)
+	default:
+		return fmt.Errorf("unsupported data type: %s: %w",
+			value.Type(), ErrInvalidArgument)
+	}
+	return nil
+}
+
+// writeLoadWeighted writes a weight vector as {"value": weight, ...}.
+// A value which is not written as a string, e.g. a number, is quoted.
+func (table *Table) writeLoadWeighted(buf *bytes.Buffer, value reflect.Value) error {
+	var elemBuf bytes.Buffer
+	buf.WriteByte('{')
+	for i := 0; i < value.Len(); i++ {
+		if i != 0 {
+			buf.WriteByte(',')
+		}
+		elemBuf.Reset()
+		if err := table.writeLoadElem(&elemBuf, value.Index(i).Field(0)); err != nil {
+			return err
+		}
+		if bytes.HasPrefix(elemBuf.Bytes(), []byte("\"")) {
+			buf.Write(elemBuf.Bytes())
+		} else {
+			writeLoadString(buf, elemBuf.String())
+		}
+		fmt.Fprintf(buf, ":%d", value.Index(i).Field(1).Uint())
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+// loadRecords returns the records and the tagged fields of values.
+func loadRecords(values interface{}) ([]reflect.Value, []structField, error) {
+	value := reflect.ValueOf(values)
+	for value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+	switch value.Kind() {
+	case reflect.Struct:
+		fields, err := structFields(value.Type())
+		if err != nil {
+			return nil, nil, err
+		}
+		return []reflect.Value{value}, fields, nil
+	case reflect.Slice:
+		if value.Len() == 0 {
+			return nil, nil, fmt.Errorf("invalid values")
+		}
+		valueType := value.Type().Elem()
+		isPtr := valueType.Kind() == reflect.Ptr
+		if isPtr {
+			valueType = valueType.Elem()
+		}
+		if valueType.Kind() != reflect.Struct {
+			return nil, nil, fmt.Errorf("invalid values")
+		}
+		fields, err := structFields(valueType)
+		if err != nil {
+			return nil, nil, err
+		}
+		records := make([]reflect.Value, value.Len())
+		for i := range records {
+			records[i] = value.Index(i)
+			if isPtr {
+				if records[i].IsNil() {
+					return nil, nil, fmt.Errorf("invalid values: values[%d] is nil", i)
+				}
+				records[i] = records[i].Elem()
+			}
+		}
+		return records, fields, nil
+	default:
+		return nil, nil, fmt.Errorf("invalid values")
+	}
+}
+
+// writeLoadBody writes the body line of a load command.
+func (table *Table) writeLoadBody(buf *bytes.Buffer, records []reflect.Value, fields []structField) error {
+	buf.WriteByte('[')
+	for i := range records {
+		if i != 0 {
+			buf.WriteByte(',')
+		}
+		if err := table.writeLoadValue(buf, &records[i], fields); err != nil {
+			if len(records) == 1 {
+				return err
+			}
+			return fmt.Errorf("values[%d]: %w", i, err)
+		}
+	}
+	buf.WriteByte(']')
+	return nil
+}
+
+// genLoadBody generates the body line of a load command.
+func (table *Table) genLoadBody(values interface{}) (string, error) {
+	records, fields, err := loadRecords(values)
+	if err != nil {
+		return "", err
+	}
+	buf := new(bytes.Buffer)
+	if err := table.writeLoadBody(buf, records, fields); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// LoadResult is the result of Load.
+//
+// IDs and Errors are aligned with the input records. IDs[i] is the ID of the
+// i-th record or NilID if it was not loaded, and Errors has an entry for each
+// record not loaded.
+type LoadResult struct {
+	Loaded int         // The number of loaded records.
+	IDs    []uint32    // The IDs of the records.
+	Errors []*RowError // The errors of the records not loaded.
+}
+
+// loadResultBody is the body of load with output_ids and output_errors.
+type loadResultBody struct {
+	NLoadedRecords int      `json:"n_loaded_records"`
+	LoadedIDs      []uint32 `json:"loaded_ids"`
+	Errors         []struct {
+		ReturnCode int    `json:"return_code"`
+		Message    string `json:"message"`
+	} `json:"errors"`
+}
+
+// supportsLoadOutputs returns whether load supports output_ids and
+// output_errors, which are available since Groonga 7.1.0.
+func supportsLoadOutputs() bool {
+	var major, minor int
+	fmt.Sscanf(GrnVersion(), "%d.%d", &major, &minor)
+	return (major > 7) || ((major == 7) && (minor >= 1))
+}
+
+// parseLoadOutputs parses the output of load with output_ids and
+// output_errors.
+func parseLoadOutputs(output []byte, nRecords int) (*LoadResult, bool) {
+	response := new(Response)
+	body := output
+	if response.parseEnvelope(output) {
+		body = response.Body
+	}
+	var resultBody loadResultBody
+	if err := json.Unmarshal(body, &resultBody); err != nil ||
+		(len(resultBody.LoadedIDs) != nRecords) {
+		return nil, false
+	}
+	result := &LoadResult{Loaded: resultBody.NLoadedRecords, IDs: resultBody.LoadedIDs}
+	for i, id := range result.IDs {
+		if id != NilID {
+			continue
+		}
+		err := &Error{Op: "load", RC: ErrInvalidArgument,
+			Message: "record not loaded"}
+		if i < len(resultBody.Errors) {
+			if rc := RC(resultBody.Errors[i].ReturnCode); rc != Success {
+				err.RC = rc
+			}
+			if message := resultBody.Errors[i].Message; message != "" {
+				err.Message = message
+			}
+		}
+		result.Errors = append(result.Errors, &RowError{Index: i, Err: err})
+	}
+	return result, true
+}
+
+// loadKey returns the "_key" field of a record as a key for FindRow.
+func loadKey(record reflect.Value, fields []structField) (interface{}, bool) {
+	for _, field := range fields {
+		if field.name != "_key" {
+			continue
+		}
+		value := record.Field(field.index)
+		for (value.Kind() == reflect.Ptr) || (value.Kind() == reflect.Interface) {
+			if value.IsNil() {
+				return nil, false
+			}
+			value = value.Elem()
+		}
+		switch value.Kind() {
+		case reflect.Bool:
+			return value.Bool(), true
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return value.Int(), true
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return value.Uint(), true
+		case reflect.Float32, reflect.Float64:
+			return value.Float(), true
+		case reflect.String:
+			return []byte(value.String()), true
+		case reflect.Slice:
+			if value.Type().Elem().Kind() == reflect.Uint8 {
+				return value.Bytes(), true
+			}
+		case reflect.Struct:
+			switch key := value.Interface().(type) {
+			case time.Time, GeoPoint:
+				return key, true
+			}
+		}
+		return nil, false
+	}
+	return nil, false
+}
+
+// resolveLoadResult builds a LoadResult by finding the keys of records.
+// This is the fallback for Groonga without output_ids and output_errors.
+// A rejected record whose key already exists is found and looks loaded, so
+// Load compares Loaded with the number of found records.
+func (table *Table) resolveLoadResult(output []byte, records []reflect.Value, fields []structField) (*LoadResult, error) {
+	n, err := strconv.Atoi(string(bytes.TrimSpace(output)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid load result: %s", output)
+	}
+	result := &LoadResult{Loaded: n}
+	if table.c.key_type == C.GRN_DB_VOID {
+		// Records of a table without keys cannot be identified.
+		return result, nil
+	}
+	result.IDs = make([]uint32, len(records))
+	for i, record := range records {
+		key, ok := loadKey(record, fields)
+		if !ok {
+			result.Errors = append(result.Errors, &RowError{Index: i,
+				Err: fmt.Errorf("record has no key: %w", ErrInvalidArgument)})
+			continue
+		}
+		id, found, err := table.FindRow(key)
+		if err != nil {
+			result.Errors = append(result.Errors, &RowError{Index: i, Err: err})
+			continue
+		}
+		if !found {
+			result.Errors = append(result.Errors, &RowError{Index: i,
+				Err: &Error{Op: "load", RC: ErrInvalidArgument,
+					Message: "record not loaded"}})
+			continue
+		}
+		result.IDs[i] = id
+	}
+	return result, nil
+}
+
+// (Experimental) Load loads values.
+//
+// values must be a struct, a pointer to a struct or a slice of structs or
+// pointers to structs. Fields tagged `grngo:"column"` are loaded.
+// Integers, floats, bool, string, []byte, time.Time, GeoPoint and slices of
+// them are supported. A slice of Weighted is loaded as a weight vector.
+// A struct field is loaded as the value of its "_key" field, which is useful
+// for reference columns.
+// A nil pointer is loaded as null.
+// A field tagged "omitempty", e.g. `grngo:"column,omitempty"`, is omitted if
+// it is empty as in encoding/json: false, 0, "", a nil pointer or interface,
+// or an empty array, slice or map. A struct such as time.Time is never
+// omitted.
+// Implicit conversion from int64 to Time is not supported.
+//
+// Load reports the IDs and the errors of records by using output_ids and
+// output_errors of load if available. Otherwise, Load finds the IDs by keys,
+// and IDs and Errors are empty for a table without keys. In this case, a
+// rejected record whose key already exists cannot be told from a loaded one,
+// and Load returns an error if Loaded is less than the number of found records
+// because IDs and Errors are unreliable.
+// If some records are not loaded, Load returns the result with an error.
+func (table *Table) Load(values interface{}, options *LoadOptions) (*LoadResult, error) {
+	if err := table.checkOpen("Table.Load()"); err != nil {
+		return nil, err
+	}
+	if options == nil {
+		options = NewLoadOptions()
+	}
+	headLine, err := table.genLoadHead(options)
+	if err != nil {
+		return nil, err
+	}
+	records, fields, err := loadRecords(values)
+	if err != nil {
+		return nil, err
+	}
+	buf := new(bytes.Buffer)
+	if err := table.writeLoadBody(buf, records, fields); err != nil {
+		return nil, err
+	}
+	bodyLine := buf.String()
+	withOutputs := supportsLoadOutputs()
+	if withOutputs {
+		headLine += " --command_version 3 --output_ids yes --output_errors yes"
+	}
+	lines := []string{ headLine, bodyLine }
+	for _, line := range lines {
+		if err := table.db.Send(line); err != nil {
+			table.db.Recv()
+			return nil, err
+		}
+	}
+	output, recvErr := table.db.Recv()
+	var result *LoadResult
+	ok := false
+	if withOutputs {
+		result, ok = parseLoadOutputs(output, len(records))
+	}
+	if !ok {
+		if recvErr != nil {
+			return nil, recvErr
+		}
+		if result, err = table.resolveLoadResult(output, records, fields); err != nil {
+			return nil, err
+		}
+	}
+	if err := result.check(len(records)); err != nil {
+		return result, err
+	}
+	return result, recvErr
+}
+
+// check returns an error if some of nRecords records are not loaded.
+func (result *LoadResult) check(nRecords int) error {
+	if (result.IDs != nil) && (result.Loaded < nRecords-len(result.Errors)) {
+		return fmt.Errorf("%d of %d records not loaded: IDs and Errors are unreliable",
+			nRecords-result.Loaded, nRecords)
+	}
+	if len(result.Errors) != 0 {
+		return fmt.Errorf("%d of %d records not loaded: %w",
+			len(result.Errors), nRecords, result.Errors[0])
+	}
+	if (result.IDs == nil) && (result.Loaded < nRecords) {
+		return fmt.Errorf("%d of %d records not loaded",
+			nRecords-result.Loaded, nRecords)
+	}
+	return nil
+}
+
+// loadStream sends records in batches of load commands.
+type loadStream struct {
+	table    *Table
+	options  *LoadOptions
+	head     string       // The head line of each load command.
+	columns  []byte       // The column names for the array form, or nil.
+	buf      bytes.Buffer // The pending part of the body.
+	nRecords int          // The number of records in the current batch.
+	batches  []LoadBatch  // The results of finished batches.
+}
+
+// newLoadStream returns a new loadStream.
+func (table *Table) newLoadStream(options *LoadOptions) (*loadStream, error) {
+	defaultOptions := NewLoadOptions()
+	if options == nil {
+		options = defaultOptions
+	}
+	if (options.BatchSize < 0) || (options.ChunkSize < 0) {
+		return nil, fmt.Errorf("invalid options: batchSize = %d, chunkSize = %d: %w",
+			options.BatchSize, options.ChunkSize, ErrInvalidArgument)
+	}
+	if (options.BatchSize == 0) || (options.ChunkSize == 0) {
+		newOptions := *options
+		if newOptions.BatchSize == 0 {
+			newOptions.BatchSize = defaultOptions.BatchSize
+		}
+		if newOptions.ChunkSize == 0 {
+			newOptions.ChunkSize = defaultOptions.ChunkSize
+		}
+		options = &newOptions
+	}
+	head, err := table.genLoadHead(options)
+	if err != nil {
+		return nil, err
+	}
+	return &loadStream{table: table, options: options, head: head}, nil
+}
+
+// begin starts a record, and a load command if needed.
+func (stream *loadStream) begin() error {
+	if stream.nRecords == 0 {
+		if err := stream.table.db.Send(stream.head); err != nil {
+			stream.table.db.Recv()
+			return err
+		}
+		stream.buf.WriteByte('[')
+		if stream.columns != nil {
+			stream.buf.Write(stream.columns)
+			stream.buf.WriteByte(',')
+		}
+	} else {
+		stream.buf.WriteByte(',')
+	}
+	return nil
+}
+
+// flush sends the pending part of the body.
+func (stream *loadStream) flush() error {
+	if stream.buf.Len() == 0 {
+		return nil
+	}
+	err := stream.table.db.Send(stream.buf.String())
+	stream.buf.Reset()
+	return err
+}
+
+// end finishes a record, and the load command if the batch is full.
+func (stream *loadStream) end() error {
+	stream.nRecords++
+	if stream.nRecords >= stream.options.BatchSize {
+		return stream.finish()
+	}
+	if stream.buf.Len() >= stream.options.ChunkSize {
+		return stream.flush()
+	}
+	return nil
+}
+
+// finish finishes the current load command.
+func (stream *loadStream) finish() error {
+	if stream.nRecords == 0 {
+		return nil
+	}
+	batch := LoadBatch{Failed: stream.nRecords}
+	stream.nRecords = 0
+	stream.buf.WriteByte(']')
+	err := stream.flush()
+	output, recvErr := stream.table.db.Recv()
+	if err != nil {
+		return err
+	}
+	batch.Err = recvErr
+	if n, err := strconv.Atoi(string(bytes.TrimSpace(output))); err == nil {
+		batch.Loaded = n
+		batch.Failed -= n
+	} else if batch.Err == nil {
+		batch.Err = fmt.Errorf("invalid load result: %s", output)
+	}
+	stream.batches = append(stream.batches, batch)
+	return nil
+}
+
+// abort finishes the current load command and returns err.
+func (stream *loadStream) abort(err error) ([]LoadBatch, error) {
+	if stream.nRecords != 0 {
+		stream.finish()
+	}
+	return stream.batches, err
+}
+
+// LoadStream loads values from a channel or an iterator in batches.
+//
+// values must be a receivable channel or an iterator,
+// func(yield func(T) bool), where T is a struct or a pointer to a struct as
+// accepted by Load. LoadStream sends at most options.BatchSize records per
+// load command and the body in chunks of about options.ChunkSize bytes, and
+// returns the result of each load command. LoadStream stops when values is
+// exhausted or ctx is done.
+func (table *Table) LoadStream(ctx context.Context, values interface{}, options *LoadOptions) ([]LoadBatch, error) {
+	if err := table.checkOpen("Table.LoadStream()"); err != nil {
+		return nil, err
+	}
+	stream, err := table.newLoadStream(options)
+	if err != nil {
+		return nil, err
+	}
+	value := reflect.ValueOf(values)
+	var valueType reflect.Type
+	switch {
+	case (value.Kind() == reflect.Chan) && (value.Type().ChanDir()&reflect.RecvDir != 0):
+		valueType = value.Type().Elem()
+	case (value.Kind() == reflect.Func) && (value.Type().NumIn() == 1) &&
+		(value.Type().NumOut() == 0) && (value.Type().In(0).Kind() == reflect.Func) &&
+		(value.Type().In(0).NumIn() == 1) && (value.Type().In(0).NumOut() == 1) &&
+		(value.Type().In(0).Out(0).Kind() == reflect.Bool):
+		valueType = value.Type().In(0).In(0)
+	default:
+		return nil, fmt.Errorf("invalid values: type = %T: %w", values, ErrInvalidArgument)
+	}
+	isPtr := valueType.Kind() == reflect.Ptr
+	if isPtr {
+		valueType = valueType.Elem()
+	}
+	if valueType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("invalid values: type = %T: %w", values, ErrInvalidArgument)
+	}
+	fields, err := structFields(valueType)
+	if err != nil {
+		return nil, err
+	}
+	write := func(v reflect.Value) error {
+		if isPtr {
+			if v.IsNil() {
+				return fmt.Errorf("invalid values: nil record: %w", ErrInvalidArgument)
+			}
+			v = v.Elem()
+		}
+		// A record is serialized before begin so that a failure leaves the
+		// body well-formed.
+		var buf bytes.Buffer
+		if err := table.writeLoadValue(&buf, &v, fields); err != nil {
+			return err
+		}
+		if err := stream.begin(); err != nil {
+			return err
+		}
+		stream.buf.Write(buf.Bytes())
+		return stream.end()
+	}
+	if value.Kind() == reflect.Chan {
+		cases := []reflect.SelectCase{
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+			{Dir: reflect.SelectRecv, Chan: value},
+		}
+		for {
+			chosen, v, ok := reflect.Select(cases)
+			if chosen == 0 {
+				return stream.abort(ctx.Err())
+			}
+			if !ok {
+				break
+			}
+			if err := write(v); err != nil {
+				return stream.abort(err)
+			}
+		}
+	} else {
+		var yieldErr error
+		yield := reflect.MakeFunc(value.Type().In(0), func(args []reflect.Value) []reflect.Value {
+			if yieldErr = ctx.Err(); yieldErr == nil {
+				yieldErr = write(args[0])
+			}
+			return []reflect.Value{reflect.ValueOf(yieldErr == nil)}
+		})
+		value.Call([]reflect.Value{yield})
+		if yieldErr != nil {
+			return stream.abort(yieldErr)
+		}
 	}
-	// http://groonga.org/docs/reference/commands/table_create.html#token-filters
-	if len(options.TokenFilters) != 0 {
-		optionsMap["token_filters"] = strings.Join(options.TokenFilters, ",")
+	if err := stream.finish(); err != nil {
+		return stream.batches, err
 	}
-	return optionsMap, nil
+	return stream.batches, nil
 }
 
-// CreateTable creates a Groonga table and returns a new Table associated with
-// it.
-//
-// If options is nil, the default parameters are used.
+// LoadJSON loads a JSON array of records in batches.
 //
-// See http://groonga.org/docs/reference/commands/table_create.html for details.
-func (db *DB) CreateTable(name string, options *TableOptions) (*Table, error) {
-	if options == nil {
-		options = NewTableOptions()
-	}
-	optionsMap, err := db.createTableOptionsMap(name, options)
-	if err != nil {
+// The input is either [[column, ...], [value, ...], ...] or
+// [{column: value, ...}, ...]. LoadJSON reads the input one record at a time
+// and sends records in the same way as LoadStream.
+func (table *Table) LoadJSON(r io.Reader, options *LoadOptions) ([]LoadBatch, error) {
+	if err := table.checkOpen("Table.LoadJSON()"); err != nil {
 		return nil, err
 	}
-	bytes, err := db.QueryEx("table_create", optionsMap)
+	stream, err := table.newLoadStream(options)
 	if err != nil {
 		return nil, err
 	}
-	if string(bytes) != "true" {
-		return nil, fmt.Errorf("table_create failed: name = <%s>", name)
+	decoder := json.NewDecoder(r)
+	if token, err := decoder.Token(); err != nil {
+		return nil, fmt.Errorf("json.Decoder.Token() failed: %w", err)
+	} else if token != json.Delim('[') {
+		return nil, fmt.Errorf("invalid JSON: not an array: %w", ErrInvalidArgument)
 	}
-	return db.FindTable(name)
-}
-
-// FindTable finds a table.
-func (db *DB) FindTable(name string) (*Table, error) {
-	if table, ok := db.tables[name]; ok {
-		return table, nil
+	for i := 0; decoder.More(); i++ {
+		var record json.RawMessage
+		if err := decoder.Decode(&record); err != nil {
+			return stream.abort(fmt.Errorf("json.Decoder.Decode() failed: %w", err))
+		}
+		record = bytes.TrimSpace(record)
+		if (i == 0) && (len(record) != 0) && (record[0] == '[') {
+			stream.columns = record
+			continue
+		}
+		if err := stream.begin(); err != nil {
+			return stream.abort(err)
+		}
+		stream.buf.Write(record)
+		if err := stream.end(); err != nil {
+			return stream.abort(err)
+		}
 	}
-	nameBytes := []byte(name)
-	var cName *C.char
-	if len(nameBytes) != 0 {
-		cName = (*C.char)(unsafe.Pointer(&nameBytes[0]))
+	if _, err := decoder.Token(); err != nil {
+		return stream.abort(fmt.Errorf("json.Decoder.Token() failed: %w", err))
 	}
-	var c *C.grngo_table
-	rc := C.grngo_open_table(db.c, cName, C.size_t(len(nameBytes)), &c)
-	if rc != C.GRN_SUCCESS {
-		return nil, newCError("grngo_find_table()", rc, db)
+	if err := stream.finish(); err != nil {
+		return stream.batches, err
 	}
-	table := newTable(db, c, name)
-	db.tables[name] = table
-	return table, nil
-}
-
-type LoadOptions struct {
-	IfExists string
+	return stream.batches, nil
 }
 
-// NewLoadOptions returns a new LoadOptions with the default settings.
-func NewLoadOptions() *LoadOptions {
-	options := new(LoadOptions)
-	return options
+// encodeKey converts a key into the internal representation of the key type.
+// If the key type is a table, the key type of the last referred table is used.
+func (table *Table) encodeKey(key interface{}) ([]byte, error) {
+	return encodeKey(table.c.key_type, key)
 }
 
-// (Experimental) Load loads values.
-func (db *DB) Load(tableName string, values interface{}, options *LoadOptions) ([]byte, error) {
-	table, err := db.FindTable(tableName)
-	if err != nil {
-		return nil, err
+// encodeKey converts a key into the internal representation of a builtin type.
+func encodeKey(keyType C.grn_builtin_type, key interface{}) ([]byte, error) {
+	invalidKey := func() error {
+		return fmt.Errorf("invalid key: keyType = %s, key = %v: %w",
+			DataType(keyType), key, ErrInvalidArgument)
+	}
+	switch key := key.(type) {
+	case nil:
+		if keyType != C.GRN_DB_VOID {
+			return nil, invalidKey()
+		}
+		return nil, nil
+	case bool:
+		if keyType != C.GRN_DB_BOOL {
+			return nil, invalidKey()
+		}
+		cKey := C.grn_bool(C.GRN_FALSE)
+		if key {
+			cKey = C.grn_bool(C.GRN_TRUE)
+		}
+		return C.GoBytes(unsafe.Pointer(&cKey), C.int(unsafe.Sizeof(cKey))), nil
+	case int64:
+		var ptr unsafe.Pointer
+		var size uintptr
+		switch keyType {
+		case C.GRN_DB_INT8:
+			if (key < math.MinInt8) || (key > math.MaxInt8) {
+				return nil, invalidKey()
+			}
+			cKey := C.int8_t(key)
+			ptr, size = unsafe.Pointer(&cKey), unsafe.Sizeof(cKey)
+		case C.GRN_DB_INT16:
+			if (key < math.MinInt16) || (key > math.MaxInt16) {
+				return nil, invalidKey()
+			}
+			cKey := C.int16_t(key)
+			ptr, size = unsafe.Pointer(&cKey), unsafe.Sizeof(cKey)
+		case C.GRN_DB_INT32:
+			if (key < math.MinInt32) || (key > math.MaxInt32) {
+				return nil, invalidKey()
+			}
+			cKey := C.int32_t(key)
+			ptr, size = unsafe.Pointer(&cKey), unsafe.Sizeof(cKey)
+		case C.GRN_DB_INT64, C.GRN_DB_TIME:
+			cKey := C.int64_t(key)
+			ptr, size = unsafe.Pointer(&cKey), unsafe.Sizeof(cKey)
+		case C.GRN_DB_UINT8:
+			if (key < 0) || (key > math.MaxUint8) {
+				return nil, invalidKey()
+			}
+			cKey := C.uint8_t(key)
+			ptr, size = unsafe.Pointer(&cKey), unsafe.Sizeof(cKey)
+		case C.GRN_DB_UINT16:
+			if (key < 0) || (key > math.MaxUint16) {
+				return nil, invalidKey()
+			}
+			cKey := C.uint16_t(key)
+			ptr, size = unsafe.Pointer(&cKey), unsafe.Sizeof(cKey)
+		case C.GRN_DB_UINT32:
+			if (key < 0) || (key > math.MaxUint32) {
+				return nil, invalidKey()
+			}
+			cKey := C.uint32_t(key)
+			ptr, size = unsafe.Pointer(&cKey), unsafe.Sizeof(cKey)
+		case C.GRN_DB_UINT64:
+			if key < 0 {
+				return nil, invalidKey()
+			}
+			cKey := C.uint64_t(key)
+			ptr, size = unsafe.Pointer(&cKey), unsafe.Sizeof(cKey)
+		default:
+			return nil, invalidKey()
+		}
+		return C.GoBytes(ptr, C.int(size)), nil
+	case uint64:
+		var ptr unsafe.Pointer
+		var size uintptr
+		switch keyType {
+		case C.GRN_DB_INT8, C.GRN_DB_INT16, C.GRN_DB_INT32, C.GRN_DB_INT64,
+			C.GRN_DB_UINT8, C.GRN_DB_UINT16, C.GRN_DB_UINT32:
+			if key > math.MaxInt64 {
+				return nil, invalidKey()
+			}
+			return encodeKey(keyType, int64(key))
+		case C.GRN_DB_UINT64:
+			cKey := C.uint64_t(key)
+			ptr, size = unsafe.Pointer(&cKey), unsafe.Sizeof(cKey)
+		default:
+			return nil, invalidKey()
+		}
+		return C.GoBytes(ptr, C.int(size)), nil
+	case float64:
+		if (keyType != C.GRN_DB_FLOAT) || math.IsNaN(key) {
+			return nil, invalidKey()
+		}
+		cKey := C.double(key)
+		return C.GoBytes(unsafe.Pointer(&cKey), C.int(unsafe.Sizeof(cKey))), nil
+	case []byte:
+		if (keyType != C.GRN_DB_SHORT_TEXT) ||
+			(len(key) >= C.GRNGO_MAX_SHORT_TEXT_LEN) {
+			return nil, invalidKey()
+		}
+		return key, nil
+	case time.Time:
+		if keyType != C.GRN_DB_TIME {
+			return nil, invalidKey()
+		}
+		cKey := C.int64_t(timeToMicros(key))
+		return C.GoBytes(unsafe.Pointer(&cKey), C.int(unsafe.Sizeof(cKey))), nil
+	case GeoPoint:
+		if (keyType != C.GRN_DB_TOKYO_GEO_POINT) &&
+			(keyType != C.GRN_DB_WGS84_GEO_POINT) {
+			return nil, invalidKey()
+		}
+		const (
+			MaxLatitude  = 90 * 60 * 60 * 1000
+			MaxLongitude = 180 * 60 * 60 * 1000
+		)
+		if (key.Latitude < -MaxLatitude) || (key.Latitude > MaxLatitude) ||
+			(key.Longitude < -MaxLongitude) || (key.Longitude > MaxLongitude) {
+			return nil, invalidKey()
+		}
+		cKey := C.grn_geo_point{C.int(key.Latitude), C.int(key.Longitude)}
+		return C.GoBytes(unsafe.Pointer(&cKey), C.int(unsafe.Sizeof(cKey))), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type: typeName = <%s>: %w",
+			reflect.TypeOf(key).Name(), ErrInvalidArgument)
 	}
-	return table.Load(values, options)
 }
 
 // InsertRow finds or inserts a row.
-func (db *DB) InsertRow(tableName string, key interface{}) (inserted bool, id uint32, err error) {
-	table, err := db.FindTable(tableName)
-	if err != nil {
+func (table *Table) InsertRow(key interface{}) (inserted bool, id uint32, err error) {
+	if err := table.checkOpen("Table.InsertRow()"); err != nil {
 		return false, NilID, err
 	}
-	return table.InsertRow(key)
+	var rc C.grn_rc
+	var cInserted C.grn_bool
+	var cID C.grn_id
+	switch key := key.(type) {
+	case nil:
+		rc = C.grngo_insert_void(table.c, &cInserted, &cID)
+	case bool:
+		cKey := C.grn_bool(C.GRN_FALSE)
+		if key {
+			cKey = C.grn_bool(C.GRN_TRUE)
+		}
+		rc = C.grngo_insert_bool(table.c, cKey, &cInserted, &cID)
+	case int64:
+		cKey := C.int64_t(key)
+		rc = C.grngo_insert_int(table.c, cKey, &cInserted, &cID)
+	case uint64:
+		cKey := C.uint64_t(key)
+		rc = C.grngo_insert_uint(table.c, cKey, &cInserted, &cID)
+	case time.Time:
+		if table.c.key_type != C.GRN_DB_TIME {
+			return false, NilID, fmt.Errorf("invalid key: keyType = %s, key = %v: %w",
+				DataType(table.c.key_type), key, ErrInvalidArgument)
+		}
+		cKey := C.int64_t(timeToMicros(key))
+		rc = C.grngo_insert_int(table.c, cKey, &cInserted, &cID)
+	case float64:
+		cKey := C.double(key)
+		rc = C.grngo_insert_float(table.c, cKey, &cInserted, &cID)
+	case []byte:
+		var cKey C.grngo_text
+		if len(key) != 0 {
+			cKey.ptr = (*C.char)(unsafe.Pointer(&key[0]))
+			cKey.size = C.size_t(len(key))
+		}
+		rc = C.grngo_insert_text(table.c, cKey, &cInserted, &cID)
+	case GeoPoint:
+		cKey := C.grn_geo_point{C.int(key.Latitude), C.int(key.Longitude)}
+		rc = C.grngo_insert_geo_point(table.c, cKey, &cInserted, &cID)
+	default:
+		return false, NilID, fmt.Errorf(
+			"unsupported key type: typeName = <%s>: %w",
+			reflect.TypeOf(key).Name(), ErrInvalidArgument)
+	}
+	if rc != C.GRN_SUCCESS {
+		return false, NilID, newCError("grngo_insert_*()", rc, table.db)
+	}
+	return cInserted == C.GRN_TRUE, uint32(cID), nil
 }
 
-// CreateColumn creates a Groonga column and returns a new Column associated
-// with it.
-//
-// If valueType starts with "[]", COLUMN_VECTOR is enabled and the rest is used
-// as the type parameter.
-// If valueType contains a dot ('.'), COLUMN_INDEX is enabled and valueType is
-// split by the first dot. Then, the former part is used as the type parameter
-// and the latter part is used as the source parameter.
-// Otherwise, COLUMN_SCALAR is enabled and valueType is used as the type
-// parameter.
-//
-// If options is nil, the default parameters are used.
+// FindRow finds a row by key without inserting it.
+// If the key does not exist, FindRow returns NilID and false.
 //
-// See http://groonga.org/docs/reference/commands/column_create.html for details.
-func (db *DB) CreateColumn(tableName, columnName string, valueType string, options *ColumnOptions) (*Column, error) {
-	table, err := db.FindTable(tableName)
+// FindRow accepts the same key types as InsertRow, but a table without _key
+// (TABLE_NO_KEY) is not supported.
+func (table *Table) FindRow(key interface{}) (id uint32, found bool, err error) {
+	if err := table.checkOpen("Table.FindRow()"); err != nil {
+		return NilID, false, err
+	}
+	keyBytes, err := table.encodeKey(key)
 	if err != nil {
-		return nil, err
+		return NilID, false, err
 	}
-	return table.CreateColumn(columnName, valueType, options)
+	var cKey unsafe.Pointer
+	if len(keyBytes) != 0 {
+		cKey = unsafe.Pointer(&keyBytes[0])
+	}
+	var cID C.grn_id
+	rc := C.grngo_find_row(table.c, cKey, C.size_t(len(keyBytes)), &cID)
+	if rc != C.GRN_SUCCESS {
+		return NilID, false, newCError("grngo_find_row()", rc, table.db)
+	}
+	return uint32(cID), cID != C.GRN_ID_NIL, nil
 }
 
-// FindColumn finds a column.
-func (db *DB) FindColumn(tableName, columnName string) (*Column, error) {
-	table, err := db.FindTable(tableName)
+// DeleteRow deletes a row by ID.
+// If the row does not exist, DeleteRow returns false.
+func (table *Table) DeleteRow(id uint32) (deleted bool, err error) {
+	if err := table.checkOpen("Table.DeleteRow()"); err != nil {
+		return false, err
+	}
+	var cDeleted C.grn_bool
+	rc := C.grngo_delete_row(table.c, C.grn_id(id), &cDeleted)
+	if rc != C.GRN_SUCCESS {
+		return false, newCError("grngo_delete_row()", rc, table.db)
+	}
+	return cDeleted == C.GRN_TRUE, nil
+}
+
+// DeleteRowByKey deletes a row by key.
+// If the key does not exist, DeleteRowByKey returns false.
+//
+// DeleteRowByKey accepts the same key types as InsertRow, but a table without
+// _key (TABLE_NO_KEY) is not supported.
+// If _key refers to another table, only the row in this table is deleted.
+func (table *Table) DeleteRowByKey(key interface{}) (deleted bool, err error) {
+	if err := table.checkOpen("Table.DeleteRowByKey()"); err != nil {
+		return false, err
+	}
+	keyBytes, err := table.encodeKey(key)
 	if err != nil {
-		return nil, err
+		return false, err
 	}
-	return table.FindColumn(columnName)
+	var cKey unsafe.Pointer
+	if len(keyBytes) != 0 {
+		cKey = unsafe.Pointer(&keyBytes[0])
+	}
+	var cDeleted C.grn_bool
+	rc := C.grngo_delete_row_by_key(table.c, cKey, C.size_t(len(keyBytes)),
+		&cDeleted)
+	if rc != C.GRN_SUCCESS {
+		return false, newCError("grngo_delete_row_by_key()", rc, table.db)
+	}
+	return cDeleted == C.GRN_TRUE, nil
 }
 
 // SetValue assigns a value.
-func (db *DB) SetValue(tableName, columnName string, id uint32, value interface{}) error {
-	table, err := db.FindTable(tableName)
+func (table *Table) SetValue(columnName string, id uint32, value interface{}) error {
+	if err := table.checkOpen("Table.SetValue()"); err != nil {
+		return err
+	}
+	column, err := table.FindColumn(columnName)
 	if err != nil {
 		return err
 	}
-	return table.SetValue(columnName, id, value)
+	return column.SetValue(id, value)
 }
 
 // GetValue gets a value.
-func (db *DB) GetValue(tableName, columnName string, id uint32) (interface{}, error) {
-	table, err := db.FindTable(tableName)
+func (table *Table) GetValue(columnName string, id uint32) (interface{}, error) {
+	if err := table.checkOpen("Table.GetValue()"); err != nil {
+		return nil, err
+	}
+	column, err := table.FindColumn(columnName)
 	if err != nil {
 		return nil, err
 	}
-	return table.GetValue(columnName, id)
+	return column.GetValue(id)
 }
 
-// -- Table --
+// GetValues gets values of multiple rows in one call.
+func (table *Table) GetValues(columnName string, ids []uint32) (interface{}, error) {
+	if err := table.checkOpen("Table.GetValues()"); err != nil {
+		return nil, err
+	}
+	column, err := table.FindColumn(columnName)
+	if err != nil {
+		return nil, err
+	}
+	return column.GetValues(ids)
+}
 
-// Table is associated with a Groonga table.
-type Table struct {
-	db      *DB                // The owner DB.
-	c       *C.grngo_table     // The associated C object.
-	name    string             // The table name.
-	columns map[string]*Column // A cache to find columns by name.
+// SetValues assigns values to multiple rows in one call.
+// See Column.SetValues for details.
+func (table *Table) SetValues(columnName string, ids []uint32, values interface{}) error {
+	if err := table.checkOpen("Table.SetValues()"); err != nil {
+		return err
+	}
+	column, err := table.FindColumn(columnName)
+	if err != nil {
+		return err
+	}
+	return column.SetValues(ids, values)
 }
 
-// newTable returns a new Table.
-func newTable(db *DB, c *C.grngo_table, name string) *Table {
-	var table Table
-	table.db = db
-	table.c = c
-	table.name = name
-	table.columns = make(map[string]*Column)
-	return &table
+// isRefStruct returns whether a type is a struct that refers to a record,
+// that is a struct other than time.Time, GeoPoint and Weighted.
+func isRefStruct(valueType reflect.Type) bool {
+	return (valueType.Kind() == reflect.Struct) &&
+		(valueType != reflect.TypeOf(time.Time{})) &&
+		(valueType != reflect.TypeOf(GeoPoint{})) &&
+		!isWeightedType(valueType)
 }
 
-// genLoadHead generates the head line of a load command.
-func (table *Table) genLoadHead(options *LoadOptions) (string, error) {
-	line := fmt.Sprintf("load --table %s", table.name)
-	if options.IfExists != "" {
-		value := strings.Replace(options.IfExists, "\\", "\\\\", -1)
-		value = strings.Replace(value, "\"", "\\\"", -1)
-		line += fmt.Sprintf(" --ifexists '%s'", value)
+// keyField returns the field tagged "_key" of a struct type.
+func keyField(valueType reflect.Type) (structField, error) {
+	fields, err := structFields(valueType)
+	if err != nil {
+		return structField{}, err
 	}
-	return line, nil
+	for _, field := range fields {
+		if field.name == "_key" {
+			return field, nil
+		}
+	}
+	return structField{}, fmt.Errorf("%s has no _key field: %w",
+		valueType, ErrInvalidArgument)
+}
+
+// assignValue assigns a value returned by GetValue to dst.
+// A struct that refers to a record gets the key in its "_key" field.
+func assignValue(dst reflect.Value, src interface{}) error {
+	if src == nil {
+		return nil
+	}
+	invalidValue := func() error {
+		return fmt.Errorf("cannot assign %T to %s: %w", src, dst.Type(),
+			ErrInvalidArgument)
+	}
+	srcValue := reflect.ValueOf(src)
+	if srcValue.Type().AssignableTo(dst.Type()) {
+		dst.Set(srcValue)
+		return nil
+	}
+	switch dst.Kind() {
+	case reflect.Ptr:
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return assignValue(dst.Elem(), src)
+	case reflect.Bool:
+		value, ok := src.(bool)
+		if !ok {
+			return invalidValue()
+		}
+		dst.SetBool(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value, ok := rawInt64(src)
+		if !ok || dst.OverflowInt(value) {
+			return invalidValue()
+		}
+		dst.SetInt(value)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value, ok := rawUint64(src)
+		if !ok || dst.OverflowUint(value) {
+			return invalidValue()
+		}
+		dst.SetUint(value)
+	case reflect.Float32, reflect.Float64:
+		value, ok := rawFloat64(src)
+		if !ok || dst.OverflowFloat(value) {
+			return invalidValue()
+		}
+		dst.SetFloat(value)
+	case reflect.String:
+		value, ok := src.([]byte)
+		if !ok {
+			return invalidValue()
+		}
+		dst.SetString(string(value))
+	case reflect.Slice:
+		if srcValue.Kind() != reflect.Slice {
+			return invalidValue()
+		}
+		values := reflect.MakeSlice(dst.Type(), srcValue.Len(), srcValue.Len())
+		for i := 0; i < srcValue.Len(); i++ {
+			if err := assignValue(values.Index(i), srcValue.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		dst.Set(values)
+	case reflect.Struct:
+		if dst.Type() == reflect.TypeOf(time.Time{}) {
+			// A Time value is int64 microseconds unless DB.SetParseTime(true).
+			micros, ok := src.(int64)
+			if !ok {
+				return invalidValue()
+			}
+			dst.Set(reflect.ValueOf(microsToTime(micros)))
+			return nil
+		}
+		if !isRefStruct(dst.Type()) {
+			return invalidValue()
+		}
+		field, err := keyField(dst.Type())
+		if err != nil {
+			return err
+		}
+		return assignValue(dst.Field(field.index), src)
+	default:
+		return invalidValue()
+	}
+	return nil
+}
+
+// Get reads a record into dst, a pointer to a struct.
+//
+// Fields tagged `grngo:"column"` are read by GetValue and converted into the
+// field types. A field tagged "_id" gets id. A struct field other than
+// time.Time and GeoPoint refers to a record, and its tagged fields are read
+// via "column.field". A slice of such structs is read in the same way.
+func (table *Table) Get(id uint32, dst interface{}) error {
+	if err := table.checkOpen("Table.Get()"); err != nil {
+		return err
+	}
+	value := reflect.ValueOf(dst)
+	if (value.Kind() != reflect.Ptr) || value.IsNil() ||
+		(value.Elem().Kind() != reflect.Struct) {
+		return fmt.Errorf("invalid destination: %T: %w", dst, ErrInvalidArgument)
+	}
+	return table.getFields(id, "", value.Elem())
 }
 
-// writeLoadColumns writes columns of a load command.
-func (table *Table) writeLoadColumns(buf *bytes.Buffer, valueType reflect.Type) error {
-	if err := buf.WriteByte('['); err != nil {
+// getFields reads the tagged fields of a struct.
+// prefix is empty or the path to the referred record, e.g. "column.".
+func (table *Table) getFields(id uint32, prefix string, dst reflect.Value) error {
+	fields, err := structFields(dst.Type())
+	if err != nil {
 		return err
 	}
-	needsDelimiter := false
-	for i := 0; i < valueType.NumField(); i++ {
-		field := valueType.Field(i)
-		columnName := field.Tag.Get("grngo")
-		if columnName == "" {
-			continue
-		}
-		if needsDelimiter {
-			if err := buf.WriteByte(','); err != nil {
+	for _, field := range fields {
+		fieldValue := dst.Field(field.index)
+		if (prefix == "") && (field.name == "_id") {
+			if err := assignValue(fieldValue, int64(id)); err != nil {
 				return err
 			}
-		} else {
-			needsDelimiter = true
+			continue
 		}
-		if _, err := fmt.Fprintf(buf, "\"%s\"", columnName); err != nil {
-			return err
+		path := prefix + field.name
+		if err := table.getField(id, path, fieldValue); err != nil {
+			return fmt.Errorf("column = %s: %w", path, err)
 		}
 	}
-	if err := buf.WriteByte(']'); err != nil {
-		return err
-	}
 	return nil
 }
 
-// writeLoadValue writes a value of a load command.
-func (table *Table) writeLoadValue(buf *bytes.Buffer, value *reflect.Value) error {
-	if err := buf.WriteByte('['); err != nil {
-		return err
+// getField reads a field.
+func (table *Table) getField(id uint32, path string, dst reflect.Value) error {
+	valueType := dst.Type()
+	for valueType.Kind() == reflect.Ptr {
+		valueType = valueType.Elem()
 	}
-	valueType := value.Type()
-	needsDelimiter := false
-	for i := 0; i < valueType.NumField(); i++ {
-		field := valueType.Field(i)
-		tag := field.Tag.Get("grngo")
-		if tag == "" {
-			continue
+	var elemType reflect.Type
+	if valueType.Kind() == reflect.Slice {
+		elemType = valueType.Elem()
+		for elemType.Kind() == reflect.Ptr {
+			elemType = elemType.Elem()
 		}
-		if needsDelimiter {
-			if err := buf.WriteByte(','); err != nil {
-				return err
+	}
+	switch {
+	case isRefStruct(valueType):
+		for dst.Kind() == reflect.Ptr {
+			if dst.IsNil() {
+				dst.Set(reflect.New(dst.Type().Elem()))
 			}
-		} else {
-			needsDelimiter = true
+			dst = dst.Elem()
 		}
-		fieldValue := value.Field(i)
-		switch fieldValue.Kind() {
-		case reflect.Bool:
-			if _, err := fmt.Fprint(buf, fieldValue.Bool()); err != nil {
-				return err
+		return table.getFields(id, path+".", dst)
+	case (elemType != nil) && isRefStruct(elemType):
+		for dst.Kind() == reflect.Ptr {
+			if dst.IsNil() {
+				dst.Set(reflect.New(dst.Type().Elem()))
 			}
-		case reflect.Int64:
-			if _, err := fmt.Fprint(buf, fieldValue.Int()); err != nil {
+			dst = dst.Elem()
+		}
+		fields, err := structFields(elemType)
+		if err != nil {
+			return err
+		}
+		// Each field of the referred records is read as a vector.
+		for i, field := range fields {
+			values, err := table.GetValue(path+"."+field.name, id)
+			if err != nil {
 				return err
 			}
-		case reflect.Float64:
-			if _, err := fmt.Fprint(buf, fieldValue.Float()); err != nil {
-				return err
+			vector := reflect.ValueOf(values)
+			if vector.Kind() != reflect.Slice {
+				return fmt.Errorf("not a vector: %s.%s: %w", path, field.name,
+					ErrInvalidArgument)
 			}
-		case reflect.String:
-			str := fieldValue.String()
-			str = strings.Replace(str, "\\", "\\\\", -1)
-			str = strings.Replace(str, "\"", "\\\"", -1)
-			if _, err := fmt.Fprintf(buf, "\"%s\"", str); err != nil {
-				return err
+			if i == 0 {
+				dst.Set(reflect.MakeSlice(valueType, vector.Len(), vector.Len()))
+			} else if vector.Len() != dst.Len() {
+				return fmt.Errorf("length mismatch: %s.%s", path, field.name)
 			}
-		case reflect.Slice:
-			switch field.Type.Elem().Kind() {
-			case reflect.Uint8:
-				str := string(fieldValue.Bytes())
-				str = strings.Replace(str, "\\", "\\\\", -1)
-				str = strings.Replace(str, "\"", "\\\"", -1)
-				if _, err := fmt.Fprintf(buf, "\"%s\"", str); err != nil {
+			for j := 0; j < vector.Len(); j++ {
+				elem := dst.Index(j)
+				for elem.Kind() == reflect.Ptr {
+					if elem.IsNil() {
+						elem.Set(reflect.New(elem.Type().Elem()))
+					}
+					elem = elem.Elem()
+				}
+				if err := assignValue(elem.Field(field.index), vector.Index(j).Interface()); err != nil {
 					return err
 				}
-			default:
-				// TODO: Support other types!
-				return fmt.Errorf("unsupported data kind")
 			}
-		default:
-			// TODO: Support other types!
-			return fmt.Errorf("unsupported data kind")
 		}
+		return nil
+	default:
+		value, err := table.GetValue(path, id)
+		if err != nil {
+			return err
+		}
+		return assignValue(dst, value)
 	}
-	if err := buf.WriteByte(']'); err != nil {
-		return err
-	}
-	return nil
 }
 
-// genLoadBody generates the body line of a load command.
-func (table *Table) genLoadBody(values interface{}) (string, error) {
-	buf := new(bytes.Buffer)
-	if err := buf.WriteByte('['); err != nil {
-		return "", err
+// setterType returns the type accepted by SetValue for a Go type.
+func setterType(valueType reflect.Type) (reflect.Type, error) {
+	for valueType.Kind() == reflect.Ptr {
+		valueType = valueType.Elem()
 	}
-	value := reflect.ValueOf(values)
-	switch value.Kind() {
-	case reflect.Struct:
-	case reflect.Ptr:
-		value := value.Elem()
-		if value.Kind() != reflect.Struct {
-			return "", fmt.Errorf("invalid values")
+	switch valueType {
+	case reflect.TypeOf(time.Time{}), reflect.TypeOf(GeoPoint{}):
+		return valueType, nil
+	}
+	if isWeightedSliceType(valueType) {
+		return valueType, nil
+	}
+	switch valueType.Kind() {
+	case reflect.Bool:
+		return reflect.TypeOf(false), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return reflect.TypeOf(int64(0)), nil
+	case reflect.Uint, reflect.Uint64:
+		return reflect.TypeOf(uint64(0)), nil
+	case reflect.Float32, reflect.Float64:
+		return reflect.TypeOf(float64(0)), nil
+	case reflect.String:
+		return reflect.TypeOf([]byte(nil)), nil
+	case reflect.Slice:
+		if valueType.Elem().Kind() == reflect.Uint8 {
+			return reflect.TypeOf([]byte(nil)), nil
 		}
-		if err := table.writeLoadColumns(buf, value.Type()); err != nil {
-			return "", err
+		elemType, err := setterType(valueType.Elem())
+		if err != nil {
+			return nil, err
 		}
-		if err := table.writeLoadValue(buf, &value); err != nil {
-			return "", err
+		return reflect.SliceOf(elemType), nil
+	case reflect.Struct:
+		if isRefStruct(valueType) {
+			field, err := keyField(valueType)
+			if err != nil {
+				return nil, err
+			}
+			return setterType(valueType.Field(field.index).Type)
 		}
-	case reflect.Slice:
-		if value.Len() == 0 {
-			return "", fmt.Errorf("invalid values")
+	}
+	return nil, fmt.Errorf("unsupported data type: %s: %w", valueType,
+		ErrInvalidArgument)
+}
+
+// setterValue converts a value into a value accepted by SetValue.
+// setterValue returns false for a nil pointer.
+func setterValue(value reflect.Value) (interface{}, bool, error) {
+	for (value.Kind() == reflect.Ptr) || (value.Kind() == reflect.Interface) {
+		if value.IsNil() {
+			return nil, false, nil
 		}
-		valueType := value.Type().Elem()
-		if valueType.Kind() != reflect.Struct {
-			return "", fmt.Errorf("invalid values")
+		value = value.Elem()
+	}
+	valueType, err := setterType(value.Type())
+	if err != nil {
+		return nil, false, err
+	}
+	switch valueType.Kind() {
+	case reflect.Bool:
+		return value.Bool(), true, nil
+	case reflect.Int64:
+		switch value.Kind() {
+		case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+			return int64(value.Uint()), true, nil
 		}
-		if err := table.writeLoadColumns(buf, valueType); err != nil {
-			return "", err
+		return value.Int(), true, nil
+	case reflect.Uint64:
+		return value.Uint(), true, nil
+	case reflect.Float64:
+		return value.Float(), true, nil
+	case reflect.Slice:
+		switch {
+		case value.Kind() == reflect.String:
+			return []byte(value.String()), true, nil
+		case value.Type() == valueType:
+			return value.Interface(), true, nil
+		case value.Type().Elem().Kind() == reflect.Uint8:
+			return value.Bytes(), true, nil
 		}
+		values := reflect.MakeSlice(valueType, value.Len(), value.Len())
 		for i := 0; i < value.Len(); i++ {
-			if err := buf.WriteByte(','); err != nil {
-				return "", err
+			elem, ok, err := setterValue(value.Index(i))
+			if err != nil {
+				return nil, false, err
 			}
-			v := value.Index(i)
-			if err := table.writeLoadValue(buf, &v); err != nil {
-				return "", err
+			if !ok {
+				return nil, false, fmt.Errorf("nil element: %w", ErrInvalidArgument)
 			}
+			values.Index(i).Set(reflect.ValueOf(elem))
 		}
+		return values.Interface(), true, nil
 	}
-	if err := buf.WriteByte(']'); err != nil {
-		return "", err
+	if isRefStruct(value.Type()) {
+		field, err := keyField(value.Type())
+		if err != nil {
+			return nil, false, err
+		}
+		return setterValue(value.Field(field.index))
 	}
-	return buf.String(), nil
+	return value.Interface(), true, nil
 }
 
-// (Experimental) Load loads values.
+// Put inserts a record if it does not exist and writes src, a struct or a
+// pointer to a struct, into it.
 //
-// Implicit conversion for Time is not supported.
-// GeoPoint is not supported.
-// Vector types are not supported.
-func (table *Table) Load(values interface{}, options *LoadOptions) ([]byte, error) {
-	if options == nil {
-		options = NewLoadOptions()
+// Fields tagged `grngo:"column"` except "_key" and "_id" are converted into
+// the types accepted by SetValue and written. A nil pointer field is skipped.
+// A struct field other than time.Time and GeoPoint is written as the value
+// of its "_key" field.
+func (table *Table) Put(key interface{}, src interface{}) (inserted bool, id uint32, err error) {
+	if err := table.checkOpen("Table.Put()"); err != nil {
+		return false, NilID, err
 	}
-	headLine, err := table.genLoadHead(options)
-	if err != nil {
-		return nil, err
+	value := reflect.ValueOf(src)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return false, NilID, fmt.Errorf("invalid source: %T: %w", src, ErrInvalidArgument)
+		}
+		value = value.Elem()
 	}
-	bodyLine, err := table.genLoadBody(values)
-	if err != nil {
-		return nil, err
+	if value.Kind() != reflect.Struct {
+		return false, NilID, fmt.Errorf("invalid source: %T: %w", src, ErrInvalidArgument)
 	}
-	lines := []string{ headLine, bodyLine }
-	for _, line := range lines {
-		if err := table.db.Send(line); err != nil {
-			result, _ := table.db.Recv()
-			return result, err
-		}
+	fields, err := structFields(value.Type())
+	if err != nil {
+		return false, NilID, err
 	}
-	return table.db.Recv()
-}
-
-// InsertRow finds or inserts a row.
-func (table *Table) InsertRow(key interface{}) (inserted bool, id uint32, err error) {
-	var rc C.grn_rc
-	var cInserted C.grn_bool
-	var cID C.grn_id
-	switch key := key.(type) {
-	case nil:
-		rc = C.grngo_insert_void(table.c, &cInserted, &cID)
-	case bool:
-		cKey := C.grn_bool(C.GRN_FALSE)
-		if key {
-			cKey = C.grn_bool(C.GRN_TRUE)
-		}
-		rc = C.grngo_insert_bool(table.c, cKey, &cInserted, &cID)
-	case int64:
-		cKey := C.int64_t(key)
-		rc = C.grngo_insert_int(table.c, cKey, &cInserted, &cID)
-	case float64:
-		cKey := C.double(key)
-		rc = C.grngo_insert_float(table.c, cKey, &cInserted, &cID)
-	case []byte:
-		var cKey C.grngo_text
-		if len(key) != 0 {
-			cKey.ptr = (*C.char)(unsafe.Pointer(&key[0]))
-			cKey.size = C.size_t(len(key))
+	if key != nil {
+		if key, _, err = setterValue(reflect.ValueOf(key)); err != nil {
+			return false, NilID, err
 		}
-		rc = C.grngo_insert_text(table.c, cKey, &cInserted, &cID)
-	case GeoPoint:
-		cKey := C.grn_geo_point{C.int(key.Latitude), C.int(key.Longitude)}
-		rc = C.grngo_insert_geo_point(table.c, cKey, &cInserted, &cID)
-	default:
-		return false, NilID, fmt.Errorf(
-			"unsupported key type: typeName = <%s>", reflect.TypeOf(key).Name())
-	}
-	if rc != C.GRN_SUCCESS {
-		return false, NilID, newCError("grngo_insert_*()", rc, table.db)
 	}
-	return cInserted == C.GRN_TRUE, uint32(cID), nil
-}
-
-// SetValue assigns a value.
-func (table *Table) SetValue(columnName string, id uint32, value interface{}) error {
-	column, err := table.FindColumn(columnName)
+	inserted, id, err = table.InsertRow(key)
 	if err != nil {
-		return err
+		return false, NilID, err
 	}
-	return column.SetValue(id, value)
-}
-
-// GetValue gets a value.
-func (table *Table) GetValue(columnName string, id uint32) (interface{}, error) {
-	column, err := table.FindColumn(columnName)
-	if err != nil {
-		return nil, err
+	for _, field := range fields {
+		if (field.name == "_key") || (field.name == "_id") {
+			continue
+		}
+		fieldValue, ok, err := setterValue(value.Field(field.index))
+		if err == nil && ok {
+			err = table.SetValue(field.name, id, fieldValue)
+		}
+		if err != nil {
+			return inserted, id, fmt.Errorf("column = %s: %w", field.name, err)
+		}
 	}
-	return column.GetValue(id)
+	return inserted, id, nil
 }
 
 // createColumnOptionsMap creates an options map for column_create.
@@ -1079,43 +4889,311 @@ func (table *Table) createColumnOptionsMap(name string, valueType string, option
 //
 // If options is nil, the default parameters are used.
 //
-// See http://groonga.org/docs/reference/commands/column_create.html for details.
-func (table *Table) CreateColumn(name string, valueType string, options *ColumnOptions) (*Column, error) {
+// See http://groonga.org/docs/reference/commands/column_create.html for details.
+func (table *Table) CreateColumn(name string, valueType string, options *ColumnOptions) (*Column, error) {
+	if err := table.checkOpen("Table.CreateColumn()"); err != nil {
+		return nil, err
+	}
+	if options == nil {
+		options = NewColumnOptions()
+	}
+	optionsMap, err := table.createColumnOptionsMap(name, valueType, options)
+	if err != nil {
+		return nil, err
+	}
+	bytes, err := table.db.queryJSON("column_create", optionsMap)
+	if err != nil {
+		return nil, err
+	}
+	if string(bytes) != "true" {
+		return nil, fmt.Errorf("column_create failed: name = <%s>", name)
+	}
+	return table.FindColumn(name)
+}
+
+// FindColumn finds a column.
+func (table *Table) FindColumn(name string) (*Column, error) {
+	if err := table.checkOpen("Table.FindColumn()"); err != nil {
+		return nil, err
+	}
+	if column, ok := table.columns[name]; ok {
+		return column, nil
+	}
+	c, err := table.openColumn(name)
+	if err != nil {
+		return nil, err
+	}
+	column := newColumn(table, c, name)
+	table.columns[name] = column
+	return column, nil
+}
+
+// openColumn opens a column.
+func (table *Table) openColumn(name string) (*C.grngo_column, error) {
+	nameBytes := []byte(name)
+	var cName *C.char
+	if len(nameBytes) != 0 {
+		cName = (*C.char)(unsafe.Pointer(&nameBytes[0]))
+	}
+	var c *C.grngo_column
+	rc := C.grngo_open_column(table.c, cName, C.size_t(len(nameBytes)), &c)
+	if rc != C.GRN_SUCCESS {
+		return nil, newCError("grngo_open_column()", rc, table.db)
+	}
+	return c, nil
+}
+
+// RemoveColumn removes a column.
+//
+// Cached Column handles that refer to the column are closed. They are closed
+// before the removal because the removal frees the column, and opened again if
+// the removal fails.
+func (table *Table) RemoveColumn(name string) error {
+	if err := table.checkOpen("Table.RemoveColumn()"); err != nil {
+		return err
+	}
+	id, err := table.db.objectID(table.name + "." + name)
+	if err != nil {
+		return err
+	}
+	tables, columns := table.db.invalidate(id)
+	nameBytes := []byte(name)
+	rc := C.grngo_remove_column(table.c, (*C.char)(unsafe.Pointer(&nameBytes[0])),
+		C.size_t(len(nameBytes)))
+	if rc != C.GRN_SUCCESS {
+		err := newCError("grngo_remove_column()", rc, table.db)
+		table.db.reopen(tables, columns)
+		return err
+	}
+	return nil
+}
+
+// RenameColumn renames a column.
+//
+// Cached Column handles that refer to the column are closed on success.
+func (table *Table) RenameColumn(name, newName string) error {
+	if err := table.checkOpen("Table.RenameColumn()"); err != nil {
+		return err
+	}
+	if newName == "" {
+		return fmt.Errorf("invalid name: newName = <%s>: %w", newName, ErrInvalidArgument)
+	}
+	id, err := table.db.objectID(table.name + "." + name)
+	if err != nil {
+		return err
+	}
+	nameBytes := []byte(name)
+	newNameBytes := []byte(newName)
+	rc := C.grngo_rename_column(table.c, (*C.char)(unsafe.Pointer(&nameBytes[0])),
+		C.size_t(len(nameBytes)), (*C.char)(unsafe.Pointer(&newNameBytes[0])),
+		C.size_t(len(newNameBytes)))
+	if rc != C.GRN_SUCCESS {
+		return newCError("grngo_rename_column()", rc, table.db)
+	}
+	table.db.invalidate(id)
+	return nil
+}
+
+// Columns returns descriptions of all the columns in the table in
+// alphabetical order.
+// Pseudo columns, such as _id and _key, are not included.
+func (table *Table) Columns() ([]*ColumnInfo, error) {
+	if err := table.checkOpen("Table.Columns()"); err != nil {
+		return nil, err
+	}
+	schema, err := table.db.querySchema()
+	if err != nil {
+		return nil, err
+	}
+	schemaTable, ok := schema.Tables[table.name]
+	if !ok {
+		return nil, fmt.Errorf("table not found: name = <%s>: %w",
+			table.name, ErrInvalidArgument)
+	}
+	names := make([]string, 0, len(schemaTable.Columns))
+	for name := range schemaTable.Columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	infos := make([]*ColumnInfo, len(names))
+	for i, name := range names {
+		info, err := schemaTable.Columns[name].columnInfo()
+		if err != nil {
+			return nil, err
+		}
+		infos[i] = info
+	}
+	return infos, nil
+}
+
+// Cursor opens a cursor to iterate over records in the table.
+// The cursor must be closed with Cursor.Close.
+//
+// If options is nil, the default parameters are used.
+//
+// See http://groonga.org/docs/reference/api/grn_table_cursor.html for details.
+func (table *Table) Cursor(options *CursorOptions) (*Cursor, error) {
+	if err := table.checkOpen("Table.Cursor()"); err != nil {
+		return nil, err
+	}
+	if options == nil {
+		options = NewCursorOptions()
+	}
+	var bounds [2][]byte
+	for i, bound := range []interface{}{options.Min, options.Max} {
+		if bound == nil {
+			continue
+		}
+		if (i == 1) && ((options.Flags & CursorPrefix) == CursorPrefix) {
+			continue
+		}
+		key, err := table.encodeKey(bound)
+		if err != nil {
+			return nil, err
+		}
+		if key == nil {
+			key = []byte{}
+		}
+		bounds[i] = key
+	}
+	var cBounds [2]unsafe.Pointer
+	for i, bound := range bounds {
+		if bound == nil {
+			continue
+		}
+		if len(bound) == 0 {
+			// A zero-size bound must not be NULL.
+			bound = make([]byte, 1)
+		}
+		cBounds[i] = unsafe.Pointer(&bound[0])
+	}
+	var c *C.grngo_cursor
+	rc := C.grngo_open_cursor(table.c,
+		cBounds[0], C.size_t(len(bounds[0])), cBounds[1], C.size_t(len(bounds[1])),
+		C.int(options.Offset), C.int(options.Limit), C.int(options.Flags), &c)
+	if rc != C.GRN_SUCCESS {
+		return nil, newCError("grngo_open_cursor()", rc, table.db)
+	}
+	cursor := newCursor(table, c)
+	table.cursors[cursor] = true
+	return cursor, nil
+}
+
+// Search searches the table with an index column and returns the hits in
+// descending score order. Hits with the same score are sorted by ID.
+// The hits are sorted by Groonga and only the hits in the window specified by
+// Offset and Limit are returned.
+//
+// indexName must be the full name of an index column whose source is the
+// table, e.g. "Terms.Docs_title".
+func (table *Table) Search(indexName, query string, options *SearchOptions) ([]SearchHit, error) {
+	if err := table.checkOpen("Table.Search()"); err != nil {
+		return nil, err
+	}
 	if options == nil {
-		options = NewColumnOptions()
+		options = NewSearchOptions()
 	}
-	optionsMap, err := table.createColumnOptionsMap(name, valueType, options)
-	if err != nil {
-		return nil, err
+	if indexName == "" {
+		return nil, fmt.Errorf("indexName is empty: %w", ErrInvalidArgument)
 	}
-	bytes, err := table.db.QueryEx("column_create", optionsMap)
-	if err != nil {
-		return nil, err
+	nameBytes := []byte(indexName)
+	cName := (*C.char)(unsafe.Pointer(&nameBytes[0]))
+	queryBytes := []byte(query)
+	var cQuery *C.char
+	if len(queryBytes) != 0 {
+		cQuery = (*C.char)(unsafe.Pointer(&queryBytes[0]))
 	}
-	if string(bytes) != "true" {
-		return nil, fmt.Errorf("column_create failed: name = <%s>", name)
+	offset := options.Offset
+	if offset < 0 {
+		offset = 0
 	}
-	return table.FindColumn(name)
+	limit := options.Limit
+	if limit < 0 {
+		limit = -1
+	}
+	var cHits *C.grngo_hit
+	var cNumHits C.size_t
+	rc := C.grngo_search(table.c, cName, C.size_t(len(nameBytes)),
+		cQuery, C.size_t(len(queryBytes)), C.int(options.Mode),
+		C.int(options.SimilarityThreshold), C.int(options.MaxInterval),
+		C.int(offset), C.int(limit), &cHits, &cNumHits)
+	if rc != C.GRN_SUCCESS {
+		return nil, newCError("grngo_search()", rc, table.db)
+	}
+	defer C.grngo_free_hits(table.c, cHits)
+	header := reflect.SliceHeader{
+		Data: uintptr(unsafe.Pointer(cHits)),
+		Len:  int(cNumHits),
+		Cap:  int(cNumHits),
+	}
+	cValue := *(*[]C.grngo_hit)(unsafe.Pointer(&header))
+	hits := make([]SearchHit, len(cValue))
+	for i := range cValue {
+		hits[i].ID = uint32(cValue[i].id)
+		hits[i].Score = float64(cValue[i].score)
+	}
+	return hits, nil
 }
 
-// FindColumn finds a column.
-func (table *Table) FindColumn(name string) (*Column, error) {
-	if column, ok := table.columns[name]; ok {
-		return column, nil
-	}
-	nameBytes := []byte(name)
-	var cName *C.char
-	if len(nameBytes) != 0 {
-		cName = (*C.char)(unsafe.Pointer(&nameBytes[0]))
+// -- Cursor --
+
+// Cursor is associated with a Groonga table cursor.
+type Cursor struct {
+	table *Table          // The owner table.
+	c     *C.grngo_cursor // The associated C object.
+	id    uint32          // The current record ID.
+}
+
+// newCursor returns a new Cursor.
+func newCursor(table *Table, c *C.grngo_cursor) *Cursor {
+	var cursor Cursor
+	cursor.table = table
+	cursor.c = c
+	cursor.id = NilID
+	return &cursor
+}
+
+// Close closes a cursor.
+func (cursor *Cursor) Close() error {
+	C.grngo_close_cursor(cursor.c)
+	cursor.c = nil
+	delete(cursor.table.cursors, cursor)
+	return nil
+}
+
+// Next moves the cursor to the next record and returns its ID.
+// If there are no more records, Next returns NilID.
+func (cursor *Cursor) Next() (uint32, error) {
+	if cursor.c == nil {
+		return NilID, fmt.Errorf("Cursor.Next() failed: cursor is closed: %w",
+			ErrClosed)
 	}
-	var c *C.grngo_column
-	rc := C.grngo_open_column(table.c, cName, C.size_t(len(nameBytes)), &c)
+	var cID C.grn_id
+	rc := C.grngo_cursor_next(cursor.c, &cID)
 	if rc != C.GRN_SUCCESS {
-		return nil, newCError("grngo_open_column()", rc, table.db)
+		return NilID, newCError("grngo_cursor_next()", rc, cursor.table.db)
 	}
-	column := newColumn(table, c, name)
-	table.columns[name] = column
-	return column, nil
+	cursor.id = uint32(cID)
+	return cursor.id, nil
+}
+
+// ID returns the ID of the current record.
+func (cursor *Cursor) ID() uint32 {
+	return cursor.id
+}
+
+// Key returns the key of the current record.
+// The type of the key is the same as Column.GetValue for _key.
+func (cursor *Cursor) Key() (interface{}, error) {
+	if cursor.c == nil {
+		return nil, fmt.Errorf("Cursor.Key() failed: cursor is closed: %w",
+			ErrClosed)
+	}
+	if cursor.id == NilID {
+		return nil, fmt.Errorf("Cursor.Key() failed: no current record: %w",
+			ErrInvalidArgument)
+	}
+	return cursor.table.GetValue("_key", cursor.id)
 }
 
 // -- Column --
@@ -1136,8 +5214,180 @@ func newColumn(table *Table, c *C.grngo_column, name string) *Column {
 	return &column
 }
 
+// close closes the column.
+func (column *Column) close() {
+	C.grngo_close_column(column.c)
+	column.c = nil
+}
+
+// checkOpen returns an error if the column is closed.
+func (column *Column) checkOpen(op string) error {
+	if column.c == nil {
+		return fmt.Errorf("%s failed: column is closed: %w", op, ErrClosed)
+	}
+	return nil
+}
+
+// Close closes a column.
+// Close does nothing if the column is already closed.
+//
+// Note that Table.FindColumn returns the same Column for the same name, so
+// Close affects all of its users. Table.FindColumn opens the column again.
+func (column *Column) Close() error {
+	if column.c == nil {
+		return nil
+	}
+	if column.table.columns[column.name] == column {
+		delete(column.table.columns, column.name)
+	}
+	column.close()
+	return nil
+}
+
+// isRefVector returns whether the column is a writable reference vector.
+func (column *Column) isRefVector() bool {
+	return (column.c.writable == C.GRN_TRUE) && (column.c.n_srcs > 1) &&
+		(column.c.dimension == 1)
+}
+
+// newCRefKeys encodes keys of the referred table into an array of
+// grngo_text. The encoded keys are pinned with pinner.
+func (column *Column) newCRefKeys(value interface{}, pinner *runtime.Pinner) ([]C.grngo_text, error) {
+	keys := reflect.ValueOf(value)
+	if keys.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("unsupported value type: type = <%T>: %w",
+			value, ErrInvalidArgument)
+	}
+	cKeys := make([]C.grngo_text, keys.Len())
+	for i := range cKeys {
+		key, err := encodeKey(column.c.value_type, keys.Index(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+		cKeys[i] = newCText(key)
+		pin(pinner, unsafe.Pointer(cKeys[i].ptr))
+	}
+	return cKeys, nil
+}
+
+// setRefVector assigns a reference vector.
+// value must be []uint32 of IDs or a slice of keys of the referred table,
+// or a slice of Weighted of them for a weight vector.
+// Rows which do not exist in the referred table are inserted if value is a
+// slice of keys.
+func (column *Column) setRefVector(id uint32, value interface{}) error {
+	var rc C.grn_rc
+	cID := C.grn_id(id)
+	switch ids := value.(type) {
+	case []uint32:
+		rc = C.grngo_set_ref_id_vector(column.c, cID, newCVector(ids))
+	case []Weighted[uint32]:
+		rc = C.grngo_set_weighted_ref_id_vector(column.c, cID, newCVector(ids))
+	default:
+		var pinner runtime.Pinner
+		defer pinner.Unpin()
+		if isWeightedSliceType(reflect.TypeOf(value)) {
+			cKeys, err := newCWeightedKeys(column.c.value_type, value, &pinner)
+			if err != nil {
+				return err
+			}
+			rc = C.grngo_set_weighted_ref_vector(column.c, cID, newCVector(cKeys))
+			break
+		}
+		cKeys, err := column.newCRefKeys(value, &pinner)
+		if err != nil {
+			return err
+		}
+		rc = C.grngo_set_ref_vector(column.c, cID, newCVector(cKeys))
+	}
+	if rc != C.GRN_SUCCESS {
+		return newCError("grngo_set_ref_*vector()", rc, column.table.db)
+	}
+	return nil
+}
+
+// setRefVectors assigns reference vectors to multiple rows in one call.
+// values must be [][]uint32 of IDs or a slice of slices of keys, or slices
+// of Weighted of them for a weight vector.
+func (column *Column) setRefVectors(ids []uint32, values interface{}) error {
+	var pinner runtime.Pinner
+	defer pinner.Unpin()
+	var rc C.grn_rc
+	var nDone C.size_t
+	cIDs := (*C.grn_id)(unsafe.Pointer(&ids[0]))
+	nIDs := C.size_t(len(ids))
+	cValues := make([]C.grngo_vector, len(ids))
+	switch idVectors := values.(type) {
+	case [][]uint32:
+		for i := range idVectors {
+			cValues[i] = newCVector(idVectors[i])
+			pin(&pinner, cValues[i].ptr)
+		}
+		rc = C.grngo_set_ref_id_vector_values(column.c, cIDs, nIDs,
+			&cValues[0], &nDone)
+	case [][]Weighted[uint32]:
+		for i := range idVectors {
+			cValues[i] = newCVector(idVectors[i])
+			pin(&pinner, cValues[i].ptr)
+		}
+		rc = C.grngo_set_weighted_ref_id_vector_values(column.c, cIDs, nIDs,
+			&cValues[0], &nDone)
+	default:
+		keyVectors := reflect.ValueOf(values)
+		if isWeightedSliceType(keyVectors.Type().Elem()) {
+			for i := range cValues {
+				cKeys, err := newCWeightedKeys(column.c.value_type,
+					keyVectors.Index(i).Interface(), &pinner)
+				if err != nil {
+					return &RowError{Index: i, ID: ids[i], Err: err}
+				}
+				cValues[i] = newCVector(cKeys)
+				pin(&pinner, cValues[i].ptr)
+			}
+			rc = C.grngo_set_weighted_ref_vector_values(column.c, cIDs, nIDs,
+				&cValues[0], &nDone)
+			break
+		}
+		for i := range cValues {
+			cKeys, err := column.newCRefKeys(keyVectors.Index(i).Interface(), &pinner)
+			if err != nil {
+				return &RowError{Index: i, ID: ids[i], Err: err}
+			}
+			cValues[i] = newCVector(cKeys)
+			pin(&pinner, cValues[i].ptr)
+		}
+		rc = C.grngo_set_ref_vector_values(column.c, cIDs, nIDs,
+			&cValues[0], &nDone)
+	}
+	if rc != C.GRN_SUCCESS {
+		err := newCError("grngo_set_ref_*vector_values()", rc, column.table.db)
+		if int(nDone) >= len(ids) {
+			return err
+		}
+		return &RowError{Index: int(nDone), ID: ids[nDone], Err: err}
+	}
+	return nil
+}
+
 // SetValue assigns a value.
+//
+// A weight vector column, a vector column created with WithWeight, accepts
+// a slice of Weighted, e.g. []WeightedText for []ShortText and
+// []Weighted[int64] for []Int32, and GetValue returns it. Bool, integers up
+// to 32 bits, texts and references are supported.
+//
+// A reference vector column accepts keys of the referred table, e.g.
+// [][]byte for []Table whose key type is ShortText, or IDs as []uint32.
+// A reference weight vector column accepts []Weighted[uint32] of IDs or a
+// slice of Weighted of keys, e.g. []WeightedText.
+// Missing keys are inserted into the referred table.
 func (column *Column) SetValue(id uint32, value interface{}) error {
+	if err := column.checkOpen("Column.SetValue()"); err != nil {
+		return err
+	}
+	if column.isRefVector() {
+		return column.setRefVector(id, value)
+	}
 	var rc C.grn_rc
 	cID := C.grn_id(id)
 	switch value := value.(type) {
@@ -1150,6 +5400,9 @@ func (column *Column) SetValue(id uint32, value interface{}) error {
 	case int64:
 		cValue := C.int64_t(value)
 		rc = C.grngo_set_int(column.c, cID, cValue)
+	case uint64:
+		cValue := C.uint64_t(value)
+		rc = C.grngo_set_uint(column.c, cID, cValue)
 	case float64:
 		cValue := C.double(value)
 		rc = C.grngo_set_float(column.c, cID, cValue)
@@ -1160,6 +5413,13 @@ func (column *Column) SetValue(id uint32, value interface{}) error {
 			cValue.size = C.size_t(len(value))
 		}
 		rc = C.grngo_set_text(column.c, cID, cValue)
+	case time.Time:
+		if column.c.value_type != C.GRN_DB_TIME {
+			return fmt.Errorf("unsupported value type: valueType = %s, type = <%T>: %w",
+				DataType(column.c.value_type), value, ErrInvalidArgument)
+		}
+		cValue := C.int64_t(timeToMicros(value))
+		rc = C.grngo_set_int(column.c, cID, cValue)
 	case GeoPoint:
 		cValue := C.grn_geo_point{C.int(value.Latitude), C.int(value.Longitude)}
 		rc = C.grngo_set_geo_point(column.c, cID, cValue)
@@ -1183,6 +5443,8 @@ func (column *Column) SetValue(id uint32, value interface{}) error {
 			cValue.size = C.size_t(len(value))
 		}
 		rc = C.grngo_set_int_vector(column.c, cID, cValue)
+	case []uint64:
+		rc = C.grngo_set_uint_vector(column.c, cID, newCVector(value))
 	case []float64:
 		var cValue C.grngo_vector
 		if len(value) != 0 {
@@ -1190,12 +5452,22 @@ func (column *Column) SetValue(id uint32, value interface{}) error {
 			cValue.size = C.size_t(len(value))
 		}
 		rc = C.grngo_set_float_vector(column.c, cID, cValue)
+	case []time.Time:
+		if column.c.value_type != C.GRN_DB_TIME {
+			return fmt.Errorf("unsupported value type: valueType = %s, type = <%T>: %w",
+				DataType(column.c.value_type), value, ErrInvalidArgument)
+		}
+		cValue := newCVector(timesToMicros(value))
+		rc = C.grngo_set_int_vector(column.c, cID, cValue)
 	case [][]byte:
+		var pinner runtime.Pinner
+		defer pinner.Unpin()
 		vector := make([]C.grngo_text, len(value))
 		for i := 0; i < len(value); i++ {
 			if len(value[i]) != 0 {
 				vector[i].ptr = (*C.char)(unsafe.Pointer(&value[i][0]))
 				vector[i].size = C.size_t(len(value[i]))
+				pinner.Pin(vector[i].ptr)
 			}
 		}
 		var cValue C.grngo_vector
@@ -1211,9 +5483,23 @@ func (column *Column) SetValue(id uint32, value interface{}) error {
 			cValue.size = C.size_t(len(value))
 		}
 		rc = C.grngo_set_geo_point_vector(column.c, cID, cValue)
+	case []WeightedText:
+		var pinner runtime.Pinner
+		defer pinner.Unpin()
+		cValue := newCVector(newCWeightedTexts(value, &pinner))
+		rc = C.grngo_set_weighted_vector(column.c, cID, cValue)
 	default:
-		return fmt.Errorf("unsupported value type: name = <%s>",
-			reflect.TypeOf(value).Name())
+		if !isWeightedSliceType(reflect.TypeOf(value)) {
+			return fmt.Errorf("unsupported value type: name = <%s>: %w",
+				reflect.TypeOf(value).Name(), ErrInvalidArgument)
+		}
+		var pinner runtime.Pinner
+		defer pinner.Unpin()
+		cValue, err := newCWeightedKeys(column.c.value_type, value, &pinner)
+		if err != nil {
+			return err
+		}
+		rc = C.grngo_set_weighted_vector(column.c, cID, newCVector(cValue))
 	}
 	if rc != C.GRN_SUCCESS {
 		return newCError("grngo_set_*()", rc, column.table.db)
@@ -1221,6 +5507,260 @@ func (column *Column) SetValue(id uint32, value interface{}) error {
 	return nil
 }
 
+// newCText returns a grngo_text which refers to value.
+func newCText(value []byte) C.grngo_text {
+	var cValue C.grngo_text
+	if len(value) != 0 {
+		cValue.ptr = (*C.char)(unsafe.Pointer(&value[0]))
+		cValue.size = C.size_t(len(value))
+	}
+	return cValue
+}
+
+// newCWeightedTexts converts value into an array of grngo_weighted_text.
+// The texts are pinned with pinner.
+func newCWeightedTexts(value []WeightedText, pinner *runtime.Pinner) []C.grngo_weighted_text {
+	cValue := make([]C.grngo_weighted_text, len(value))
+	for i := range value {
+		text := newCText(value[i].Value)
+		pin(pinner, unsafe.Pointer(text.ptr))
+		cValue[i].ptr = text.ptr
+		cValue[i].size = text.size
+		cValue[i].weight = C.uint(value[i].Weight)
+	}
+	return cValue
+}
+
+// newCWeightedKeys encodes values of value, a slice of Weighted, as keys of
+// keyType into an array of grngo_weighted_text. The encoded values are
+// pinned with pinner.
+func newCWeightedKeys(keyType C.grn_builtin_type, value interface{}, pinner *runtime.Pinner) ([]C.grngo_weighted_text, error) {
+	elems := reflect.ValueOf(value)
+	if !isWeightedSliceType(elems.Type()) {
+		return nil, fmt.Errorf("unsupported value type: type = <%T>: %w",
+			value, ErrInvalidArgument)
+	}
+	cValue := make([]C.grngo_weighted_text, elems.Len())
+	for i := range cValue {
+		key, err := encodeKey(keyType, elems.Index(i).Field(0).Interface())
+		if err != nil {
+			return nil, err
+		}
+		text := newCText(key)
+		pin(pinner, unsafe.Pointer(text.ptr))
+		cValue[i].ptr = text.ptr
+		cValue[i].size = text.size
+		cValue[i].weight = C.uint(elems.Index(i).Field(1).Uint())
+	}
+	return cValue, nil
+}
+
+// newCBools converts value into an array of grn_bool.
+func newCBools(value []bool) []C.grn_bool {
+	cValue := make([]C.grn_bool, len(value))
+	for i := range value {
+		if value[i] {
+			cValue[i] = C.grn_bool(C.GRN_TRUE)
+		}
+	}
+	return cValue
+}
+
+// timesToMicros converts []time.Time into []int64 microseconds.
+func timesToMicros(value []time.Time) []int64 {
+	micros := make([]int64, len(value))
+	for i := range value {
+		micros[i] = timeToMicros(value[i])
+	}
+	return micros
+}
+
+// pin pins ptr unless it is nil.
+// Go memory passed to C must not contain pointers to unpinned Go memory.
+func pin(pinner *runtime.Pinner, ptr unsafe.Pointer) {
+	if ptr != nil {
+		pinner.Pin(ptr)
+	}
+}
+
+// newCVector returns a grngo_vector which refers to a slice.
+// slice must be a slice whose elements have the C representation.
+func newCVector(slice interface{}) C.grngo_vector {
+	var cValue C.grngo_vector
+	value := reflect.ValueOf(slice)
+	if value.Len() != 0 {
+		cValue.ptr = unsafe.Pointer(value.Pointer())
+		cValue.size = C.size_t(value.Len())
+	}
+	return cValue
+}
+
+// SetValues assigns values to multiple rows in one call.
+//
+// values must be a slice whose elements are acceptable for SetValue, e.g.
+// []int64 for an Int32 column and [][]byte for a []ShortText column.
+// Each value is validated as SetValue does, e.g. 1000 is rejected by an Int8
+// column.
+//
+// SetValues is not atomic. If a row fails, the rows before it keep their new
+// values and SetValues returns a *RowError which tells the failed row.
+func (column *Column) SetValues(ids []uint32, values interface{}) error {
+	if err := column.checkOpen("Column.SetValues()"); err != nil {
+		return err
+	}
+	value := reflect.ValueOf(values)
+	if value.Kind() != reflect.Slice {
+		return fmt.Errorf("unsupported values type: type = <%T>: %w",
+			values, ErrInvalidArgument)
+	}
+	if value.Len() != len(ids) {
+		return fmt.Errorf("length mismatch: len(ids) = %d, len(values) = %d: %w",
+			len(ids), value.Len(), ErrInvalidArgument)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	if column.isRefVector() {
+		return column.setRefVectors(ids, values)
+	}
+	// Arrays of grngo_text and grngo_vector refer to pinned Go memory.
+	var pinner runtime.Pinner
+	defer pinner.Unpin()
+	var rc C.grn_rc
+	var nDone C.size_t
+	cIDs := (*C.grn_id)(unsafe.Pointer(&ids[0]))
+	nIDs := C.size_t(len(ids))
+	switch values := values.(type) {
+	case []bool:
+		cValues := newCBools(values)
+		rc = C.grngo_set_bool_values(column.c, cIDs, nIDs, &cValues[0], &nDone)
+	case []int64:
+		cValues := (*C.int64_t)(unsafe.Pointer(&values[0]))
+		rc = C.grngo_set_int_values(column.c, cIDs, nIDs, cValues, &nDone)
+	case []uint64:
+		cValues := (*C.uint64_t)(unsafe.Pointer(&values[0]))
+		rc = C.grngo_set_uint_values(column.c, cIDs, nIDs, cValues, &nDone)
+	case []float64:
+		cValues := (*C.double)(unsafe.Pointer(&values[0]))
+		rc = C.grngo_set_float_values(column.c, cIDs, nIDs, cValues, &nDone)
+	case []time.Time:
+		if column.c.value_type != C.GRN_DB_TIME {
+			return fmt.Errorf("unsupported value type: valueType = %s, type = <%T>: %w",
+				DataType(column.c.value_type), values, ErrInvalidArgument)
+		}
+		cValues := (*C.int64_t)(unsafe.Pointer(&timesToMicros(values)[0]))
+		rc = C.grngo_set_int_values(column.c, cIDs, nIDs, cValues, &nDone)
+	case [][]byte:
+		cValues := make([]C.grngo_text, len(values))
+		for i := range values {
+			cValues[i] = newCText(values[i])
+			pin(&pinner, unsafe.Pointer(cValues[i].ptr))
+		}
+		rc = C.grngo_set_text_values(column.c, cIDs, nIDs, &cValues[0], &nDone)
+	case []GeoPoint:
+		cValues := (*C.grn_geo_point)(unsafe.Pointer(&values[0]))
+		rc = C.grngo_set_geo_point_values(column.c, cIDs, nIDs, cValues, &nDone)
+	case [][]bool:
+		cValues := make([]C.grngo_vector, len(values))
+		for i := range values {
+			cValues[i] = newCVector(newCBools(values[i]))
+			pin(&pinner, cValues[i].ptr)
+		}
+		rc = C.grngo_set_bool_vector_values(column.c, cIDs, nIDs,
+			&cValues[0], &nDone)
+	case [][]int64:
+		cValues := make([]C.grngo_vector, len(values))
+		for i := range values {
+			cValues[i] = newCVector(values[i])
+			pin(&pinner, cValues[i].ptr)
+		}
+		rc = C.grngo_set_int_vector_values(column.c, cIDs, nIDs,
+			&cValues[0], &nDone)
+	case [][]uint64:
+		cValues := make([]C.grngo_vector, len(values))
+		for i := range values {
+			cValues[i] = newCVector(values[i])
+			pin(&pinner, cValues[i].ptr)
+		}
+		rc = C.grngo_set_uint_vector_values(column.c, cIDs, nIDs,
+			&cValues[0], &nDone)
+	case [][]float64:
+		cValues := make([]C.grngo_vector, len(values))
+		for i := range values {
+			cValues[i] = newCVector(values[i])
+			pin(&pinner, cValues[i].ptr)
+		}
+		rc = C.grngo_set_float_vector_values(column.c, cIDs, nIDs,
+			&cValues[0], &nDone)
+	case [][]time.Time:
+		if column.c.value_type != C.GRN_DB_TIME {
+			return fmt.Errorf("unsupported value type: valueType = %s, type = <%T>: %w",
+				DataType(column.c.value_type), values, ErrInvalidArgument)
+		}
+		cValues := make([]C.grngo_vector, len(values))
+		for i := range values {
+			cValues[i] = newCVector(timesToMicros(values[i]))
+			pin(&pinner, cValues[i].ptr)
+		}
+		rc = C.grngo_set_int_vector_values(column.c, cIDs, nIDs,
+			&cValues[0], &nDone)
+	case [][][]byte:
+		cValues := make([]C.grngo_vector, len(values))
+		for i := range values {
+			texts := make([]C.grngo_text, len(values[i]))
+			for j := range values[i] {
+				texts[j] = newCText(values[i][j])
+				pin(&pinner, unsafe.Pointer(texts[j].ptr))
+			}
+			cValues[i] = newCVector(texts)
+			pin(&pinner, cValues[i].ptr)
+		}
+		rc = C.grngo_set_text_vector_values(column.c, cIDs, nIDs,
+			&cValues[0], &nDone)
+	case [][]WeightedText:
+		cValues := make([]C.grngo_vector, len(values))
+		for i := range values {
+			cValues[i] = newCVector(newCWeightedTexts(values[i], &pinner))
+			pin(&pinner, cValues[i].ptr)
+		}
+		rc = C.grngo_set_weighted_vector_values(column.c, cIDs, nIDs,
+			&cValues[0], &nDone)
+	case [][]GeoPoint:
+		cValues := make([]C.grngo_vector, len(values))
+		for i := range values {
+			cValues[i] = newCVector(values[i])
+			pin(&pinner, cValues[i].ptr)
+		}
+		rc = C.grngo_set_geo_point_vector_values(column.c, cIDs, nIDs,
+			&cValues[0], &nDone)
+	default:
+		if !isWeightedSliceType(value.Type().Elem()) {
+			return fmt.Errorf("unsupported values type: type = <%T>: %w",
+				values, ErrInvalidArgument)
+		}
+		cValues := make([]C.grngo_vector, len(ids))
+		for i := range cValues {
+			cValue, err := newCWeightedKeys(column.c.value_type,
+				value.Index(i).Interface(), &pinner)
+			if err != nil {
+				return &RowError{Index: i, ID: ids[i], Err: err}
+			}
+			cValues[i] = newCVector(cValue)
+			pin(&pinner, cValues[i].ptr)
+		}
+		rc = C.grngo_set_weighted_vector_values(column.c, cIDs, nIDs,
+			&cValues[0], &nDone)
+	}
+	if rc != C.GRN_SUCCESS {
+		err := newCError("grngo_set_*_values()", rc, column.table.db)
+		if int(nDone) >= len(ids) {
+			return err
+		}
+		return &RowError{Index: int(nDone), ID: ids[nDone], Err: err}
+	}
+	return nil
+}
+
 // parseScalar parses a scalar value.
 func (column *Column) parseScalar(ptr unsafe.Pointer) (interface{}, error) {
 	switch column.c.value_type {
@@ -1242,10 +5782,13 @@ func (column *Column) parseScalar(ptr unsafe.Pointer) (interface{}, error) {
 	case C.GRN_DB_UINT32:
 		return int64(*(*C.uint32_t)(ptr)), nil
 	case C.GRN_DB_UINT64:
-		return int64(*(*C.uint64_t)(ptr)), nil
+		return uint64(*(*C.uint64_t)(ptr)), nil
 	case C.GRN_DB_FLOAT:
 		return float64(*(*C.double)(ptr)), nil
 	case C.GRN_DB_TIME:
+		if column.table.db.parseTime {
+			return microsToTime(int64(*(*C.int64_t)(ptr))), nil
+		}
 		return int64(*(*C.int64_t)(ptr)), nil
 	case C.GRN_DB_SHORT_TEXT, C.GRN_DB_TEXT, C.GRN_DB_LONG_TEXT:
 		cValue := *(*C.grngo_text)(ptr)
@@ -1325,9 +5868,9 @@ func (column *Column) parseVector(ptr unsafe.Pointer) (interface{}, error) {
 		return value, nil
 	case C.GRN_DB_UINT64:
 		cValue := *(*[]C.uint64_t)(unsafe.Pointer(&header))
-		value := make([]int64, len(cValue))
+		value := make([]uint64, len(cValue))
 		for i := 0; i < len(value); i++ {
-			value[i] = int64(cValue[i])
+			value[i] = uint64(cValue[i])
 		}
 		return value, nil
 	case C.GRN_DB_FLOAT:
@@ -1339,6 +5882,13 @@ func (column *Column) parseVector(ptr unsafe.Pointer) (interface{}, error) {
 		return value, nil
 	case C.GRN_DB_TIME:
 		cValue := *(*[]C.int64_t)(unsafe.Pointer(&header))
+		if column.table.db.parseTime {
+			value := make([]time.Time, len(cValue))
+			for i := 0; i < len(value); i++ {
+				value[i] = microsToTime(int64(cValue[i]))
+			}
+			return value, nil
+		}
 		value := make([]int64, len(cValue))
 		for i := 0; i < len(value); i++ {
 			value[i] = int64(cValue[i])
@@ -1364,20 +5914,93 @@ func (column *Column) parseVector(ptr unsafe.Pointer) (interface{}, error) {
 	}
 }
 
+// parseWeightedVector parses a vector value of a weight vector column.
+// weights points to the weight of the first element.
+func (column *Column) parseWeightedVector(ptr unsafe.Pointer, weights *C.uint) (interface{}, error) {
+	value, err := column.parseVector(ptr)
+	if err != nil {
+		return nil, err
+	}
+	size := int((*C.grngo_vector)(ptr).size)
+	header := reflect.SliceHeader{
+		Data: uintptr(unsafe.Pointer(weights)),
+		Len:  size,
+		Cap:  size,
+	}
+	cWeights := *(*[]C.uint)(unsafe.Pointer(&header))
+	switch value := value.(type) {
+	case []bool:
+		return newWeighted(value, cWeights), nil
+	case []int64:
+		return newWeighted(value, cWeights), nil
+	case []uint64:
+		return newWeighted(value, cWeights), nil
+	case []float64:
+		return newWeighted(value, cWeights), nil
+	case []time.Time:
+		return newWeighted(value, cWeights), nil
+	case [][]byte:
+		return newWeighted(value, cWeights), nil
+	case []GeoPoint:
+		return newWeighted(value, cWeights), nil
+	default:
+		return nil, fmt.Errorf("unsupported value type")
+	}
+}
+
+// newWeighted pairs values with weights.
+func newWeighted[T any](values []T, weights []C.uint) []Weighted[T] {
+	weighted := make([]Weighted[T], len(values))
+	for i := range values {
+		weighted[i] = Weighted[T]{values[i], uint32(weights[i])}
+	}
+	return weighted
+}
+
+// weightedTypes maps a value type to the type of an element of a weight
+// vector of it.
+var weightedTypes = map[reflect.Type]reflect.Type{
+	reflect.TypeOf(false):       reflect.TypeOf(Weighted[bool]{}),
+	reflect.TypeOf(int64(0)):    reflect.TypeOf(Weighted[int64]{}),
+	reflect.TypeOf(uint64(0)):   reflect.TypeOf(Weighted[uint64]{}),
+	reflect.TypeOf(float64(0)):  reflect.TypeOf(Weighted[float64]{}),
+	reflect.TypeOf(time.Time{}): reflect.TypeOf(Weighted[time.Time]{}),
+	reflect.TypeOf([]byte(nil)): reflect.TypeOf(WeightedText{}),
+	reflect.TypeOf(GeoPoint{}):  reflect.TypeOf(Weighted[GeoPoint]{}),
+}
+
 // getValueType() returns a reflect.Type associated with the value type.
+// It returns a type of Weighted for a weight vector column.
 func (column *Column) getValueType() (reflect.Type, error) {
+	valueType, err := column.getScalarType()
+	if (err != nil) || (column.c.with_weight != C.GRN_TRUE) {
+		return valueType, err
+	}
+	return weightedTypes[valueType], nil
+}
+
+// getScalarType() returns a reflect.Type associated with the value type
+// regardless of weights.
+func (column *Column) getScalarType() (reflect.Type, error) {
 	switch column.c.value_type {
 	case C.GRN_DB_BOOL:
 		var dummy bool
 		return reflect.TypeOf(dummy), nil
 	case C.GRN_DB_INT8, C.GRN_DB_INT16, C.GRN_DB_INT32, C.GRN_DB_INT64,
-		C.GRN_DB_UINT8, C.GRN_DB_UINT16, C.GRN_DB_UINT32, C.GRN_DB_UINT64:
+		C.GRN_DB_UINT8, C.GRN_DB_UINT16, C.GRN_DB_UINT32:
 		var dummy int64
 		return reflect.TypeOf(dummy), nil
+	case C.GRN_DB_UINT64:
+		var dummy uint64
+		return reflect.TypeOf(dummy), nil
 	case C.GRN_DB_FLOAT:
 		var dummy float64
 		return reflect.TypeOf(dummy), nil
 	case C.GRN_DB_TIME:
+		if column.table.db.parseTime {
+			var dummy time.Time
+			return reflect.TypeOf(dummy), nil
+		}
 		var dummy int64
 		return reflect.TypeOf(dummy), nil
 	case C.GRN_DB_SHORT_TEXT, C.GRN_DB_TEXT, C.GRN_DB_LONG_TEXT:
@@ -1437,19 +6060,121 @@ func (column *Column) parseDeepVector(ptr unsafe.Pointer) (interface{}, error) {
 	return value.Interface(), nil
 }
 
+// scalarSize returns the size of a scalar value in the C representation.
+func (column *Column) scalarSize() uintptr {
+	switch column.c.value_type {
+	case C.GRN_DB_BOOL:
+		return unsafe.Sizeof(C.grn_bool(0))
+	case C.GRN_DB_INT8, C.GRN_DB_UINT8:
+		return unsafe.Sizeof(C.int8_t(0))
+	case C.GRN_DB_INT16, C.GRN_DB_UINT16:
+		return unsafe.Sizeof(C.int16_t(0))
+	case C.GRN_DB_INT32, C.GRN_DB_UINT32:
+		return unsafe.Sizeof(C.int32_t(0))
+	case C.GRN_DB_INT64, C.GRN_DB_UINT64, C.GRN_DB_TIME:
+		return unsafe.Sizeof(C.int64_t(0))
+	case C.GRN_DB_FLOAT:
+		return unsafe.Sizeof(C.double(0))
+	case C.GRN_DB_SHORT_TEXT, C.GRN_DB_TEXT, C.GRN_DB_LONG_TEXT:
+		return unsafe.Sizeof(C.grngo_text{})
+	case C.GRN_DB_TOKYO_GEO_POINT, C.GRN_DB_WGS84_GEO_POINT:
+		return unsafe.Sizeof(C.grn_geo_point{})
+	default:
+		return 0
+	}
+}
+
+// GetValues gets values of multiple rows in one call.
+// GetValues returns a slice whose elements have the same type as GetValue,
+// e.g. []int64 for Int32 and [][]byte for []ShortText.
+func (column *Column) GetValues(ids []uint32) (interface{}, error) {
+	if err := column.checkOpen("Column.GetValues()"); err != nil {
+		return nil, err
+	}
+	valueType, err := column.getValueType()
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < int(column.c.dimension); i++ {
+		valueType = reflect.SliceOf(valueType)
+	}
+	values := reflect.MakeSlice(reflect.SliceOf(valueType), len(ids), len(ids))
+	if len(ids) == 0 {
+		return values.Interface(), nil
+	}
+	var ptr unsafe.Pointer
+	rc := C.grngo_get_values(column.c, (*C.grn_id)(unsafe.Pointer(&ids[0])),
+		C.size_t(len(ids)), &ptr)
+	if rc != C.GRN_SUCCESS {
+		return nil, newCError("grngo_get_values()", rc, column.table.db)
+	}
+	weights, err := column.getWeights()
+	if err != nil {
+		return nil, err
+	}
+	size := unsafe.Sizeof(C.grngo_vector{})
+	if column.c.dimension == 0 {
+		size = column.scalarSize()
+	}
+	for i := range ids {
+		var value interface{}
+		switch {
+		case column.c.dimension == 0:
+			value, err = column.parseScalar(ptr)
+		case weights != nil:
+			value, err = column.parseWeightedVector(ptr, weights)
+			n := uintptr((*C.grngo_vector)(ptr).size)
+			weights = (*C.uint)(unsafe.Pointer(uintptr(unsafe.Pointer(weights)) +
+				n*unsafe.Sizeof(*weights)))
+		case column.c.dimension == 1:
+			value, err = column.parseVector(ptr)
+		default:
+			value, err = column.parseDeepVector(ptr)
+		}
+		if err != nil {
+			return nil, err
+		}
+		values.Index(i).Set(reflect.ValueOf(value))
+		ptr = unsafe.Pointer(uintptr(ptr) + size)
+	}
+	return values.Interface(), nil
+}
+
 // GetValue gets a value.
 func (column *Column) GetValue(id uint32) (interface{}, error) {
+	if err := column.checkOpen("Column.GetValue()"); err != nil {
+		return nil, err
+	}
 	var ptr unsafe.Pointer
 	rc := C.grngo_get(column.c, C.grn_id(id), &ptr)
 	if rc != C.GRN_SUCCESS {
 		return nil, newCError("grngo_get()", rc, column.table.db)
 	}
-	switch column.c.dimension {
-	case 0:
+	weights, err := column.getWeights()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case column.c.dimension == 0:
 		return column.parseScalar(ptr)
-	case 1:
+	case weights != nil:
+		return column.parseWeightedVector(ptr, weights)
+	case column.c.dimension == 1:
 		return column.parseVector(ptr)
 	default:
 		return column.parseDeepVector(ptr)
 	}
 }
+
+// getWeights returns the weights of the last values got from a weight
+// vector column, or nil for other columns.
+func (column *Column) getWeights() (*C.uint, error) {
+	if column.c.with_weight != C.GRN_TRUE {
+		return nil, nil
+	}
+	var weights *C.uint
+	if rc := C.grngo_get_weights(column.c, &weights); rc != C.GRN_SUCCESS {
+		return nil, newCError("grngo_get_weights()", rc, column.table.db)
+	}
+	return weights, nil
+}
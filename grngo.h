@@ -8,6 +8,10 @@
 
 #define GRNGO_ESTR_BUF_SIZE 256
 
+#define GRNGO_MAX_SHORT_TEXT_LEN 4095
+#define GRNGO_MAX_TEXT_LEN       65535
+#define GRNGO_MAX_LONG_TEXT_LEN  2147484647
+
 #ifdef __cplusplus
 extern "C" {
 #endif  // __cplusplus
@@ -19,6 +23,17 @@ typedef struct {
   size_t     size;
 } grngo_text;
 
+typedef struct {
+  const char   *ptr;
+  size_t       size;
+  unsigned int weight;
+} grngo_weighted_text;
+
+typedef struct {
+  grn_id       id;
+  unsigned int weight;
+} grngo_weighted_id;
+
 typedef struct {
   const void *ptr;
   size_t     size;
@@ -26,19 +41,28 @@ typedef struct {
 
 // -- grngo_db --
 
-typedef struct {
-  grn_ctx *ctx;
-  grn_obj *obj;
-  char    *estr;  // TODO: Reserved.
-  char    estr_buf[GRNGO_ESTR_BUF_SIZE];  // TODO: Reserved.
-} grngo_db;
+typedef struct grngo_db grngo_db;
+
+struct grngo_db {
+  grn_ctx  *ctx;
+  grn_obj  *obj;
+  grngo_db *parent;  // The owner of obj if obj is shared, otherwise NULL.
+  char     *estr;  // TODO: Reserved.
+  char     estr_buf[GRNGO_ESTR_BUF_SIZE];  // TODO: Reserved.
+};
 
 grn_rc grngo_create_db(const char *path, size_t path_len, grngo_db **db);
 grn_rc grngo_open_db(const char *path, size_t path_len, grngo_db **db);
+grn_rc grngo_dup_db(grngo_db *db, grngo_db **new_db);
 void grngo_close_db(grngo_db *db);
 
 grn_rc grngo_send(grngo_db *db, const char *cmd, size_t cmd_len);
 grn_rc grngo_recv(grngo_db *db, char **res, unsigned int *res_len);
+grn_rc grngo_send_stream(grngo_db *db, const char *cmd, size_t cmd_len,
+                         uintptr_t handle);
+
+grn_rc grngo_get_obj_id(grngo_db *db, const char *name, size_t name_len,
+                        grn_id *id);
 
 // -- grngo_table --
 
@@ -53,9 +77,17 @@ grn_rc grngo_open_table(grngo_db *db, const char *name, size_t name_len,
                         grngo_table **tbl);
 void grngo_close_table(grngo_table *tbl);
 
+grn_bool grngo_table_refers(grngo_table *tbl, grn_id id);
+
+grn_rc grngo_remove_table(grngo_db *db, const char *name, size_t name_len);
+grn_rc grngo_rename_table(grngo_db *db, const char *name, size_t name_len,
+                          const char *new_name, size_t new_name_len);
+
 grn_rc grngo_insert_void(grngo_table *tbl, grn_bool *inserted, grn_id *id);
 grn_rc grngo_insert_bool(grngo_table *tbl, grn_bool key,
                          grn_bool *inserted, grn_id *id);
+grn_rc grngo_insert_uint(grngo_table *tbl, uint64_t key,
+                         grn_bool *inserted, grn_id *id);
 grn_rc grngo_insert_int(grngo_table *tbl, int64_t key,
                         grn_bool *inserted, grn_id *id);
 grn_rc grngo_insert_float(grngo_table *tbl, double key,
@@ -65,6 +97,41 @@ grn_rc grngo_insert_text(grngo_table *tbl, grngo_text key,
 grn_rc grngo_insert_geo_point(grngo_table *tbl, grn_geo_point key,
                               grn_bool *inserted, grn_id *id);
 
+grn_rc grngo_find_row(grngo_table *tbl, const void *key, size_t key_size,
+                      grn_id *id);
+grn_rc grngo_delete_row(grngo_table *tbl, grn_id id, grn_bool *deleted);
+grn_rc grngo_delete_row_by_key(grngo_table *tbl,
+                               const void *key, size_t key_size,
+                               grn_bool *deleted);
+
+// -- grngo_cursor --
+
+typedef struct {
+  grngo_table      *table;
+  grn_table_cursor *obj;
+} grngo_cursor;
+
+grn_rc grngo_open_cursor(grngo_table *tbl, const void *min, size_t min_size,
+                         const void *max, size_t max_size,
+                         int offset, int limit, int flags,
+                         grngo_cursor **cursor);
+void grngo_close_cursor(grngo_cursor *cursor);
+
+grn_rc grngo_cursor_next(grngo_cursor *cursor, grn_id *id);
+
+// -- grngo_search --
+
+typedef struct {
+  grn_id id;
+  double score;
+} grngo_hit;
+
+grn_rc grngo_search(grngo_table *tbl, const char *index_name, size_t name_len,
+                    const char *query, size_t query_len, int mode,
+                    int similarity_threshold, int max_interval,
+                    int offset, int limit, grngo_hit **hits, size_t *n_hits);
+void grngo_free_hits(grngo_table *tbl, grngo_hit *hits);
+
 // -- grngo_column --
 
 typedef struct {
@@ -78,20 +145,33 @@ typedef struct {
   grn_builtin_type value_type;
   int              dimension;
   grn_bool         writable;
+  grn_bool         with_weight;  // Whether srcs[0] has weights or not.
+  grn_obj          *weight_buf;  // Weights of the last values.
 } grngo_column;
 
 grn_rc grngo_open_column(grngo_table *tbl, const char *name, size_t name_len,
                          grngo_column **column);
 void grngo_close_column(grngo_column *column);
 
+grn_bool grngo_column_refers(grngo_column *column, grn_id id);
+
+grn_rc grngo_remove_column(grngo_table *tbl, const char *name,
+                           size_t name_len);
+grn_rc grngo_rename_column(grngo_table *tbl, const char *name,
+                           size_t name_len,
+                           const char *new_name, size_t new_name_len);
+
 grn_rc grngo_set_bool(grngo_column *column, grn_id id, grn_bool value);
 grn_rc grngo_set_int(grngo_column *column, grn_id id, int64_t value);
+grn_rc grngo_set_uint(grngo_column *column, grn_id id, uint64_t value);
 grn_rc grngo_set_float(grngo_column *column, grn_id id, double value);
 grn_rc grngo_set_text(grngo_column *column, grn_id id, grngo_text value);
 grn_rc grngo_set_geo_point(grngo_column *column, grn_id id,
                            grn_geo_point value);
 grn_rc grngo_set_bool_vector(grngo_column *column, grn_id id,
                              grngo_vector value);
+grn_rc grngo_set_uint_vector(grngo_column *column, grn_id id,
+                             grngo_vector value);
 grn_rc grngo_set_int_vector(grngo_column *column, grn_id id,
                             grngo_vector value);
 grn_rc grngo_set_float_vector(grngo_column *column, grn_id id,
@@ -100,8 +180,86 @@ grn_rc grngo_set_text_vector(grngo_column *column, grn_id id,
                              grngo_vector value);
 grn_rc grngo_set_geo_point_vector(grngo_column *column, grn_id id,
                                   grngo_vector value);
+grn_rc grngo_set_weighted_vector(grngo_column *column, grn_id id,
+                                 grngo_vector value);
+grn_rc grngo_set_ref_vector(grngo_column *column, grn_id id,
+                            grngo_vector value);
+grn_rc grngo_set_ref_id_vector(grngo_column *column, grn_id id,
+                               grngo_vector value);
+grn_rc grngo_set_weighted_ref_vector(grngo_column *column, grn_id id,
+                                     grngo_vector value);
+grn_rc grngo_set_weighted_ref_id_vector(grngo_column *column, grn_id id,
+                                        grngo_vector value);
+
+grn_rc grngo_set_bool_values(grngo_column *column,
+                             const grn_id *ids, size_t n_ids,
+                             const grn_bool *values, size_t *n_done);
+grn_rc grngo_set_int_values(grngo_column *column,
+                            const grn_id *ids, size_t n_ids,
+                            const int64_t *values, size_t *n_done);
+grn_rc grngo_set_uint_values(grngo_column *column,
+                             const grn_id *ids, size_t n_ids,
+                             const uint64_t *values, size_t *n_done);
+grn_rc grngo_set_float_values(grngo_column *column,
+                              const grn_id *ids, size_t n_ids,
+                              const double *values, size_t *n_done);
+grn_rc grngo_set_text_values(grngo_column *column,
+                             const grn_id *ids, size_t n_ids,
+                             const grngo_text *values, size_t *n_done);
+grn_rc grngo_set_geo_point_values(grngo_column *column,
+                                  const grn_id *ids, size_t n_ids,
+                                  const grn_geo_point *values,
+                                  size_t *n_done);
+grn_rc grngo_set_bool_vector_values(grngo_column *column,
+                                    const grn_id *ids, size_t n_ids,
+                                    const grngo_vector *values,
+                                    size_t *n_done);
+grn_rc grngo_set_int_vector_values(grngo_column *column,
+                                   const grn_id *ids, size_t n_ids,
+                                   const grngo_vector *values,
+                                   size_t *n_done);
+grn_rc grngo_set_uint_vector_values(grngo_column *column,
+                                    const grn_id *ids, size_t n_ids,
+                                    const grngo_vector *values,
+                                    size_t *n_done);
+grn_rc grngo_set_float_vector_values(grngo_column *column,
+                                     const grn_id *ids, size_t n_ids,
+                                     const grngo_vector *values,
+                                     size_t *n_done);
+grn_rc grngo_set_text_vector_values(grngo_column *column,
+                                    const grn_id *ids, size_t n_ids,
+                                    const grngo_vector *values,
+                                    size_t *n_done);
+grn_rc grngo_set_weighted_vector_values(grngo_column *column,
+                                        const grn_id *ids, size_t n_ids,
+                                        const grngo_vector *values,
+                                        size_t *n_done);
+grn_rc grngo_set_geo_point_vector_values(grngo_column *column,
+                                         const grn_id *ids, size_t n_ids,
+                                         const grngo_vector *values,
+                                         size_t *n_done);
+grn_rc grngo_set_ref_vector_values(grngo_column *column,
+                                   const grn_id *ids, size_t n_ids,
+                                   const grngo_vector *values,
+                                   size_t *n_done);
+grn_rc grngo_set_ref_id_vector_values(grngo_column *column,
+                                      const grn_id *ids, size_t n_ids,
+                                      const grngo_vector *values,
+                                      size_t *n_done);
+grn_rc grngo_set_weighted_ref_vector_values(grngo_column *column,
+                                            const grn_id *ids, size_t n_ids,
+                                            const grngo_vector *values,
+                                            size_t *n_done);
+grn_rc grngo_set_weighted_ref_id_vector_values(grngo_column *column,
+                                               const grn_id *ids,
+                                               size_t n_ids,
+                                               const grngo_vector *values,
+                                               size_t *n_done);
 
 grn_rc grngo_get(grngo_column *column, grn_id id, void **value);
+grn_rc grngo_get_values(grngo_column *column, const grn_id *ids, size_t n_ids,
+                        void **values);
+grn_rc grngo_get_weights(grngo_column *column, const unsigned int **weights);
 
 #ifdef __cplusplus
 }  // extern "C"
@@ -1,14 +1,21 @@
 package grngo
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"math/rand"
 	"os"
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 // Functions for random key/value generation.
@@ -56,7 +63,7 @@ func generateRandomScalar(valueType string) interface{} {
 	case "UInt32":
 		return int64(rand.Uint32())
 	case "UInt64":
-		return rand.Int63()
+		return rand.Uint64()
 	case "Float":
 		return rand.Float64()
 	case "Time":
@@ -121,9 +128,9 @@ func generateRandomVector(valueType string) interface{} {
 		}
 		return value
 	case "UInt64":
-		value := make([]int64, size)
+		value := make([]uint64, size)
 		for i := 0; i < size; i++ {
-			value[i] = rand.Int63()
+			value[i] = rand.Uint64()
 		}
 		return value
 	case "Float":
@@ -180,7 +187,7 @@ func generateRandomKey(keyType string) interface{} {
 	case "UInt32":
 		return int64(rand.Uint32())
 	case "UInt64":
-		return rand.Int63()
+		return rand.Uint64()
 	case "Float":
 		return rand.Float64()
 	case "Time":
@@ -292,438 +299,2429 @@ func TestLoad(t *testing.T) {
 //	bytes, _ := db.Query("select Table")
 }
 
-func TestDB(t *testing.T) {
-	dirPath, dbPath, db := createTempDB(t)
-	defer os.RemoveAll(dirPath)
-	if err := db.Close(); err != nil {
-		t.Fatalf("DB.Close() failed: %v", err)
-	}
-	db, err := OpenDB(dbPath)
+func TestLoadTypes(t *testing.T) {
+	options := NewTableOptions()
+	options.KeyType = "ShortText"
+	dirPath, _, db, users := createTempTable(t, "Users", options)
+	defer removeTempDB(t, dirPath, db)
+	table, err := db.CreateTable("Table", options)
 	if err != nil {
-		t.Fatalf("OpenDB() failed: %v", err)
+		t.Fatalf("DB.CreateTable() failed: %v", err)
 	}
-	defer db.Close()
-	db2, err := OpenDB(dbPath)
+	columns := [][2]string{
+		{"Int8", "Int8"}, {"UInt16", "UInt16"}, {"Float", "Float"},
+		{"Time", "Time"}, {"GeoPoint", "WGS84GeoPoint"}, {"Ints", "[]Int32"},
+		{"Author", "Users"}, {"Readers", "[]Users"}, {"Score", "Int64"},
+	}
+	for _, column := range columns {
+		if _, err := table.CreateColumn(column[0], column[1], nil); err != nil {
+			t.Fatalf("Table.CreateColumn() failed: %v", err)
+		}
+	}
+	type User struct {
+		Key string `grngo:"_key"`
+	}
+	type Rec struct {
+		Key      string    `grngo:"_key"`
+		Int8     int8      `grngo:"Int8"`
+		UInt16   uint16    `grngo:"UInt16"`
+		Float    float32   `grngo:"Float"`
+		Time     time.Time `grngo:"Time"`
+		GeoPoint GeoPoint  `grngo:"GeoPoint"`
+		Ints     []int32   `grngo:"Ints"`
+		Author   User      `grngo:"Author"`
+		Readers  []*User   `grngo:"Readers"`
+		Score    *int64    `grngo:"Score,omitempty"`
+		Ignored  int       `grngo:"-"`
+	}
+	score := int64(100)
+	recs := []*Rec{{
+		Key: "A", Int8: -8, UInt16: 16, Float: 1.5,
+		Time: time.Unix(1, 500000000), GeoPoint: GeoPoint{100, 200},
+		Ints: []int32{1, -2}, Author: User{"alice"},
+		Readers: []*User{{"bob"}, {"carol"}}, Score: &score,
+	}, {Key: "B\n", Time: time.Unix(0, 0)}}
+	body, err := table.genLoadBody(recs)
 	if err != nil {
-		t.Fatalf("OpenDB() failed: %v", err)
+		t.Fatalf("Table.genLoadBody() failed: %v", err)
+	}
+	expected := `[{"_key":"A","Int8":-8,"UInt16":16,"Float":1.5,` +
+		`"Time":1.500000,"GeoPoint":"100x200","Ints":[1,-2],"Author":"alice",` +
+		`"Readers":["bob","carol"],"Score":100},` +
+		`{"_key":"B\u000a","Int8":0,"UInt16":0,"Float":0,"Time":0.000000,` +
+		`"GeoPoint":"0x0","Ints":[],"Author":"","Readers":[]}]`
+	if body != expected {
+		t.Fatalf("Table.genLoadBody() failed: body = %s, expected = %s",
+			body, expected)
+	}
+	type OmitRec struct {
+		Key    string    `grngo:"_key"`
+		Int8   int8      `grngo:"Int8,omitempty"`
+		Float  float32   `grngo:"Float,omitempty"`
+		Ints   []int32   `grngo:"Ints,omitempty"`
+		Author User      `grngo:"Author,omitempty"`
+		Time   time.Time `grngo:"Time,omitempty"`
+		Score  *int64    `grngo:"Score,omitempty"`
+	}
+	zero := int64(0)
+	body, err = table.genLoadBody([]OmitRec{
+		{Key: "C", Ints: []int32{}, Time: time.Unix(0, 0)},
+		{Key: "D", Int8: -1, Float: 0.5, Ints: []int32{0}, Time: time.Unix(0, 0),
+			Score: &zero},
+	})
+	if err != nil {
+		t.Fatalf("Table.genLoadBody() failed: %v", err)
+	}
+	expected = `[{"_key":"C","Author":"","Time":0.000000},` +
+		`{"_key":"D","Int8":-1,"Float":0.5,"Ints":[0],"Author":"",` +
+		`"Time":0.000000,"Score":0}]`
+	if body != expected {
+		t.Fatalf("Table.genLoadBody() failed: body = %s, expected = %s",
+			body, expected)
+	}
+	if _, err := table.Load(recs[:1], nil); err != nil {
+		t.Fatalf("Table.Load() failed: %v", err)
+	}
+	id, found, err := table.FindRow([]byte("A"))
+	if err != nil || !found {
+		t.Fatalf("Table.FindRow() failed: found = %v, err = %v", found, err)
+	}
+	expectedValues := map[string]interface{}{
+		"Int8": int64(-8), "UInt16": int64(16), "Float": 1.5,
+		"Time": int64(1500000), "GeoPoint": GeoPoint{100, 200},
+		"Ints": []int64{1, -2}, "Author": []byte("alice"),
+		"Readers": [][]byte{[]byte("bob"), []byte("carol")}, "Score": int64(100),
+	}
+	for name, expectedValue := range expectedValues {
+		value, err := table.GetValue(name, id)
+		if err != nil {
+			t.Fatalf("Table.GetValue() failed: %v", err)
+		}
+		if !reflect.DeepEqual(value, expectedValue) {
+			t.Fatalf("Table.GetValue() failed: name = %s, value = %v, expected = %v",
+				name, value, expectedValue)
+		}
+	}
+	if _, found, _ := users.FindRow([]byte("carol")); !found {
+		t.Fatalf("Table.Load() failed: referred keys are not inserted")
+	}
+	type BadRec struct {
+		Value float64 `grngo:"Float"`
+	}
+	if _, err := table.Load(BadRec{math.NaN()}, nil); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("Table.Load() succeeded for NaN: %v", err)
 	}
-	defer db2.Close()
 }
 
-func TestDBRefresh(t *testing.T) {
-	dirPath, _, db, _, _ := createTempColumn(t, "Table", nil, "Value", "Bool", nil)
+func TestLoadResult(t *testing.T) {
+	options := NewTableOptions()
+	options.KeyType = "ShortText"
+	dirPath, _, db, table := createTempTable(t, "Table", options)
 	defer removeTempDB(t, dirPath, db)
-	if _, err := db.Query("column_remove Table Value"); err != nil {
-		t.Fatalf("DB.Query() failed: %v", err)
+	if _, err := table.CreateColumn("Value", "Int64", nil); err != nil {
+		t.Fatalf("Table.CreateColumn() failed: %v", err)
 	}
-	if err := db.Refresh(); err != nil {
-		t.Fatalf("DB.Refresh() failed: %v", err)
+	type Rec struct {
+		Key   *string `grngo:"_key,omitempty"`
+		Value int64   `grngo:"Value"`
 	}
-	if _, err := db.FindTable("Table"); err != nil {
-		t.Fatalf("DB.FindTable() failed: %v", err)
+	keys := []string{"A", "B"}
+	recs := []Rec{{&keys[0], 1}, {nil, 2}, {&keys[1], 3}}
+	result, err := table.Load(recs, nil)
+	if err == nil {
+		t.Fatalf("Table.Load() succeeded for a record without _key")
 	}
-	if _, err := db.FindColumn("Table", "Column"); err == nil {
-		t.Fatalf("DB.FindColumn() succeeded for deleted column")
+	var rowErr *RowError
+	if !errors.As(err, &rowErr) || (rowErr.Index != 1) {
+		t.Fatalf("Table.Load() failed: %v", err)
 	}
-	if _, err := db.Query("table_remove Table"); err != nil {
-		t.Fatalf("DB.Query() failed: %v", err)
+	if (result == nil) || (result.Loaded != 2) || (len(result.Errors) != 1) ||
+		!reflect.DeepEqual(result.IDs, []uint32{1, NilID, 2}) {
+		t.Fatalf("Table.Load() failed: result = %+v", result)
 	}
-	if err := db.Refresh(); err != nil {
-		t.Fatalf("DB.Refresh() failed: %v", err)
+	result, err = db.Load("Table", recs[2:], nil)
+	if err != nil {
+		t.Fatalf("DB.Load() failed: %v", err)
 	}
-	if _, err := db.FindTable("Table"); err == nil {
-		t.Fatalf("DB.FindTable() succeeded for deleted table")
+	if (result.Loaded != 1) || !reflect.DeepEqual(result.IDs, []uint32{2}) ||
+		(len(result.Errors) != 0) {
+		t.Fatalf("DB.Load() failed: result = %+v", result)
+	}
+
+	// The fallback finds both records while only one of them is loaded.
+	records, fields, err := loadRecords([]Rec{recs[0], recs[2]})
+	if err != nil {
+		t.Fatalf("loadRecords() failed: %v", err)
+	}
+	result, err = table.resolveLoadResult([]byte("1"), records, fields)
+	if err != nil {
+		t.Fatalf("Table.resolveLoadResult() failed: %v", err)
+	}
+	if !reflect.DeepEqual(result.IDs, []uint32{1, 2}) || (result.check(2) == nil) {
+		t.Fatalf("Table.resolveLoadResult() failed: result = %+v", result)
 	}
 }
 
-func testKeyValue(t *testing.T, db *DB, keyType, valueType string) bool {
+func TestGetPut(t *testing.T) {
 	options := NewTableOptions()
-	options.KeyType = keyType
-	options.ValueType = valueType
+	options.KeyType = "ShortText"
+	dirPath, _, db, users := createTempTable(t, "Users", options)
+	defer removeTempDB(t, dirPath, db)
+	if _, err := users.CreateColumn("Age", "Int32", nil); err != nil {
+		t.Fatalf("Table.CreateColumn() failed: %v", err)
+	}
 	table, err := db.CreateTable("Table", options)
 	if err != nil {
-		t.Log("DB.CreateTable() failed:", err)
-		return false
+		t.Fatalf("DB.CreateTable() failed: %v", err)
 	}
-	defer db.Query("table_remove Table")
-	keyColumn, err := table.FindColumn("_key")
-	if (keyType == "") && (err == nil) {
-		t.Log("Table.FindColumn() succeeded for non-existent _key")
-		return false
+	columns := [][2]string{
+		{"Count", "Int8"}, {"Score", "Float"}, {"Time", "Time"},
+		{"Point", "WGS84GeoPoint"}, {"Values", "[]Int64"}, {"Note", "ShortText"},
+		{"Author", "Users"}, {"Readers", "[]Users"},
 	}
-	if (keyType != "") && (err != nil) {
-		t.Log("Table.FindColumn() failed", err)
-		return false
+	for _, column := range columns {
+		if _, err := table.CreateColumn(column[0], column[1], nil); err != nil {
+			t.Fatalf("Table.CreateColumn() failed: %v", err)
+		}
 	}
-	valueColumn, err := table.FindColumn("_value")
-	if (valueType == "") && (err == nil) {
-		t.Log("Table.FindColumn() succeeded for non-existent _value")
-		return false
+	type User struct {
+		ID  uint32 `grngo:"_id"`
+		Key string `grngo:"_key"`
+		Age int    `grngo:"Age"`
 	}
-	if (valueType != "") && (err != nil) {
-		t.Log("Table.FindColumn() failed:", err)
-		return false
+	type Rec struct {
+		ID      uint32    `grngo:"_id"`
+		Key     string    `grngo:"_key"`
+		Count   int8      `grngo:"Count"`
+		Score   float32   `grngo:"Score"`
+		Time    time.Time `grngo:"Time"`
+		Point   GeoPoint  `grngo:"Point"`
+		Values  []uint16  `grngo:"Values"`
+		Note    *string   `grngo:"Note"`
+		Author  *User     `grngo:"Author"`
+		Readers []User    `grngo:"Readers"`
 	}
-	for i := 0; i < 100; i++ {
-		key := generateRandomKey(keyType)
-		_, id, err := table.InsertRow(key)
-		if err != nil {
-			t.Log("Table.InsertRow() failed:", err)
-			return false
-		}
-		if keyColumn != nil {
-			storedKey, err := keyColumn.GetValue(id)
-			if err != nil {
-				t.Log("Column.GetValue() failed:", err)
-				return false
-			}
-			if !reflect.DeepEqual(key, storedKey) {
-				t.Logf("DeepEqual() failed: key = %v, storedKey = %v", key, storedKey)
-				return false
-			}
-		}
-		if valueColumn != nil {
-			value := generateRandomValue(valueType)
-			if err := valueColumn.SetValue(id, value); err != nil {
-				t.Log("Column.SetValue() failed:", err)
-				return false
-			}
-			storedValue, err := valueColumn.GetValue(id)
-			if err != nil {
-				t.Log("Column.GetValue() failed:", err)
-				return false
-			}
-			if !reflect.DeepEqual(value, storedValue) {
-				t.Logf("DeepEqual() failed: value = %v, storedValue = %v",
-					value, storedValue)
-				return false
-			}
+	for i, name := range []string{"alice", "bob"} {
+		if _, _, err := db.Put("Users", name, User{Age: 20 + i}); err != nil {
+			t.Fatalf("DB.Put() failed: %v", err)
 		}
 	}
-	return true
+	note := "note"
+	rec := Rec{
+		Key: "A", Count: -3, Score: 1.5, Time: time.Unix(100, 5000),
+		Point: GeoPoint{1, 2}, Values: []uint16{1, 2}, Note: &note,
+		Author:  &User{Key: "alice"},
+		Readers: []User{{Key: "bob"}, {Key: "alice"}},
+	}
+	inserted, id, err := table.Put(rec.Key, &rec)
+	if err != nil || !inserted {
+		t.Fatalf("Table.Put() failed: inserted = %v, err = %v", inserted, err)
+	}
+	var stored Rec
+	if err := table.Get(id, &stored); err != nil {
+		t.Fatalf("Table.Get() failed: %v", err)
+	}
+	rec.ID = id
+	rec.Author = &User{1, "alice", 20}
+	rec.Readers = []User{{2, "bob", 21}, {1, "alice", 20}}
+	if !stored.Time.Equal(rec.Time) {
+		t.Fatalf("Table.Get() failed: time = %v, expected = %v", stored.Time, rec.Time)
+	}
+	stored.Time = rec.Time
+	if !reflect.DeepEqual(stored, rec) {
+		t.Fatalf("Table.Get() failed: stored = %+v, expected = %+v", stored, rec)
+	}
+	rec.Note = nil
+	rec.Count = 4
+	if inserted, _, err := table.Put([]byte("A"), rec); err != nil || inserted {
+		t.Fatalf("Table.Put() failed: inserted = %v, err = %v", inserted, err)
+	}
+	stored = Rec{}
+	if err := db.Get("Table", id, &stored); err != nil {
+		t.Fatalf("DB.Get() failed: %v", err)
+	}
+	if (stored.Count != 4) || (stored.Note == nil) || (*stored.Note != "note") {
+		t.Fatalf("DB.Get() failed: stored = %+v", stored)
+	}
+	var wrong struct {
+		Count bool `grngo:"Count"`
+	}
+	if err := table.Get(id, &wrong); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("Table.Get() succeeded for a wrong type: %v", err)
+	}
 }
 
-func TestKeyValue(t *testing.T) {
-	dirPath, _, db := createTempDB(t)
+func TestPutRefVector(t *testing.T) {
+	// Keys of a reference vector are passed to C and must satisfy the cgo
+	// pointer check, which is enabled by default.
+	options := NewTableOptions()
+	options.KeyType = "ShortText"
+	dirPath, _, db, users := createTempTable(t, "Users", options)
 	defer removeTempDB(t, dirPath, db)
-	keyTypes := []string{
-		"", "Bool", "Int8", "Int16", "Int32", "Int64", "UInt8", "UInt16", "UInt32",
-		"UInt64", "Float", "Time", "ShortText", "TokyoGeoPoint", "WGS84GeoPoint",
+	table, err := db.CreateTable("Table", options)
+	if err != nil {
+		t.Fatalf("DB.CreateTable() failed: %v", err)
 	}
-	valueTypes := []string{
-		"", "Bool", "Int8", "Int16", "Int32", "Int64", "UInt8", "UInt16", "UInt32",
-		"UInt64", "Float", "Time", "TokyoGeoPoint", "WGS84GeoPoint",
+	if _, err := table.CreateColumn("Readers", "[]Users", nil); err != nil {
+		t.Fatalf("Table.CreateColumn() failed: %v", err)
 	}
-	for _, keyType := range keyTypes {
-		for _, valueType := range valueTypes {
-			if !testKeyValue(t, db, keyType, valueType) {
-				t.Logf("[ fail ] keyType = \"%s\", valueType = \"%s\"",
-					keyType, valueType)
-				t.Fail()
-			}
+	type User struct {
+		Key string `grngo:"_key"`
+	}
+	type Rec struct {
+		Readers []User `grngo:"Readers"`
+	}
+	for _, name := range []string{"alice", "bob"} {
+		if _, _, err := users.InsertRow([]byte(name)); err != nil {
+			t.Fatalf("Table.InsertRow() failed: %v", err)
 		}
 	}
+	rec := Rec{Readers: []User{{"bob"}, {"carol"}, {"alice"}}}
+	_, id, err := table.Put("A", &rec)
+	if err != nil {
+		t.Fatalf("Table.Put() failed: %v", err)
+	}
+	value, err := table.GetValue("Readers", id)
+	if err != nil {
+		t.Fatalf("Table.GetValue() failed: %v", err)
+	}
+	expected := [][]byte{[]byte("bob"), []byte("carol"), []byte("alice")}
+	if !reflect.DeepEqual(value, expected) {
+		t.Fatalf("Table.GetValue() failed: value = %v", value)
+	}
 }
 
-func testRefKey(t *testing.T, db *DB, depth int, keyType string) bool {
-	for i := depth; i > 0; i-- {
-		tableName := fmt.Sprintf("Table%d", i)
-		options := NewTableOptions()
-		options.KeyType = keyType
-		_, err := db.CreateTable(tableName, options)
-		if err != nil {
-			t.Log("DB.CreateTable() failed:", err)
-			return false
-		}
-		defer db.Query(fmt.Sprintf("table_remove %s", tableName))
+func TestCreateSchemaFromStruct(t *testing.T) {
+	dirPath, _, db := createTempDB(t)
+	defer removeTempDB(t, dirPath, db)
+	type User struct {
+		Key string `grngo:"_key,table=pat,normalizer=NormalizerAuto"`
+		Age int32  `grngo:"age"`
+	}
+	type Doc struct {
+		ID     uint32   `grngo:"_id"`
+		Key    string   `grngo:"_key"`
+		Title  string   `grngo:"title,type=ShortText,index=Terms:TokenBigram"`
+		Body   []byte   `grngo:"body,compress=zlib,index=Terms:TokenBigram"`
+		Tags   []string `grngo:"tags"`
+		Author *User    `grngo:"author,type=Users"`
+		Count  uint16   `grngo:"count,omitempty"`
+		Skip   int      `grngo:"-"`
+	}
+	if _, err := db.CreateSchemaFromStruct("Users", User{}); err != nil {
+		t.Fatalf("DB.CreateSchemaFromStruct() failed: %v", err)
+	}
+	table, err := db.CreateSchemaFromStruct("Docs", (*Doc)(nil))
+	if err != nil {
+		t.Fatalf("DB.CreateSchemaFromStruct() failed: %v", err)
 	}
-	options := NewTableOptions()
-	options.KeyType = "Table1"
-	table, err := db.CreateTable("Table", options)
+	columns, err := table.Columns()
 	if err != nil {
-		t.Log("DB.CreateTable() failed:", err)
-		return false
+		t.Fatalf("Table.Columns() failed: %v", err)
 	}
-	defer db.Query("table_remove Table")
-	keyColumn, err := table.FindColumn("_key")
+	var types []string
+	for _, column := range columns {
+		types = append(types, column.Name+":"+column.TypeString())
+	}
+	expectedTypes := []string{"author:Users", "body:Text", "count:UInt16",
+		"tags:[]ShortText", "title:ShortText"}
+	if !reflect.DeepEqual(types, expectedTypes) {
+		t.Fatalf("Table.Columns() failed: types = %v", types)
+	}
+	tables, err := db.Tables()
 	if err != nil {
-		t.Log("Table.FindColumn() failed:", err)
-		return false
+		t.Fatalf("DB.Tables() failed: %v", err)
 	}
-	for i := 0; i < 100; i++ {
-		key := generateRandomKey(keyType)
-		_, id, err := table.InsertRow(key)
-		if err != nil {
-			t.Log("Table.InsertRow() failed:", err)
-			return false
-		}
-		storedKey, err := keyColumn.GetValue(id)
-		if err != nil {
-			t.Log("Column.GetValue() failed:", err)
-			return false
+	var terms *TableInfo
+	for _, info := range tables {
+		if info.Name == "Terms" {
+			terms = info
 		}
-		if !reflect.DeepEqual(key, storedKey) {
-			t.Logf("DeepEqual() failed: key = %v, storedKey = %v", key, storedKey)
-			return false
+	}
+	if (terms == nil) || (terms.Flags != TablePatKey) ||
+		(terms.KeyType != "ShortText") || (terms.DefaultTokenizer != "TokenBigram") ||
+		(terms.Normalizer != "NormalizerAuto") {
+		t.Fatalf("DB.CreateSchemaFromStruct() failed: terms = %+v", terms)
+	}
+	for _, name := range []string{"Docs_title", "Docs_body"} {
+		if _, err := db.FindColumn("Terms", name); err != nil {
+			t.Fatalf("DB.FindColumn() failed: %v", err)
 		}
 	}
-	return true
+	if _, err := db.CreateSchemaFromStruct("Docs", Doc{}); err != nil {
+		t.Fatalf("DB.CreateSchemaFromStruct() failed for the same schema: %v", err)
+	}
+	type ConflictingDoc struct {
+		Key   string `grngo:"_key"`
+		Title string `grngo:"title"`
+	}
+	if _, err := db.CreateSchemaFromStruct("Docs", ConflictingDoc{}); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("DB.CreateSchemaFromStruct() succeeded for a conflict: %v", err)
+	}
+	type ConflictingNote struct {
+		Key  string `grngo:"_key"`
+		Text string `grngo:"text,index=Terms:TokenTrigram"`
+	}
+	if _, err := db.CreateSchemaFromStruct("Notes", ConflictingNote{}); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("DB.CreateSchemaFromStruct() succeeded for a conflict: %v", err)
+	}
+	if _, err := db.FindTable("Notes"); err == nil {
+		t.Fatalf("DB.CreateSchemaFromStruct() created a table before a conflict")
+	}
+	type BadDoc struct {
+		Author User `grngo:"author"`
+	}
+	if _, err := db.CreateSchemaFromStruct("BadDocs", BadDoc{}); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("DB.CreateSchemaFromStruct() succeeded without type: %v", err)
+	}
+	if _, err := db.CreateSchemaFromStruct("NilDocs", nil); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("DB.CreateSchemaFromStruct() succeeded for nil: %v", err)
+	}
+	if _, err := SchemaFromStruct("NilDocs", nil); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("SchemaFromStruct() succeeded for nil: %v", err)
+	}
 }
 
-func TestRefKey(t *testing.T) {
+func TestSchemaMigration(t *testing.T) {
 	dirPath, _, db := createTempDB(t)
 	defer removeTempDB(t, dirPath, db)
-	keyTypes := []string{
-		"Bool", "Int8", "Int16", "Int32", "Int64", "UInt8", "UInt16", "UInt32",
-		"UInt64", "Float", "Time", "ShortText", "TokyoGeoPoint", "WGS84GeoPoint",
+	type DocV1 struct {
+		Key   string `grngo:"_key"`
+		Title string `grngo:"title,type=ShortText,index=Terms:TokenBigram"`
+		Old   int32  `grngo:"old"`
+		Body  string `grngo:"body"`
+	}
+	type DocV2 struct {
+		Key   string `grngo:"_key"`
+		Title string `grngo:"title,index=Terms:TokenDelimit"`
+		Count int32  `grngo:"count"`
+		Tags  string `grngo:"tags"`
+	}
+	if _, err := db.CreateSchemaFromStruct("Docs", DocV1{}); err != nil {
+		t.Fatalf("DB.CreateSchemaFromStruct() failed: %v", err)
+	}
+	table, err := db.FindTable("Docs")
+	if err != nil {
+		t.Fatalf("DB.FindTable() failed: %v", err)
 	}
-	maxDepth := 3
-	for depth := 1; depth <= maxDepth; depth++ {
-		for _, keyType := range keyTypes {
-			if !testRefKey(t, db, depth, keyType) {
-				t.Logf("[ fail ] depth = %d, keyType = \"%s\"", depth, keyType)
-				t.Fail()
-			}
-		}
+	docs := []DocV1{{Key: "a", Title: "Hello", Old: 1}, {Key: "b", Title: "World", Old: 2}}
+	if _, err := table.Load(docs, nil); err != nil {
+		t.Fatalf("Table.Load() failed: %v", err)
 	}
-}
-
-func testColumn(t *testing.T, table *Table, valueType string, ids []uint32) bool {
-	columnName := valueType
-	if strings.HasPrefix(valueType, "[]") {
-		columnName = valueType[2:] + "Vector"
+	desired, err := SchemaFromStruct("Docs", DocV2{})
+	if err != nil {
+		t.Fatalf("SchemaFromStruct() failed: %v", err)
 	}
-	columnName += "Value"
-	column, err := table.CreateColumn(columnName, valueType, nil)
+	desired.RenamedColumns = map[string]string{"Docs.count": "old"}
+	if _, err := db.DiffSchema(desired, nil); !errors.Is(err, ErrOperationNotPermitted) {
+		t.Fatalf("DB.DiffSchema() succeeded for data loss: %v", err)
+	}
+	options := &SchemaDiffOptions{AllowDataLoss: true}
+	changes, err := db.DiffSchema(desired, options)
 	if err != nil {
-		t.Log("Table.CreateColumn() failed:", err)
-		return false
+		t.Fatalf("DB.DiffSchema() failed: %v", err)
+	}
+	var actual []string
+	for _, change := range changes {
+		actual = append(actual, change.String())
+	}
+	if !changes[0].DataLoss || changes[1].DataLoss {
+		t.Fatalf("DB.DiffSchema() failed: dataLoss = %v, %v",
+			changes[0].DataLoss, changes[1].DataLoss)
+	}
+	expected := []string{
+		"RemoveColumn Docs.body",
+		"ChangeTable Terms",
+		"RenameColumn Docs.old -> count",
+		"ChangeColumn Docs.title: ShortText -> Text",
+		"AddColumn Docs.tags",
+		"AddColumn Terms.Docs_title",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("DB.DiffSchema() failed: actual = %q, expected = %q", actual, expected)
+	}
+	if err := db.ApplySchema(changes); err != nil {
+		t.Fatalf("DB.ApplySchema() failed: %v", err)
+	}
+	if changes, err := db.DiffSchema(desired, nil); err != nil {
+		t.Fatalf("DB.DiffSchema() failed: %v", err)
+	} else if len(changes) != 0 {
+		t.Fatalf("DB.ApplySchema() failed: changes = %v", changes)
+	}
+	var doc DocV2
+	if err := db.Get("Docs", uint32(2), &doc); err != nil {
+		t.Fatalf("DB.Get() failed: %v", err)
+	}
+	if (doc.Title != "World") || (doc.Count != 2) {
+		t.Fatalf("DB.ApplySchema() lost values: doc = %+v", doc)
+	}
+	if _, err := db.FindColumn("Docs", "body"); err == nil {
+		t.Fatalf("DB.FindColumn() succeeded for a removed column")
+	}
+
+	current := &Schema{Tables: []*TableInfo{
+		{Name: "Users", TableOptions: TableOptions{Flags: TableHashKey, KeyType: "ShortText"}},
+		{Name: "Admins", TableOptions: TableOptions{Flags: TableHashKey, KeyType: "Users"}},
+	}}
+	if changes, err = DiffSchema(current, &Schema{}, nil); (err != nil) || (len(changes) != 0) {
+		t.Fatalf("DiffSchema() failed: changes = %v, err = %v", changes, err)
+	}
+	options = &SchemaDiffOptions{RemoveTables: true, AllowDataLoss: true}
+	changes, err = DiffSchema(current, &Schema{}, options)
+	if (err != nil) || (len(changes) != 2) || (changes[0].OldTable.Name != "Admins") ||
+		(changes[1].OldTable.Name != "Users") {
+		t.Fatalf("DiffSchema() failed: changes = %v, err = %v", changes, err)
+	}
+	changes, err = DiffSchema(&Schema{}, current, nil)
+	if (err != nil) || (len(changes) != 2) || (changes[0].NewTable.Name != "Users") ||
+		(changes[1].NewTable.Name != "Admins") {
+		t.Fatalf("DiffSchema() failed: changes = %v, err = %v", changes, err)
+	}
+
+	// An index column with different sources is rebuilt, and an index column
+	// of a table out of scope is kept.
+	current.Columns = []*ColumnInfo{
+		{Name: "name", Table: "Users", Type: ScalarColumn, ValueType: "ShortText"},
+		{Name: "Users_key", Table: "Terms", Type: IndexColumn, ValueType: "Users",
+			Sources: []string{"_key"}},
+		{Name: "Admins_key", Table: "Terms", Type: IndexColumn, ValueType: "Admins",
+			Sources: []string{"_key"}},
+	}
+	current.Tables = append(current.Tables, &TableInfo{Name: "Terms",
+		TableOptions: TableOptions{Flags: TablePatKey, KeyType: "ShortText"}})
+	desired = &Schema{Tables: current.Tables[0:1], Columns: []*ColumnInfo{
+		current.Columns[0],
+		{Name: "Users_key", Table: "Terms", Type: IndexColumn, ValueType: "Users",
+			Sources: []string{"name"}},
+	}}
+	desired.Tables = append(desired.Tables, current.Tables[2])
+	changes, err = DiffSchema(current, desired, nil)
+	if err != nil {
+		t.Fatalf("DiffSchema() failed: %v", err)
 	}
-	for _, id := range ids {
-		value := generateRandomValue(valueType)
-		if err := column.SetValue(id, value); err != nil {
-			t.Log("Column.SetValue() failed:", err)
-			return false
-		}
-		storedValue, err := column.GetValue(id)
-		if err != nil {
-			t.Log("Column.GetValue() failed:", err)
-			return false
-		}
-		if !reflect.DeepEqual(value, storedValue) {
-			t.Logf("DeepEqual() failed: value = %v, storedValue = %v",
-				value, storedValue)
-			return false
-		}
+	actual = nil
+	for _, change := range changes {
+		actual = append(actual, change.String())
+	}
+	expected = []string{"RemoveColumn Terms.Users_key", "AddColumn Terms.Users_key"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("DiffSchema() failed: actual = %q, expected = %q", actual, expected)
 	}
-	return true
 }
 
-/*
-func TestColumn(t *testing.T) {
-	dirPath, _, db, table := createTempTable(t, "Table", nil)
+func TestRemoveRename(t *testing.T) {
+	dirPath, _, db := createTempDB(t)
 	defer removeTempDB(t, dirPath, db)
-	valueTypes := []string{
-		"Bool", "Int8", "Int16", "Int32", "Int64", "UInt8", "UInt16", "UInt32",
-		"UInt64", "Float", "Time", "ShortText", "Text", "LongText",
+	users, err := db.CreateTable("Users", nil)
+	if err != nil {
+		t.Fatalf("DB.CreateTable() failed: %v", err)
+	}
+	if _, err := users.CreateColumn("age", "Int32", nil); err != nil {
+		t.Fatalf("Table.CreateColumn() failed: %v", err)
+	}
+	docs, err := db.CreateTable("Docs", nil)
+	if err != nil {
+		t.Fatalf("DB.CreateTable() failed: %v", err)
+	}
+	if _, err := docs.CreateColumn("author", "Users", nil); err != nil {
+		t.Fatalf("Table.CreateColumn() failed: %v", err)
+	}
+	title, err := docs.CreateColumn("title", "ShortText", nil)
+	if err != nil {
+		t.Fatalf("Table.CreateColumn() failed: %v", err)
+	}
+	authorAge, err := docs.FindColumn("author.age")
+	if err != nil {
+		t.Fatalf("Table.FindColumn() failed: %v", err)
+	}
+
+	if err := users.RenameColumn("age", "years"); err != nil {
+		t.Fatalf("Table.RenameColumn() failed: %v", err)
+	}
+	if (authorAge.c != nil) || (title.c == nil) {
+		t.Fatalf("Table.RenameColumn() failed to invalidate handles")
+	}
+	if _, err := docs.FindColumn("author.years"); err != nil {
+		t.Fatalf("Table.FindColumn() failed: %v", err)
+	}
+
+	author, err := docs.FindColumn("author")
+	if err != nil {
+		t.Fatalf("Table.FindColumn() failed: %v", err)
+	}
+	if err := db.RenameTable("Users", "Members"); err != nil {
+		t.Fatalf("DB.RenameTable() failed: %v", err)
+	}
+	if (users.c != nil) || (author.c != nil) || (docs.c == nil) || (title.c == nil) {
+		t.Fatalf("DB.RenameTable() failed to invalidate handles")
+	}
+	if _, err := db.FindTable("Users"); err == nil {
+		t.Fatalf("DB.FindTable() succeeded for an old name")
+	}
+	if _, err := db.FindColumn("Members", "years"); err != nil {
+		t.Fatalf("DB.FindColumn() failed: %v", err)
+	}
+
+	if err := docs.RemoveColumn("_key"); err == nil {
+		t.Fatalf("Table.RemoveColumn() succeeded for _key")
+	}
+	members, err := db.FindTable("Members")
+	if err != nil {
+		t.Fatalf("DB.FindTable() failed: %v", err)
+	}
+	if author, err = docs.FindColumn("author"); err != nil {
+		t.Fatalf("Table.FindColumn() failed: %v", err)
+	}
+	if err := db.RemoveTable("Members"); err == nil {
+		t.Fatalf("DB.RemoveTable() succeeded for a referred table")
+	}
+	if (members.c == nil) || (author.c == nil) {
+		t.Fatalf("DB.RemoveTable() failed to reopen handles")
+	}
+	if table, err := db.FindTable("Members"); (err != nil) || (table != members) {
+		t.Fatalf("DB.FindTable() failed: table = %v, err = %v", table, err)
+	}
+	if err := docs.RemoveColumn("author"); err != nil {
+		t.Fatalf("Table.RemoveColumn() failed: %v", err)
+	}
+	if err := db.RemoveTable("Members"); err != nil {
+		t.Fatalf("DB.RemoveTable() failed: %v", err)
+	}
+	if _, err := db.FindTable("Members"); err == nil {
+		t.Fatalf("DB.FindTable() succeeded for a removed table")
+	}
+	if err := db.RemoveTable("Docs"); err != nil {
+		t.Fatalf("DB.RemoveTable() failed: %v", err)
+	}
+	if (docs.c != nil) || (title.c != nil) {
+		t.Fatalf("DB.RemoveTable() failed to invalidate handles")
+	}
+}
+
+func TestClose(t *testing.T) {
+	dirPath, _, db := createTempDB(t)
+	defer os.RemoveAll(dirPath)
+	table, err := db.CreateTable("Table", nil)
+	if err != nil {
+		t.Fatalf("DB.CreateTable() failed: %v", err)
+	}
+	column, err := table.CreateColumn("Value", "Int32", nil)
+	if err != nil {
+		t.Fatalf("Table.CreateColumn() failed: %v", err)
+	}
+	_, id, err := table.InsertRow(nil)
+	if err != nil {
+		t.Fatalf("Table.InsertRow() failed: %v", err)
+	}
+
+	if err := column.Close(); err != nil {
+		t.Fatalf("Column.Close() failed: %v", err)
+	}
+	if err := column.Close(); err != nil {
+		t.Fatalf("Column.Close() failed for a closed column: %v", err)
+	}
+	if _, err := column.GetValue(id); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Column.GetValue() succeeded for a closed column: %v", err)
+	}
+	if err := column.SetValue(id, int64(1)); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Column.SetValue() succeeded for a closed column: %v", err)
+	}
+	if err := table.SetValue("Value", id, int64(1)); err != nil {
+		t.Fatalf("Table.SetValue() failed: %v", err)
+	}
+
+	cursor, err := table.Cursor(nil)
+	if err != nil {
+		t.Fatalf("Table.Cursor() failed: %v", err)
+	}
+	if err := table.Close(); err != nil {
+		t.Fatalf("Table.Close() failed: %v", err)
+	}
+	if _, err := cursor.Next(); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Cursor.Next() succeeded for a closed table: %v", err)
+	}
+	if _, _, err := table.InsertRow(nil); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Table.InsertRow() succeeded for a closed table: %v", err)
+	}
+	if _, err := table.FindColumn("Value"); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Table.FindColumn() succeeded for a closed table: %v", err)
+	}
+	table, err = db.FindTable("Table")
+	if err != nil {
+		t.Fatalf("DB.FindTable() failed: %v", err)
+	}
+	column, err = table.FindColumn("Value")
+	if err != nil {
+		t.Fatalf("Table.FindColumn() failed: %v", err)
+	}
+	if value, err := column.GetValue(id); err != nil {
+		t.Fatalf("Column.GetValue() failed: %v", err)
+	} else if value != int64(1) {
+		t.Fatalf("Column.GetValue() returned a wrong value: %v", value)
+	}
+
+	if err := db.Refresh(); err != nil {
+		t.Fatalf("DB.Refresh() failed: %v", err)
+	}
+	if _, err := column.GetValue(id); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Column.GetValue() succeeded after DB.Refresh(): %v", err)
+	}
+	table, err = db.FindTable("Table")
+	if err != nil {
+		t.Fatalf("DB.FindTable() failed: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("DB.Close() failed: %v", err)
+	}
+	if _, err := table.GetValue("Value", id); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Table.GetValue() succeeded after DB.Close(): %v", err)
+	}
+	if _, err := db.FindTable("Table"); !errors.Is(err, ErrClosed) {
+		t.Fatalf("DB.FindTable() succeeded after DB.Close(): %v", err)
+	}
+	if err := db.Close(); !errors.Is(err, ErrClosed) {
+		t.Fatalf("DB.Close() succeeded for a closed DB: %v", err)
+	}
+}
+
+func TestLoadStream(t *testing.T) {
+	dirPath, _, db, table := createTempTable(t, "Table", nil)
+	defer removeTempDB(t, dirPath, db)
+	if _, err := table.CreateColumn("Value", "Int64", nil); err != nil {
+		t.Fatalf("Table.CreateColumn() failed: %v", err)
+	}
+	type Rec struct {
+		Value int64 `grngo:"Value"`
+	}
+	options := NewLoadOptions()
+	options.BatchSize = 10
+	options.ChunkSize = 16
+	checkBatches := func(batches []LoadBatch, expected ...int) {
+		if len(batches) != len(expected) {
+			t.Fatalf("Table.LoadStream() failed: batches = %v", batches)
+		}
+		for i, batch := range batches {
+			if (batch.Loaded != expected[i]) || (batch.Failed != 0) || (batch.Err != nil) {
+				t.Fatalf("Table.LoadStream() failed: batches = %v", batches)
+			}
+		}
+	}
+
+	ch := make(chan Rec)
+	go func() {
+		for i := 0; i < 25; i++ {
+			ch <- Rec{int64(i)}
+		}
+		close(ch)
+	}()
+	batches, err := table.LoadStream(context.Background(), ch, options)
+	if err != nil {
+		t.Fatalf("Table.LoadStream() failed: %v", err)
+	}
+	checkBatches(batches, 10, 10, 5)
+
+	iterator := func(yield func(*Rec) bool) {
+		for i := 25; i < 30; i++ {
+			if !yield(&Rec{int64(i)}) {
+				return
+			}
+		}
+	}
+	batches, err = table.LoadStream(context.Background(), iterator, options)
+	if err != nil {
+		t.Fatalf("Table.LoadStream() failed: %v", err)
+	}
+	checkBatches(batches, 5)
+
+	input := `[["Value"],[30],[31]]`
+	batches, err = table.LoadJSON(strings.NewReader(input), options)
+	if err != nil {
+		t.Fatalf("Table.LoadJSON() failed: %v", err)
+	}
+	checkBatches(batches, 2)
+	input = `[{"Value":32},{"Value":33},{"Value":34}]`
+	batches, err = db.LoadJSON("Table", strings.NewReader(input), options)
+	if err != nil {
+		t.Fatalf("DB.LoadJSON() failed: %v", err)
+	}
+	checkBatches(batches, 3)
+	for id := uint32(1); id <= 35; id++ {
+		value, err := table.GetValue("Value", id)
+		if err != nil {
+			t.Fatalf("Table.GetValue() failed: %v", err)
+		}
+		if value != int64(id-1) {
+			t.Fatalf("Table.GetValue() failed: id = %d, value = %v", id, value)
+		}
+	}
+	batches, err = table.LoadJSON(strings.NewReader(`[{"Value":35}]`), &LoadOptions{})
+	if err != nil {
+		t.Fatalf("Table.LoadJSON() failed: %v", err)
+	}
+	checkBatches(batches, 1)
+	var syntaxError *json.SyntaxError
+	_, err = table.LoadJSON(strings.NewReader(`[{"Value":36},{]`), options)
+	if !errors.As(err, &syntaxError) {
+		t.Fatalf("Table.LoadJSON() failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := table.LoadStream(ctx, make(chan Rec), options); err != context.Canceled {
+		t.Fatalf("Table.LoadStream() failed: %v", err)
+	}
+	if _, err := table.LoadStream(context.Background(), []Rec{}, options); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("Table.LoadStream() succeeded for a slice: %v", err)
+	}
+}
+
+func TestTime(t *testing.T) {
+	options := NewTableOptions()
+	options.KeyType = "Time"
+	dirPath, _, db, table := createTempTable(t, "Table", options)
+	defer removeTempDB(t, dirPath, db)
+	if _, err := table.CreateColumn("Value", "Time", nil); err != nil {
+		t.Fatalf("Table.CreateColumn() failed: %v", err)
+	}
+	if _, err := table.CreateColumn("Vector", "[]Time", nil); err != nil {
+		t.Fatalf("Table.CreateColumn() failed: %v", err)
+	}
+	key := time.Date(2015, 6, 1, 12, 34, 56, 789012345, time.UTC)
+	value := time.Date(1960, 1, 2, 3, 4, 5, 999999999, time.UTC)
+	vector := []time.Time{key, value}
+	_, id, err := table.InsertRow(key)
+	if err != nil {
+		t.Fatalf("Table.InsertRow() failed: %v", err)
+	}
+	if err := table.SetValue("Value", id, value); err != nil {
+		t.Fatalf("Table.SetValue() failed: %v", err)
+	}
+	if err := table.SetValue("Vector", id, vector); err != nil {
+		t.Fatalf("Table.SetValue() failed: %v", err)
+	}
+	storedKey, err := table.GetValue("_key", id)
+	if err != nil {
+		t.Fatalf("Table.GetValue() failed: %v", err)
+	}
+	if storedKey != key.UnixNano()/1000 {
+		t.Fatalf("Table.GetValue() failed: key = %v, storedKey = %v",
+			key, storedKey)
+	}
+
+	db.SetParseTime(true)
+	for _, columnName := range []string{"_key", "Value"} {
+		want := key
+		if columnName == "Value" {
+			want = value
+		}
+		storedValue, err := table.GetValue(columnName, id)
+		if err != nil {
+			t.Fatalf("Table.GetValue() failed: %v", err)
+		}
+		if !storedValue.(time.Time).Equal(want.Truncate(time.Microsecond)) {
+			t.Fatalf("Table.GetValue() failed: value = %v, storedValue = %v",
+				want, storedValue)
+		}
+	}
+	storedVector, err := table.GetValue("Vector", id)
+	if err != nil {
+		t.Fatalf("Table.GetValue() failed: %v", err)
+	}
+	for i, storedTime := range storedVector.([]time.Time) {
+		if !storedTime.Equal(vector[i].Truncate(time.Microsecond)) {
+			t.Fatalf("Table.GetValue() failed: vector = %v, storedVector = %v",
+				vector, storedVector)
+		}
+	}
+
+	type TimeRec struct {
+		Key   time.Time `grngo:"_key"`
+		Value time.Time `grngo:"Value"`
+	}
+	next := key.Add(time.Hour)
+	if _, err := table.Load([]TimeRec{{next, value}}, nil); err != nil {
+		t.Fatalf("Table.Load() failed: %v", err)
+	}
+	id, found, err := table.FindRow(next)
+	if err != nil {
+		t.Fatalf("Table.FindRow() failed: %v", err)
+	}
+	if !found {
+		t.Fatalf("Table.FindRow() failed: key = %v", next)
+	}
+	storedValue, err := table.GetValue("Value", id)
+	if err != nil {
+		t.Fatalf("Table.GetValue() failed: %v", err)
+	}
+	if !storedValue.(time.Time).Equal(value.Truncate(time.Microsecond)) {
+		t.Fatalf("Table.GetValue() failed: value = %v, storedValue = %v",
+			value, storedValue)
+	}
+
+	column, err := table.CreateColumn("Int", "Int64", nil)
+	if err != nil {
+		t.Fatalf("Table.CreateColumn() failed: %v", err)
+	}
+	err = column.SetValue(id, value)
+	if !errors.Is(err, ErrInvalidArgument) ||
+		!strings.Contains(err.Error(), "valueType = Int64, type = <time.Time>") {
+		t.Fatalf("Column.SetValue() failed with an unexpected error: %v", err)
+	}
+}
+
+func TestGrnInitFin(t *testing.T) {
+	_, baseCount := GrnInitStatus()
+	var wg sync.WaitGroup
+	errs := make(chan error, 32)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := GrnInit(); err != nil {
+				errs <- err
+				return
+			}
+			errs <- GrnFin()
+		}()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dirPath, err := ioutil.TempDir("", "grngo_test")
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer os.RemoveAll(dirPath)
+			db, err := CreateDB(dirPath + "/db")
+			if err != nil {
+				errs <- err
+				return
+			}
+			errs <- db.Close()
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("GrnInit(), GrnFin(), CreateDB() or DB.Close() failed: %v", err)
+		}
+	}
+	initialized, count := GrnInitStatus()
+	if count != baseCount {
+		t.Fatalf("GrnInitStatus() returned a wrong count: count = %d, want %d",
+			count, baseCount)
+	}
+	if initialized != (baseCount != 0) {
+		t.Fatalf("GrnInitStatus() returned a wrong status: initialized = %v",
+			initialized)
+	}
+}
+
+func TestDB(t *testing.T) {
+	dirPath, dbPath, db := createTempDB(t)
+	defer os.RemoveAll(dirPath)
+	if err := db.Close(); err != nil {
+		t.Fatalf("DB.Close() failed: %v", err)
+	}
+	db, err := OpenDB(dbPath)
+	if err != nil {
+		t.Fatalf("OpenDB() failed: %v", err)
+	}
+	defer db.Close()
+	db2, err := OpenDB(dbPath)
+	if err != nil {
+		t.Fatalf("OpenDB() failed: %v", err)
+	}
+	defer db2.Close()
+}
+
+func TestDBPool(t *testing.T) {
+	dirPath, dbPath, db := createTempDB(t)
+	defer os.RemoveAll(dirPath)
+	options := NewTableOptions()
+	options.KeyType = "ShortText"
+	if _, err := db.CreateTable("Table", options); err != nil {
+		db.Close()
+		t.Fatalf("DB.CreateTable() failed: %v", err)
+	}
+	if _, err := db.CreateColumn("Table", "Value", "Int64", nil); err != nil {
+		db.Close()
+		t.Fatalf("DB.CreateColumn() failed: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("DB.Close() failed: %v", err)
+	}
+	pool, err := OpenDBPool(dbPath, 4)
+	if err != nil {
+		t.Fatalf("OpenDBPool() failed: %v", err)
+	}
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- pool.Do(func(db *DB) error {
+				key := []byte(strconv.Itoa(i))
+				_, id, err := db.InsertRow("Table", key)
+				if err != nil {
+					return err
+				}
+				if err := db.SetValue("Table", "Value", id, int64(i)); err != nil {
+					return err
+				}
+				value, err := db.GetValue("Table", "Value", id)
+				if err != nil {
+					return err
+				}
+				if value != int64(i) {
+					return fmt.Errorf("wrong value: value = %v, i = %d", value, i)
+				}
+				return nil
+			})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("DBPool.Do() failed: %v", err)
+		}
+	}
+	db, err = pool.Get()
+	if err != nil {
+		t.Fatalf("DBPool.Get() failed: %v", err)
+	}
+	if err := db.Close(); err == nil {
+		t.Fatalf("DB.Close() succeeded for a DB owned by DBPool")
+	}
+	if err := pool.Put(db); err != nil {
+		t.Fatalf("DBPool.Put() failed: %v", err)
+	}
+	if err := pool.Put(db); !errors.Is(err, ErrOperationNotPermitted) {
+		t.Fatalf("DBPool.Put() succeeded for a returned DB: %v", err)
+	}
+	db, err = pool.Get()
+	if err != nil {
+		t.Fatalf("DBPool.Get() failed: %v", err)
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		pool.Put(db)
+	}()
+	if err := pool.Close(); err != nil {
+		t.Fatalf("DBPool.Close() failed: %v", err)
+	}
+	if _, err := pool.Get(); err == nil {
+		t.Fatalf("DBPool.Get() succeeded for a closed DBPool")
+	}
+	if err := pool.Put(pool.dbs[1]); !errors.Is(err, ErrOperationNotPermitted) {
+		t.Fatalf("DBPool.Put() succeeded for a closed DBPool: %v", err)
+	}
+}
+
+func TestDBRefresh(t *testing.T) {
+	dirPath, _, db, _, _ := createTempColumn(t, "Table", nil, "Value", "Bool", nil)
+	defer removeTempDB(t, dirPath, db)
+	if _, err := db.Query("column_remove Table Value"); err != nil {
+		t.Fatalf("DB.Query() failed: %v", err)
+	}
+	if err := db.Refresh(); err != nil {
+		t.Fatalf("DB.Refresh() failed: %v", err)
+	}
+	if _, err := db.FindTable("Table"); err != nil {
+		t.Fatalf("DB.FindTable() failed: %v", err)
+	}
+	if _, err := db.FindColumn("Table", "Column"); err == nil {
+		t.Fatalf("DB.FindColumn() succeeded for deleted column")
+	}
+	if _, err := db.Query("table_remove Table"); err != nil {
+		t.Fatalf("DB.Query() failed: %v", err)
+	}
+	if err := db.Refresh(); err != nil {
+		t.Fatalf("DB.Refresh() failed: %v", err)
+	}
+	if _, err := db.FindTable("Table"); err == nil {
+		t.Fatalf("DB.FindTable() succeeded for deleted table")
+	}
+}
+
+func TestError(t *testing.T) {
+	dirPath, _, db, table, column :=
+		createTempColumn(t, "Table", nil, "Value", "Int32", nil)
+	defer removeTempDB(t, dirPath, db)
+	if _, err := OpenDB(dirPath + "/no_such_db"); err == nil {
+		t.Fatalf("OpenDB() succeeded for a non-existent database")
+	} else if !errors.Is(err, ErrNoSuchFileOrDirectory) {
+		t.Fatalf("OpenDB() failed with an unexpected error: %v", err)
+	}
+	err := column.SetValue(1, int64(100))
+	if err == nil {
+		t.Fatalf("Column.SetValue() succeeded for an invalid row")
+	}
+	if !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("Column.SetValue() failed with an unexpected error: %v", err)
+	}
+	var grnErr *Error
+	if !errors.As(err, &grnErr) {
+		t.Fatalf("errors.As() failed: err = %v", err)
+	}
+	if grnErr.RC != ErrInvalidArgument {
+		t.Fatalf("Error.RC is wrong: rc = %v", grnErr.RC)
+	}
+	if _, _, err := table.InsertRow(int64(100)); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("Table.InsertRow() failed with an unexpected error: %v", err)
+	}
+	if err := column.SetValue(1, "100"); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("Column.SetValue() failed with an unexpected error: %v", err)
+	}
+}
+
+func TestSchema(t *testing.T) {
+	dirPath, _, db := createTempDB(t)
+	defer removeTempDB(t, dirPath, db)
+	docsOptions := NewTableOptions()
+	docsOptions.KeyType = "ShortText"
+	docs, err := db.CreateTable("Docs", docsOptions)
+	if err != nil {
+		t.Fatalf("DB.CreateTable() failed: %v", err)
+	}
+	termsOptions := NewTableOptions()
+	termsOptions.Flags = TablePatKey
+	termsOptions.KeyType = "ShortText"
+	termsOptions.DefaultTokenizer = "TokenBigram"
+	termsOptions.Normalizer = "NormalizerAuto"
+	terms, err := db.CreateTable("Terms", termsOptions)
+	if err != nil {
+		t.Fatalf("DB.CreateTable() failed: %v", err)
+	}
+	if _, err := docs.CreateColumn("tags", "[]ShortText", nil); err != nil {
+		t.Fatalf("Table.CreateColumn() failed: %v", err)
+	}
+	titleOptions := NewColumnOptions()
+	titleOptions.Flags = CompressZlib
+	if _, err := docs.CreateColumn("title", "Text", titleOptions); err != nil {
+		t.Fatalf("Table.CreateColumn() failed: %v", err)
+	}
+	indexOptions := NewColumnOptions()
+	indexOptions.Flags = WithPosition
+	if _, err := terms.CreateColumn("index", "Docs.title", indexOptions); err != nil {
+		t.Fatalf("Table.CreateColumn() failed: %v", err)
+	}
+	tables, err := db.Tables()
+	if err != nil {
+		t.Fatalf("DB.Tables() failed: %v", err)
+	}
+	expectedTables := []*TableInfo{
+		{"Docs", TableOptions{Flags: TableHashKey, KeyType: "ShortText"}},
+		{"Terms", TableOptions{Flags: TablePatKey, KeyType: "ShortText",
+			DefaultTokenizer: "TokenBigram", Normalizer: "NormalizerAuto"}},
+	}
+	if !reflect.DeepEqual(tables, expectedTables) {
+		t.Fatalf("DB.Tables() failed: tables = %+v", tables)
+	}
+	columns, err := docs.Columns()
+	if err != nil {
+		t.Fatalf("Table.Columns() failed: %v", err)
+	}
+	expectedColumns := []*ColumnInfo{
+		{"tags", "Docs", VectorColumn, "ShortText", nil,
+			ColumnOptions{Flags: CompressNone}},
+		{"title", "Docs", ScalarColumn, "Text", nil,
+			ColumnOptions{Flags: CompressZlib}},
+	}
+	if !reflect.DeepEqual(columns, expectedColumns) {
+		t.Fatalf("Table.Columns() failed: columns = %+v", columns)
+	}
+	columns, err = terms.Columns()
+	if err != nil {
+		t.Fatalf("Table.Columns() failed: %v", err)
+	}
+	expectedColumns = []*ColumnInfo{
+		{"index", "Terms", IndexColumn, "Docs", []string{"title"},
+			ColumnOptions{Flags: WithPosition}},
+	}
+	if !reflect.DeepEqual(columns, expectedColumns) {
+		t.Fatalf("Table.Columns() failed: columns = %+v", columns)
+	}
+	if typeString := columns[0].TypeString(); typeString != "Docs.title" {
+		t.Fatalf("ColumnInfo.TypeString() failed: typeString = %s", typeString)
+	}
+}
+
+func testKeyValue(t *testing.T, db *DB, keyType, valueType string) bool {
+	options := NewTableOptions()
+	options.KeyType = keyType
+	options.ValueType = valueType
+	table, err := db.CreateTable("Table", options)
+	if err != nil {
+		t.Log("DB.CreateTable() failed:", err)
+		return false
+	}
+	defer db.Query("table_remove Table")
+	keyColumn, err := table.FindColumn("_key")
+	if (keyType == "") && (err == nil) {
+		t.Log("Table.FindColumn() succeeded for non-existent _key")
+		return false
+	}
+	if (keyType != "") && (err != nil) {
+		t.Log("Table.FindColumn() failed", err)
+		return false
+	}
+	valueColumn, err := table.FindColumn("_value")
+	if (valueType == "") && (err == nil) {
+		t.Log("Table.FindColumn() succeeded for non-existent _value")
+		return false
+	}
+	if (valueType != "") && (err != nil) {
+		t.Log("Table.FindColumn() failed:", err)
+		return false
+	}
+	for i := 0; i < 100; i++ {
+		key := generateRandomKey(keyType)
+		_, id, err := table.InsertRow(key)
+		if err != nil {
+			t.Log("Table.InsertRow() failed:", err)
+			return false
+		}
+		if keyColumn != nil {
+			storedKey, err := keyColumn.GetValue(id)
+			if err != nil {
+				t.Log("Column.GetValue() failed:", err)
+				return false
+			}
+			if !reflect.DeepEqual(key, storedKey) {
+				t.Logf("DeepEqual() failed: key = %v, storedKey = %v", key, storedKey)
+				return false
+			}
+		}
+		if valueColumn != nil {
+			value := generateRandomValue(valueType)
+			if err := valueColumn.SetValue(id, value); err != nil {
+				t.Log("Column.SetValue() failed:", err)
+				return false
+			}
+			storedValue, err := valueColumn.GetValue(id)
+			if err != nil {
+				t.Log("Column.GetValue() failed:", err)
+				return false
+			}
+			if !reflect.DeepEqual(value, storedValue) {
+				t.Logf("DeepEqual() failed: value = %v, storedValue = %v",
+					value, storedValue)
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestKeyValue(t *testing.T) {
+	dirPath, _, db := createTempDB(t)
+	defer removeTempDB(t, dirPath, db)
+	keyTypes := []string{
+		"", "Bool", "Int8", "Int16", "Int32", "Int64", "UInt8", "UInt16", "UInt32",
+		"UInt64", "Float", "Time", "ShortText", "TokyoGeoPoint", "WGS84GeoPoint",
+	}
+	valueTypes := []string{
+		"", "Bool", "Int8", "Int16", "Int32", "Int64", "UInt8", "UInt16", "UInt32",
+		"UInt64", "Float", "Time", "TokyoGeoPoint", "WGS84GeoPoint",
+	}
+	for _, keyType := range keyTypes {
+		for _, valueType := range valueTypes {
+			if !testKeyValue(t, db, keyType, valueType) {
+				t.Logf("[ fail ] keyType = \"%s\", valueType = \"%s\"",
+					keyType, valueType)
+				t.Fail()
+			}
+		}
+	}
+}
+
+func testRefKey(t *testing.T, db *DB, depth int, keyType string) bool {
+	for i := depth; i > 0; i-- {
+		tableName := fmt.Sprintf("Table%d", i)
+		options := NewTableOptions()
+		options.KeyType = keyType
+		_, err := db.CreateTable(tableName, options)
+		if err != nil {
+			t.Log("DB.CreateTable() failed:", err)
+			return false
+		}
+		defer db.Query(fmt.Sprintf("table_remove %s", tableName))
+	}
+	options := NewTableOptions()
+	options.KeyType = "Table1"
+	table, err := db.CreateTable("Table", options)
+	if err != nil {
+		t.Log("DB.CreateTable() failed:", err)
+		return false
+	}
+	defer db.Query("table_remove Table")
+	keyColumn, err := table.FindColumn("_key")
+	if err != nil {
+		t.Log("Table.FindColumn() failed:", err)
+		return false
+	}
+	for i := 0; i < 100; i++ {
+		key := generateRandomKey(keyType)
+		_, id, err := table.InsertRow(key)
+		if err != nil {
+			t.Log("Table.InsertRow() failed:", err)
+			return false
+		}
+		storedKey, err := keyColumn.GetValue(id)
+		if err != nil {
+			t.Log("Column.GetValue() failed:", err)
+			return false
+		}
+		if !reflect.DeepEqual(key, storedKey) {
+			t.Logf("DeepEqual() failed: key = %v, storedKey = %v", key, storedKey)
+			return false
+		}
+	}
+	return true
+}
+
+func TestRefKey(t *testing.T) {
+	dirPath, _, db := createTempDB(t)
+	defer removeTempDB(t, dirPath, db)
+	keyTypes := []string{
+		"Bool", "Int8", "Int16", "Int32", "Int64", "UInt8", "UInt16", "UInt32",
+		"UInt64", "Float", "Time", "ShortText", "TokyoGeoPoint", "WGS84GeoPoint",
+	}
+	maxDepth := 3
+	for depth := 1; depth <= maxDepth; depth++ {
+		for _, keyType := range keyTypes {
+			if !testRefKey(t, db, depth, keyType) {
+				t.Logf("[ fail ] depth = %d, keyType = \"%s\"", depth, keyType)
+				t.Fail()
+			}
+		}
+	}
+}
+
+func testFindRow(t *testing.T, db *DB, keyType string, refKey bool) bool {
+	options := NewTableOptions()
+	options.KeyType = keyType
+	table, err := db.CreateTable("Table", options)
+	if err != nil {
+		t.Log("DB.CreateTable() failed:", err)
+		return false
+	}
+	defer db.Query("table_remove Table")
+	if refKey {
+		options := NewTableOptions()
+		options.KeyType = "Table"
+		table, err = db.CreateTable("RefTable", options)
+		if err != nil {
+			t.Log("DB.CreateTable() failed:", err)
+			return false
+		}
+		defer db.Query("table_remove RefTable")
+	}
+	for i := 0; i < 100; i++ {
+		key := generateRandomKey(keyType)
+		id, found, err := table.FindRow(key)
+		if err != nil {
+			t.Log("Table.FindRow() failed:", err)
+			return false
+		}
+		inserted, insertedID, err := table.InsertRow(key)
+		if err != nil {
+			t.Log("Table.InsertRow() failed:", err)
+			return false
+		}
+		if found == inserted {
+			t.Logf("Table.FindRow() failed: found = %v, inserted = %v",
+				found, inserted)
+			return false
+		}
+		if found && (id != insertedID) {
+			t.Logf("Table.FindRow() failed: id = %d, insertedID = %d",
+				id, insertedID)
+			return false
+		}
+		id, found, err = table.FindRow(key)
+		if err != nil {
+			t.Log("Table.FindRow() failed:", err)
+			return false
+		}
+		if !found || (id != insertedID) {
+			t.Logf("Table.FindRow() failed: found = %v, id = %d, insertedID = %d",
+				found, id, insertedID)
+			return false
+		}
+	}
+	return true
+}
+
+func TestFindRow(t *testing.T) {
+	dirPath, _, db := createTempDB(t)
+	defer removeTempDB(t, dirPath, db)
+	keyTypes := []string{
+		"Bool", "Int8", "Int16", "Int32", "Int64", "UInt8", "UInt16", "UInt32",
+		"UInt64", "Float", "Time", "ShortText", "TokyoGeoPoint", "WGS84GeoPoint",
+	}
+	for _, keyType := range keyTypes {
+		for _, refKey := range []bool{false, true} {
+			if !testFindRow(t, db, keyType, refKey) {
+				t.Logf("[ fail ] keyType = \"%s\", refKey = %v", keyType, refKey)
+				t.Fail()
+			}
+		}
+	}
+	table, err := db.CreateTable("Table", nil)
+	if err != nil {
+		t.Fatalf("DB.CreateTable() failed: %v", err)
+	}
+	if _, _, err := table.FindRow(nil); err == nil {
+		t.Fatalf("Table.FindRow() succeeded for TABLE_NO_KEY")
+	}
+}
+
+func TestDeleteRow(t *testing.T) {
+	dirPath, _, db, table, column :=
+		createTempColumn(t, "Table", nil, "Value", "Int32", nil)
+	defer removeTempDB(t, dirPath, db)
+	for i := 0; i < 10; i++ {
+		if _, _, err := table.InsertRow(nil); err != nil {
+			t.Fatalf("Table.InsertRow() failed: %v", err)
+		}
+	}
+	for id := uint32(2); id <= 10; id += 2 {
+		for _, expected := range []bool{true, false} {
+			deleted, err := table.DeleteRow(id)
+			if err != nil {
+				t.Fatalf("Table.DeleteRow() failed: %v", err)
+			}
+			if deleted != expected {
+				t.Fatalf("Table.DeleteRow() failed: id = %d, deleted = %v", id, deleted)
+			}
+		}
+		if _, err := column.GetValue(id); err == nil {
+			t.Fatalf("Column.GetValue() succeeded for a deleted row")
+		}
+	}
+	if _, err := table.DeleteRowByKey(nil); err == nil {
+		t.Fatalf("Table.DeleteRowByKey() succeeded for TABLE_NO_KEY")
+	}
+
+	options := NewTableOptions()
+	options.KeyType = "ShortText"
+	if _, err := db.CreateTable("Keys", options); err != nil {
+		t.Fatalf("DB.CreateTable() failed: %v", err)
+	}
+	options.KeyType = "Keys"
+	refTable, err := db.CreateTable("RefTable", options)
+	if err != nil {
+		t.Fatalf("DB.CreateTable() failed: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		if _, _, err := refTable.InsertRow([]byte(strconv.Itoa(i))); err != nil {
+			t.Fatalf("Table.InsertRow() failed: %v", err)
+		}
+	}
+	for i := 0; i < 10; i += 2 {
+		key := []byte(strconv.Itoa(i))
+		for _, expected := range []bool{true, false} {
+			deleted, err := refTable.DeleteRowByKey(key)
+			if err != nil {
+				t.Fatalf("Table.DeleteRowByKey() failed: %v", err)
+			}
+			if deleted != expected {
+				t.Fatalf("Table.DeleteRowByKey() failed: key = %s, deleted = %v",
+					key, deleted)
+			}
+		}
+		if _, found, _ := refTable.FindRow(key); found {
+			t.Fatalf("Table.FindRow() found a deleted row: key = %s", key)
+		}
+		if _, found, _ := db.FindRow("Keys", key); !found {
+			t.Fatalf("DB.FindRow() failed to find a referred row: key = %s", key)
+		}
+	}
+}
+
+func testColumn(t *testing.T, table *Table, valueType string, ids []uint32) bool {
+	columnName := valueType
+	if strings.HasPrefix(valueType, "[]") {
+		columnName = valueType[2:] + "Vector"
+	}
+	columnName += "Value"
+	column, err := table.CreateColumn(columnName, valueType, nil)
+	if err != nil {
+		t.Log("Table.CreateColumn() failed:", err)
+		return false
+	}
+	for _, id := range ids {
+		value := generateRandomValue(valueType)
+		if err := column.SetValue(id, value); err != nil {
+			t.Log("Column.SetValue() failed:", err)
+			return false
+		}
+		storedValue, err := column.GetValue(id)
+		if err != nil {
+			t.Log("Column.GetValue() failed:", err)
+			return false
+		}
+		if !reflect.DeepEqual(value, storedValue) {
+			t.Logf("DeepEqual() failed: value = %v, storedValue = %v",
+				value, storedValue)
+			return false
+		}
+	}
+	return true
+}
+
+/*
+func TestColumn(t *testing.T) {
+	dirPath, _, db, table := createTempTable(t, "Table", nil)
+	defer removeTempDB(t, dirPath, db)
+	valueTypes := []string{
+		"Bool", "Int8", "Int16", "Int32", "Int64", "UInt8", "UInt16", "UInt32",
+		"UInt64", "Float", "Time", "ShortText", "Text", "LongText",
 		"TokyoGeoPoint", "WGS84GeoPoint",
 		"[]Bool", "[]Int8", "[]Int16", "[]Int32", "[]Int64", "[]UInt8", "[]UInt16",
 		"[]UInt32", "[]UInt64", "[]Float", "[]Time", "[]ShortText", "[]Text",
 		"[]LongText", "[]TokyoGeoPoint", "[]WGS84GeoPoint",
 	}
-	ids := make([]uint32, 100)
-	for i := 0; i < len(ids); i++ {
-		inserted, id, err := table.InsertRow(nil)
+	ids := make([]uint32, 100)
+	for i := 0; i < len(ids); i++ {
+		inserted, id, err := table.InsertRow(nil)
+		if err != nil {
+			t.Fatalf("Table.InsertRow() failed: %v", err)
+		}
+		if !inserted {
+			t.Fatalf("Table.InsertRow() failed")
+		}
+		ids[i] = id
+	}
+	for _, valueType := range valueTypes {
+		if !testColumn(t, table, valueType, ids) {
+			t.Logf("[ fail ] valueType = \"%s\"", valueType)
+			t.Fail()
+		}
+	}
+}
+*/
+
+func testRefColumn(t *testing.T, db *DB, keyType, refType string) bool {
+	// Create a referred table.
+	options := NewTableOptions()
+	options.KeyType = keyType
+	_, err := db.CreateTable("Table", options)
+	if err != nil {
+		t.Log("DB.CreateTable() failed:", err)
+		return false
+	}
+	defer db.Query("table_remove Table")
+
+	// Create a referrer table.
+	refTable, err := db.CreateTable("RefTable", nil)
+	if err != nil {
+		t.Log("DB.CreateTable() failed:", err)
+		return false
+	}
+	defer db.Query("table_remove RefTable")
+	refColumn, err := refTable.CreateColumn("Ref", refType, nil)
+	if err != nil {
+		t.Log("Table.CreateColumn() failed:", err)
+		return false
+	}
+	valueType := strings.Replace(refType, "Table", keyType, 1)
+
+	for i := 0; i < 10; i++ {
+		_, id, err := refTable.InsertRow(nil)
+		if err != nil {
+			t.Log("Table.InsertRow() failed:", err)
+			return false
+		}
+		value := generateRandomValue(valueType)
+		if err := refColumn.SetValue(id, value); err != nil {
+			t.Log("Column.SetValue() failed:", err)
+			return false
+		}
+		storedValue, err := refColumn.GetValue(id)
+		if err != nil {
+			t.Log("Column.GetValue() failed:", err)
+			return false
+		}
+		if !reflect.DeepEqual(value, storedValue) {
+			t.Logf("DeepEqual() failed: value = %v, storedValue = %v",
+				value, storedValue)
+			return false
+		}
+	}
+	return true
+}
+
+func TestRefColumn(t *testing.T) {
+	dirPath, _, db := createTempDB(t)
+	defer removeTempDB(t, dirPath, db)
+	keyTypes := []string{
+		"Bool", "Int8", "Int16", "Int32", "Int64", "UInt8", "UInt16", "UInt32",
+		"UInt64", "Float", "Time", "ShortText",
+		//"TokyoGeoPoint", "WGS84GeoPoint",
+	}
+	refTypes := []string{"Table"}
+	//refTypes := []string{ "Table", "[]Table" }
+	for _, keyType := range keyTypes {
+		for _, refType := range refTypes {
+			if !testRefColumn(t, db, keyType, refType) {
+				t.Logf("[ fail ] keyType = \"%s\", refType = \"%s\"", keyType, refType)
+				t.Fail()
+			}
+		}
+	}
+}
+
+func readCursorKeys(t *testing.T, cursor *Cursor) []string {
+	defer cursor.Close()
+	var keys []string
+	for {
+		id, err := cursor.Next()
+		if err != nil {
+			t.Fatalf("Cursor.Next() failed: %v", err)
+		}
+		if id == NilID {
+			return keys
+		}
+		key, err := cursor.Key()
+		if err != nil {
+			t.Fatalf("Cursor.Key() failed: %v", err)
+		}
+		keys = append(keys, string(key.([]byte)))
+	}
+}
+
+func TestCursor(t *testing.T) {
+	options := NewTableOptions()
+	options.Flags = TablePatKey
+	options.KeyType = "ShortText"
+	dirPath, _, db, table := createTempTable(t, "Table", options)
+	defer removeTempDB(t, dirPath, db)
+	for _, key := range []string{"b", "abc", "c", "a", "ab"} {
+		if _, _, err := table.InsertRow([]byte(key)); err != nil {
+			t.Fatalf("Table.InsertRow() failed: %v", err)
+		}
+	}
+	tests := []struct {
+		setup func(options *CursorOptions)
+		keys  []string
+	}{
+		{func(options *CursorOptions) {}, []string{"a", "ab", "abc", "b", "c"}},
+		{func(options *CursorOptions) {
+			options.Flags |= CursorDescending
+		}, []string{"c", "b", "abc", "ab", "a"}},
+		{func(options *CursorOptions) {
+			options.Flags |= CursorByID
+		}, []string{"b", "abc", "c", "a", "ab"}},
+		{func(options *CursorOptions) {
+			options.Min = []byte("ab")
+			options.Max = []byte("b")
+		}, []string{"ab", "abc", "b"}},
+		{func(options *CursorOptions) {
+			options.Flags |= CursorGT | CursorLT
+			options.Min = []byte("ab")
+			options.Max = []byte("b")
+		}, []string{"abc"}},
+		{func(options *CursorOptions) {
+			options.Flags |= CursorPrefix
+			options.Min = []byte("ab")
+		}, []string{"ab", "abc"}},
+		{func(options *CursorOptions) {
+			options.Offset = 1
+			options.Limit = 2
+		}, []string{"ab", "abc"}},
+	}
+	for i, test := range tests {
+		cursorOptions := NewCursorOptions()
+		test.setup(cursorOptions)
+		cursor, err := table.Cursor(cursorOptions)
+		if err != nil {
+			t.Fatalf("Table.Cursor() failed: %v", err)
+		}
+		keys := readCursorKeys(t, cursor)
+		if !reflect.DeepEqual(keys, test.keys) {
+			t.Errorf("Table.Cursor() failed: i = %d, keys = %v, want %v",
+				i, keys, test.keys)
+		}
+	}
+	cursorOptions := NewCursorOptions()
+	cursorOptions.Min = int64(1)
+	if _, err := table.Cursor(cursorOptions); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("Table.Cursor() failed with an unexpected error: %v", err)
+	}
+	// Cursor and InsertRow share the ShortText key size limit.
+	for _, size := range []int{4094, 4095} {
+		key := bytes.Repeat([]byte("x"), size)
+		cursorOptions := NewCursorOptions()
+		cursorOptions.Min = key
+		cursor, cursorErr := table.Cursor(cursorOptions)
+		if cursorErr == nil {
+			cursor.Close()
+		}
+		_, _, insertErr := table.InsertRow(key)
+		if (cursorErr == nil) != (insertErr == nil) || (cursorErr == nil) != (size < 4095) {
+			t.Fatalf("Table.Cursor() and Table.InsertRow() disagree: size = %d, errs = %v, %v",
+				size, cursorErr, insertErr)
+		}
+	}
+}
+
+func TestInvalidRows(t *testing.T) {
+	dirPath, _, db, table, column :=
+		createTempColumn(t, "Table", nil, "Value", "Int32", nil)
+	defer removeTempDB(t, dirPath, db)
+	for id := uint32(1); id <= 100; id++ {
+		if _, err := column.GetValue(id); err == nil {
+			t.Fatalf("Column.GetValue() succeeded for an invalid row")
+		}
+		if err := column.SetValue(id, int64(id)+100); err == nil {
+			t.Fatalf("Column.SetValue() succeeded for an invalid row")
+		}
+	}
+	for id := uint32(1); id <= 100; id++ {
+		_, _, err := table.InsertRow(nil)
+		if err != nil {
+			t.Fatalf("Table.InsertRow() failed: %v", err)
+		}
+	}
+	for id := uint32(2); id <= 100; id += 2 {
+		_, err := db.Query("delete Table --id " + strconv.Itoa(int(id)))
+		if err != nil {
+			t.Fatalf("DB.Query() failed: %v", err)
+		}
+	}
+	for id := uint32(1); id <= 100; id++ {
+		if (id % 2) == 0 {
+			if _, err := column.GetValue(id); err == nil {
+				t.Fatalf("Column.GetValue() succeeded for an invalid row")
+			}
+			if err := column.SetValue(id, int64(id)+200); err == nil {
+				t.Fatalf("Column.SetValue() succeeded for an invalid row")
+			}
+		} else {
+			if _, err := column.GetValue(id); err != nil {
+				t.Fatalf("Column.GetValue() failed: %v", err)
+			}
+			if err := column.SetValue(id, int64(id)+200); err != nil {
+				t.Fatalf("Column.GetValue() failed: %v", err)
+			}
+		}
+	}
+}
+
+func TestUInt64(t *testing.T) {
+	options := NewTableOptions()
+	options.KeyType = "UInt64"
+	dirPath, _, db, table := createTempTable(t, "Table", options)
+	defer removeTempDB(t, dirPath, db)
+	if _, err := table.CreateColumn("Value", "UInt64", nil); err != nil {
+		t.Fatalf("Table.CreateColumn() failed: %v", err)
+	}
+	if _, err := table.CreateColumn("Vector", "[]UInt64", nil); err != nil {
+		t.Fatalf("Table.CreateColumn() failed: %v", err)
+	}
+	key := uint64(math.MaxUint64)
+	value := uint64(math.MaxInt64) + 1
+	vector := []uint64{0, value, key}
+	_, id, err := table.InsertRow(key)
+	if err != nil {
+		t.Fatalf("Table.InsertRow() failed: %v", err)
+	}
+	if err := table.SetValue("Value", id, value); err != nil {
+		t.Fatalf("Table.SetValue() failed: %v", err)
+	}
+	if err := table.SetValue("Vector", id, vector); err != nil {
+		t.Fatalf("Table.SetValue() failed: %v", err)
+	}
+	expected := map[string]interface{}{
+		"_key": key, "Value": value, "Vector": vector,
+	}
+	for columnName, want := range expected {
+		storedValue, err := table.GetValue(columnName, id)
+		if err != nil {
+			t.Fatalf("Table.GetValue() failed: %v", err)
+		}
+		if !reflect.DeepEqual(storedValue, want) {
+			t.Fatalf("Table.GetValue() failed: column = %s, value = %v, storedValue = %v",
+				columnName, want, storedValue)
+		}
+	}
+	if foundID, found, err := table.FindRow(key); (err != nil) || !found || (foundID != id) {
+		t.Fatalf("Table.FindRow() failed: id = %d, found = %v, err = %v",
+			foundID, found, err)
+	}
+
+	column, err := table.CreateColumn("Signed", "Int64", nil)
+	if err != nil {
+		t.Fatalf("Table.CreateColumn() failed: %v", err)
+	}
+	if err := column.SetValue(id, value); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("Column.SetValue() failed: err = %v", err)
+	}
+}
+
+func TestRef(t *testing.T) {
+	dirPath, _, db := createTempDB(t)
+	defer removeTempDB(t, dirPath, db)
+	options := NewTableOptions()
+	options.KeyType = "ShortText"
+	table, err := db.CreateTable("Table", options)
+	if err != nil {
+		t.Fatalf("DB.CreateTable() failed: %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		key := []byte(strconv.Itoa(i))
+		if _, _, err := table.InsertRow(key); err != nil {
+			t.Fatalf("Table.InsertRow() failed: %v", err)
+		}
+	}
+	column, err := table.CreateColumn("Ref", "Table", nil)
+	for i := 0; i < 100; i++ {
+		id := uint32(i + 1)
+		value := []byte(strconv.Itoa((i + 1) % 100))
+		if err := column.SetValue(id, value); err != nil {
+			t.Fatalf("Column.SetValue() failed: %v", err)
+		}
+		storedValue, err := column.GetValue(id)
+		if err != nil {
+			t.Fatalf("Column.GetValue() failed: %v", err)
+		}
+		if !reflect.DeepEqual(value, storedValue) {
+			t.Fatalf("Column.GetValue() failed: value = %v, storedValue = %v",
+				value, storedValue)
+		}
+	}
+	column, err = table.FindColumn("Ref._key")
+	for i := 0; i < 100; i++ {
+		id := uint32(i + 1)
+		value := []byte(strconv.Itoa((i + 1) % 100))
+		storedValue, err := column.GetValue(id)
+		if err != nil {
+			t.Fatalf("Column.GetValue() failed: %v", err)
+		}
+		if !reflect.DeepEqual(value, storedValue) {
+			t.Fatalf("Column.GetValue() failed: value = %v, storedValue = %v",
+				value, storedValue)
+		}
+	}
+	column, err = table.FindColumn("Ref.Ref.Ref")
+	for i := 0; i < 100; i++ {
+		id := uint32(i + 1)
+		value := []byte(strconv.Itoa((i + 3) % 100))
+		storedValue, err := column.GetValue(id)
+		if err != nil {
+			t.Fatalf("Column.GetValue() failed: %v", err)
+		}
+		if !reflect.DeepEqual(value, storedValue) {
+			t.Fatalf("Column.GetValue() failed: value = %v, storedValue = %v",
+				value, storedValue)
+		}
+	}
+}
+
+func TestGetValues(t *testing.T) {
+	options := NewTableOptions()
+	options.KeyType = "ShortText"
+	dirPath, _, db, table := createTempTable(t, "Table", options)
+	defer removeTempDB(t, dirPath, db)
+	ids := make([]uint32, 100)
+	for i := range ids {
+		_, id, err := table.InsertRow([]byte(strconv.Itoa(i)))
+		if err != nil {
+			t.Fatalf("Table.InsertRow() failed: %v", err)
+		}
+		ids[i] = id
+	}
+	valueColumn, err := table.CreateColumn("Value", "Int32", nil)
+	if err != nil {
+		t.Fatalf("Table.CreateColumn() failed: %v", err)
+	}
+	vectorColumn, err := table.CreateColumn("Vector", "[]ShortText", nil)
+	if err != nil {
+		t.Fatalf("Table.CreateColumn() failed: %v", err)
+	}
+	refColumn, err := table.CreateColumn("Ref", "Table", nil)
+	if err != nil {
+		t.Fatalf("Table.CreateColumn() failed: %v", err)
+	}
+	for i, id := range ids {
+		if err := valueColumn.SetValue(id, int64(i*10)); err != nil {
+			t.Fatalf("Column.SetValue() failed: %v", err)
+		}
+		if err := vectorColumn.SetValue(id, generateRandomVector("ShortText")); err != nil {
+			t.Fatalf("Column.SetValue() failed: %v", err)
+		}
+		if err := refColumn.SetValue(id, []byte(strconv.Itoa((i+1)%len(ids)))); err != nil {
+			t.Fatalf("Column.SetValue() failed: %v", err)
+		}
+	}
+	rand.Shuffle(len(ids), func(i, j int) { ids[i], ids[j] = ids[j], ids[i] })
+	for _, columnName := range []string{"_key", "Value", "Vector", "Ref", "Ref.Ref.Value"} {
+		column, err := table.FindColumn(columnName)
+		if err != nil {
+			t.Fatalf("Table.FindColumn() failed: %v", err)
+		}
+		values, err := column.GetValues(ids)
+		if err != nil {
+			t.Fatalf("Column.GetValues() failed: %v", err)
+		}
+		valuesValue := reflect.ValueOf(values)
+		if valuesValue.Len() != len(ids) {
+			t.Fatalf("Column.GetValues() failed: len = %d", valuesValue.Len())
+		}
+		for i, id := range ids {
+			value, err := column.GetValue(id)
+			if err != nil {
+				t.Fatalf("Column.GetValue() failed: %v", err)
+			}
+			if !reflect.DeepEqual(value, valuesValue.Index(i).Interface()) {
+				t.Fatalf("Column.GetValues() failed: column = %s, value = %v, values[%d] = %v",
+					columnName, value, i, valuesValue.Index(i).Interface())
+			}
+		}
+	}
+	if _, err := valueColumn.GetValues([]uint32{1, 1000}); err == nil {
+		t.Fatalf("Column.GetValues() succeeded for an invalid row")
+	}
+}
+
+func TestSetValues(t *testing.T) {
+	dirPath, _, db, table := createTempTable(t, "Table", nil)
+	defer removeTempDB(t, dirPath, db)
+	ids := make([]uint32, 10)
+	for i := range ids {
+		_, id, err := table.InsertRow(nil)
+		if err != nil {
+			t.Fatalf("Table.InsertRow() failed: %v", err)
+		}
+		ids[i] = id
+	}
+	valueColumn, err := table.CreateColumn("Value", "Int8", nil)
+	if err != nil {
+		t.Fatalf("Table.CreateColumn() failed: %v", err)
+	}
+	vectorColumn, err := table.CreateColumn("Vector", "[]Text", nil)
+	if err != nil {
+		t.Fatalf("Table.CreateColumn() failed: %v", err)
+	}
+	values := make([]int64, len(ids))
+	vectors := make([][][]byte, len(ids))
+	for i := range ids {
+		values[i] = int64(i * 10)
+		vectors[i] = generateRandomVector("Text").([][]byte)
+	}
+	if err := valueColumn.SetValues(ids, values); err != nil {
+		t.Fatalf("Column.SetValues() failed: %v", err)
+	}
+	if err := vectorColumn.SetValues(ids, vectors); err != nil {
+		t.Fatalf("Column.SetValues() failed: %v", err)
+	}
+	storedValues, err := valueColumn.GetValues(ids)
+	if err != nil {
+		t.Fatalf("Column.GetValues() failed: %v", err)
+	}
+	if !reflect.DeepEqual(storedValues, values) {
+		t.Fatalf("Column.GetValues() failed: values = %v, want = %v",
+			storedValues, values)
+	}
+	storedVectors, err := vectorColumn.GetValues(ids)
+	if err != nil {
+		t.Fatalf("Column.GetValues() failed: %v", err)
+	}
+	if !reflect.DeepEqual(storedVectors, vectors) {
+		t.Fatalf("Column.GetValues() failed: vectors = %v, want = %v",
+			storedVectors, vectors)
+	}
+
+	values[5] = 1000
+	err = valueColumn.SetValues(ids, values)
+	var rowErr *RowError
+	if !errors.As(err, &rowErr) {
+		t.Fatalf("Column.SetValues() failed: err = %v", err)
+	}
+	if (rowErr.Index != 5) || (rowErr.ID != ids[5]) {
+		t.Fatalf("Column.SetValues() failed: index = %d, id = %d",
+			rowErr.Index, rowErr.ID)
+	}
+	if !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("Column.SetValues() failed: err = %v", err)
+	}
+	if err := valueColumn.SetValues(ids[:1], values); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("Column.SetValues() failed: err = %v", err)
+	}
+}
+
+func TestWeightVector(t *testing.T) {
+	options := NewColumnOptions()
+	options.Flags |= WithWeight
+	dirPath, _, db, table, column :=
+		createTempColumn(t, "Table", nil, "Tags", "[]ShortText", options)
+	defer removeTempDB(t, dirPath, db)
+	_, id, err := table.InsertRow(nil)
+	if err != nil {
+		t.Fatalf("Table.InsertRow() failed: %v", err)
+	}
+	value := []WeightedText{{[]byte("Groonga"), 2}, {[]byte("Go"), 10}}
+	if err := column.SetValue(id, value); err != nil {
+		t.Fatalf("Column.SetValue() failed: %v", err)
+	}
+	storedValue, err := column.GetValue(id)
+	if err != nil {
+		t.Fatalf("Column.GetValue() failed: %v", err)
+	}
+	if !reflect.DeepEqual(value, storedValue) {
+		t.Fatalf("Column.GetValue() failed: value = %v, storedValue = %v",
+			value, storedValue)
+	}
+	s := NewSelect("Table")
+	s.OutputColumns = "Tags"
+	for _, outputType := range []OutputType{OutputJSON, OutputMessagePack} {
+		db.SetOutputType(outputType)
+		result, err := db.Select(s)
+		if err != nil {
+			t.Fatalf("DB.Select() failed: %v", err)
+		}
+		if (len(result.Rows) != 1) || !reflect.DeepEqual(result.Rows[0][0], value) {
+			t.Fatalf("DB.Select() failed: outputType = %s, rows = %v",
+				outputType, result.Rows)
+		}
+	}
+	db.SetOutputType(OutputJSON)
+	type TagRec struct {
+		Tags []WeightedText `grngo:"Tags"`
+	}
+	value = []WeightedText{{[]byte("C\"Go\""), 3}}
+	if _, err := table.Load(&TagRec{value}, nil); err != nil {
+		t.Fatalf("Table.Load() failed: %v", err)
+	}
+	storedValue, err = column.GetValue(id + 1)
+	if err != nil {
+		t.Fatalf("Column.GetValue() failed: %v", err)
+	}
+	if !reflect.DeepEqual(value, storedValue) {
+		t.Fatalf("Column.GetValue() failed: value = %v, storedValue = %v",
+			value, storedValue)
+	}
+}
+
+func TestRefWeightVector(t *testing.T) {
+	tagsOptions := NewTableOptions()
+	tagsOptions.KeyType = "ShortText"
+	dirPath, _, db, tags := createTempTable(t, "Tags", tagsOptions)
+	defer removeTempDB(t, dirPath, db)
+	table, err := db.CreateTable("Table", nil)
+	if err != nil {
+		t.Fatalf("DB.CreateTable() failed: %v", err)
+	}
+	options := NewColumnOptions()
+	options.Flags |= WithWeight
+	column, err := table.CreateColumn("Tags", "[]Tags", options)
+	if err != nil {
+		t.Fatalf("Table.CreateColumn() failed: %v", err)
+	}
+	ranks, err := table.CreateColumn("Ranks", "[]Int32", options)
+	if err != nil {
+		t.Fatalf("Table.CreateColumn() failed: %v", err)
+	}
+	_, id, err := table.InsertRow(nil)
+	if err != nil {
+		t.Fatalf("Table.InsertRow() failed: %v", err)
+	}
+	tests := []struct {
+		column *Column
+		value  interface{}
+		want   interface{}
+	}{
+		{column, []WeightedText{{[]byte("Groonga"), 2}, {[]byte("Go"), 10}},
+			[]WeightedText{{[]byte("Groonga"), 2}, {[]byte("Go"), 10}}},
+		{column, []Weighted[uint32]{{2, 5}, {1, 0}},
+			[]WeightedText{{[]byte("Go"), 5}, {[]byte("Groonga"), 0}}},
+		{ranks, []Weighted[int64]{{-1, 3}, {7, 0}},
+			[]Weighted[int64]{{-1, 3}, {7, 0}}},
+	}
+	for i, test := range tests {
+		if err := test.column.SetValue(id, test.value); err != nil {
+			t.Fatalf("Column.SetValue() failed: i = %d, err = %v", i, err)
+		}
+		storedValue, err := test.column.GetValue(id)
 		if err != nil {
-			t.Fatalf("Table.InsertRow() failed: %v", err)
+			t.Fatalf("Column.GetValue() failed: %v", err)
 		}
-		if !inserted {
-			t.Fatalf("Table.InsertRow() failed")
+		if !reflect.DeepEqual(storedValue, test.want) {
+			t.Fatalf("Column.GetValue() failed: i = %d, storedValue = %v, want %v",
+				i, storedValue, test.want)
 		}
-		ids[i] = id
 	}
-	for _, valueType := range valueTypes {
-		if !testColumn(t, table, valueType, ids) {
-			t.Logf("[ fail ] valueType = \"%s\"", valueType)
-			t.Fail()
-		}
+	if err := ranks.SetValue(id, []Weighted[int64]{{math.MaxInt32 + 1, 1}}); err == nil {
+		t.Fatalf("Column.SetValue() succeeded for an out of range value")
+	}
+	if _, found, err := tags.FindRow([]byte("Go")); err != nil || !found {
+		t.Fatalf("Table.FindRow() failed: found = %v, err = %v", found, err)
 	}
-}
-*/
 
-func testRefColumn(t *testing.T, db *DB, keyType, refType string) bool {
-	// Create a referred table.
-	options := NewTableOptions()
-	options.KeyType = keyType
-	_, err := db.CreateTable("Table", options)
+	type TagRec struct {
+		Tags  []WeightedText    `grngo:"Tags"`
+		Ranks []Weighted[int64] `grngo:"Ranks"`
+	}
+	rec := TagRec{
+		Tags:  []WeightedText{{[]byte("Mroonga"), 4}},
+		Ranks: []Weighted[int64]{{100, 1}},
+	}
+	if _, err := table.Load(&rec, nil); err != nil {
+		t.Fatalf("Table.Load() failed: %v", err)
+	}
+	values, err := column.GetValues([]uint32{id, id + 1})
 	if err != nil {
-		t.Log("DB.CreateTable() failed:", err)
-		return false
+		t.Fatalf("Column.GetValues() failed: %v", err)
 	}
-	defer db.Query("table_remove Table")
+	want := [][]WeightedText{
+		{{[]byte("Go"), 5}, {[]byte("Groonga"), 0}}, rec.Tags,
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Fatalf("Column.GetValues() failed: values = %v, want %v", values, want)
+	}
+	storedRanks, err := ranks.GetValue(id + 1)
+	if err != nil {
+		t.Fatalf("Column.GetValue() failed: %v", err)
+	}
+	if !reflect.DeepEqual(storedRanks, rec.Ranks) {
+		t.Fatalf("Column.GetValue() failed: storedRanks = %v, want %v",
+			storedRanks, rec.Ranks)
+	}
+}
 
-	// Create a referrer table.
-	refTable, err := db.CreateTable("RefTable", nil)
+func TestSearch(t *testing.T) {
+	dirPath, _, db := createTempDB(t)
+	defer removeTempDB(t, dirPath, db)
+	docsOptions := NewTableOptions()
+	docsOptions.KeyType = "ShortText"
+	docs, err := db.CreateTable("Docs", docsOptions)
 	if err != nil {
-		t.Log("DB.CreateTable() failed:", err)
-		return false
+		t.Fatalf("DB.CreateTable() failed: %v", err)
 	}
-	defer db.Query("table_remove RefTable")
-	refColumn, err := refTable.CreateColumn("Ref", refType, nil)
+	termsOptions := NewTableOptions()
+	termsOptions.Flags = TablePatKey
+	termsOptions.KeyType = "ShortText"
+	termsOptions.DefaultTokenizer = "TokenBigram"
+	termsOptions.Normalizer = "NormalizerAuto"
+	terms, err := db.CreateTable("Terms", termsOptions)
 	if err != nil {
-		t.Log("Table.CreateColumn() failed:", err)
-		return false
+		t.Fatalf("DB.CreateTable() failed: %v", err)
 	}
-	valueType := strings.Replace(refType, "Table", keyType, 1)
+	if _, err := docs.CreateColumn("title", "Text", nil); err != nil {
+		t.Fatalf("Table.CreateColumn() failed: %v", err)
+	}
+	indexOptions := NewColumnOptions()
+	indexOptions.Flags = WithPosition
+	if _, err := terms.CreateColumn("Docs_title", "Docs.title", indexOptions); err != nil {
+		t.Fatalf("Table.CreateColumn() failed: %v", err)
+	}
+	titles := []string{
+		"Groonga is a full-text search engine",
+		"Grngo is a Groonga binding for Go",
+		"Go is a programming language",
+	}
+	for i, title := range titles {
+		_, id, err := docs.InsertRow([]byte(strconv.Itoa(i)))
+		if err != nil {
+			t.Fatalf("Table.InsertRow() failed: %v", err)
+		}
+		if err := docs.SetValue("title", id, []byte(title)); err != nil {
+			t.Fatalf("Table.SetValue() failed: %v", err)
+		}
+	}
+	hits, err := docs.Search("Terms.Docs_title", "groonga", nil)
+	if err != nil {
+		t.Fatalf("Table.Search() failed: %v", err)
+	}
+	if (len(hits) != 2) || (hits[0].Score <= 0) ||
+		(hits[0].Score == hits[1].Score && hits[0].ID > hits[1].ID) {
+		t.Fatalf("Table.Search() failed: hits = %v", hits)
+	}
+	options := NewSearchOptions()
+	options.Offset = 1
+	options.Limit = 1
+	window, err := docs.Search("Terms.Docs_title", "groonga", options)
+	if err != nil {
+		t.Fatalf("Table.Search() failed: %v", err)
+	}
+	if !reflect.DeepEqual(window, hits[1:]) {
+		t.Fatalf("Table.Search() failed: window = %v, hits = %v", window, hits)
+	}
+	options.Offset = 2
+	window, err = docs.Search("Terms.Docs_title", "groonga", options)
+	if err != nil {
+		t.Fatalf("Table.Search() failed: %v", err)
+	}
+	if len(window) != 0 {
+		t.Fatalf("Table.Search() failed: window = %v", window)
+	}
+	options = NewSearchOptions()
+	options.Mode = SearchPrefix
+	options.Limit = 1
+	hits, err = docs.Search("Terms.Docs_title", "program", options)
+	if err != nil {
+		t.Fatalf("Table.Search() failed: %v", err)
+	}
+	if (len(hits) != 1) || (hits[0].ID != 3) {
+		t.Fatalf("Table.Search() failed: hits = %v", hits)
+	}
+	if _, err := docs.Search("Docs.title", "go", nil); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("Table.Search() failed: err = %v", err)
+	}
+}
 
+func TestSelect(t *testing.T) {
+	options := NewTableOptions()
+	options.KeyType = "ShortText"
+	dirPath, _, db, table := createTempTable(t, "Table", options)
+	defer removeTempDB(t, dirPath, db)
+	if _, err := table.CreateColumn("Value", "Int32", nil); err != nil {
+		t.Fatalf("Table.CreateColumn() failed: %v", err)
+	}
+	if _, err := table.CreateColumn("Category", "ShortText", nil); err != nil {
+		t.Fatalf("Table.CreateColumn() failed: %v", err)
+	}
+	if _, err := table.CreateColumn("Tags", "[]ShortText", nil); err != nil {
+		t.Fatalf("Table.CreateColumn() failed: %v", err)
+	}
 	for i := 0; i < 10; i++ {
-		_, id, err := refTable.InsertRow(nil)
+		_, id, err := table.InsertRow([]byte(strconv.Itoa(i)))
 		if err != nil {
-			t.Log("Table.InsertRow() failed:", err)
-			return false
+			t.Fatalf("Table.InsertRow() failed: %v", err)
 		}
-		value := generateRandomValue(valueType)
-		if err := refColumn.SetValue(id, value); err != nil {
-			t.Log("Column.SetValue() failed:", err)
-			return false
+		if err := table.SetValue("Value", id, int64(i*10)); err != nil {
+			t.Fatalf("Table.SetValue() failed: %v", err)
 		}
-		storedValue, err := refColumn.GetValue(id)
-		if err != nil {
-			t.Log("Column.GetValue() failed:", err)
-			return false
+		category := []byte("Even")
+		if (i % 2) == 1 {
+			category = []byte("Odd")
 		}
-		if !reflect.DeepEqual(value, storedValue) {
-			t.Logf("DeepEqual() failed: value = %v, storedValue = %v",
-				value, storedValue)
-			return false
+		if err := table.SetValue("Category", id, category); err != nil {
+			t.Fatalf("Table.SetValue() failed: %v", err)
+		}
+		if err := table.SetValue("Tags", id, [][]byte{[]byte("Tag")}); err != nil {
+			t.Fatalf("Table.SetValue() failed: %v", err)
 		}
 	}
-	return true
+	s := NewSelect("Table")
+	s.Filter = "Value >= 50"
+	s.SortBy = "-Value"
+	s.OutputColumns = "_key,Value,Tags"
+	s.Limit = 2
+	s.Drilldown = "Category"
+	s.DrilldownSortBy = "_key"
+	result, err := db.Select(s)
+	if err != nil {
+		t.Fatalf("DB.Select() failed: %v", err)
+	}
+	expectedColumns := []SelectColumn{
+		{"_key", "ShortText"}, {"Value", "Int32"}, {"Tags", "ShortText"},
+	}
+	if (result.NHits != 5) || !reflect.DeepEqual(result.Columns, expectedColumns) {
+		t.Fatalf("DB.Select() failed: nHits = %d, columns = %v",
+			result.NHits, result.Columns)
+	}
+	expectedRows := [][]interface{}{
+		{[]byte("9"), int64(90), [][]byte{[]byte("Tag")}},
+		{[]byte("8"), int64(80), [][]byte{[]byte("Tag")}},
+	}
+	if !reflect.DeepEqual(result.Rows, expectedRows) {
+		t.Fatalf("DB.Select() failed: rows = %v", result.Rows)
+	}
+	if len(result.Drilldowns) != 1 {
+		t.Fatalf("DB.Select() failed: drilldowns = %v", result.Drilldowns)
+	}
+	expectedRows = [][]interface{}{
+		{[]byte("Even"), int64(2)},
+		{[]byte("Odd"), int64(3)},
+	}
+	if !reflect.DeepEqual(result.Drilldowns[0].Rows, expectedRows) {
+		t.Fatalf("DB.Select() failed: drilldown rows = %v",
+			result.Drilldowns[0].Rows)
+	}
 }
 
-func TestRefColumn(t *testing.T) {
-	dirPath, _, db := createTempDB(t)
+func TestQueryResponse(t *testing.T) {
+	dirPath, _, db, _ := createTempTable(t, "Table", nil)
 	defer removeTempDB(t, dirPath, db)
-	keyTypes := []string{
-		"Bool", "Int8", "Int16", "Int32", "Int64", "UInt8", "UInt16", "UInt32",
-		"UInt64", "Float", "Time", "ShortText",
-		//"TokyoGeoPoint", "WGS84GeoPoint",
+	response, err := db.QueryResponse("select Table")
+	if err != nil {
+		t.Fatalf("DB.QueryResponse() failed: %v", err)
 	}
-	refTypes := []string{"Table"}
-	//refTypes := []string{ "Table", "[]Table" }
-	for _, keyType := range keyTypes {
-		for _, refType := range refTypes {
-			if !testRefColumn(t, db, keyType, refType) {
-				t.Logf("[ fail ] keyType = \"%s\", refType = \"%s\"", keyType, refType)
-				t.Fail()
-			}
-		}
+	if (response.ReturnCode != Success) || (response.ErrorMessage != "") ||
+		response.StartTime.IsZero() || (response.Elapsed < 0) {
+		t.Fatalf("DB.QueryResponse() failed: response = %+v", response)
+	}
+	if _, err := db.parseSelect(OutputJSON, response.Body); err != nil {
+		t.Fatalf("DB.parseSelect() failed: %v", err)
+	}
+	response, err = db.QueryResponse("select NoSuchTable")
+	if err == nil {
+		t.Fatalf("DB.QueryResponse() succeeded for an invalid command")
+	}
+	if (response.ReturnCode == Success) || (response.ErrorMessage == "") {
+		t.Fatalf("DB.QueryResponse() failed: response = %+v", response)
+	}
+
+	response = new(Response)
+	output := `[[-22,1431950400.5,0.25,"invalid table name"],[]]`
+	if !response.parseEnvelope([]byte(output)) {
+		t.Fatalf("Response.parseEnvelope() failed: output = %s", output)
+	}
+	if (response.ReturnCode != ErrInvalidArgument) ||
+		(response.StartTime.UnixNano() != 1431950400500000000) ||
+		(response.Elapsed != 250*time.Millisecond) ||
+		(response.ErrorMessage != "invalid table name") ||
+		(string(response.Body) != "[]") {
+		t.Fatalf("Response.parseEnvelope() failed: response = %+v", response)
+	}
+	output = `{"header":{"return_code":0,"start_time":1.0,"elapsed_time":0.5},"body":true}`
+	if !response.parseEnvelope([]byte(output)) {
+		t.Fatalf("Response.parseEnvelope() failed: output = %s", output)
+	}
+	if (response.ReturnCode != Success) || (string(response.Body) != "true") {
+		t.Fatalf("Response.parseEnvelope() failed: response = %+v", response)
+	}
+	if output := `[[[0],[["_id","UInt32"]]]]`; response.parseEnvelope([]byte(output)) {
+		t.Fatalf("Response.parseEnvelope() succeeded: output = %s", output)
 	}
 }
 
-func TestInvalidRows(t *testing.T) {
-	dirPath, _, db, table, column :=
-		createTempColumn(t, "Table", nil, "Value", "Int32", nil)
+func TestOutputType(t *testing.T) {
+	dirPath, _, db, table := createTempTable(t, "Table", nil)
 	defer removeTempDB(t, dirPath, db)
-	for id := uint32(1); id <= 100; id++ {
-		if _, err := column.GetValue(id); err == nil {
-			t.Fatalf("Column.GetValue() succeeded for an invalid row")
-		}
-		if err := column.SetValue(id, int64(id)+100); err == nil {
-			t.Fatalf("Column.SetValue() succeeded for an invalid row")
-		}
+	db.SetOutputType(OutputMessagePack)
+	if _, err := table.CreateColumn("Value", "Float", nil); err != nil {
+		t.Fatalf("Table.CreateColumn() failed: %v", err)
 	}
-	for id := uint32(1); id <= 100; id++ {
-		_, _, err := table.InsertRow(nil)
+	for i := 0; i < 3; i++ {
+		_, id, err := table.InsertRow(nil)
 		if err != nil {
 			t.Fatalf("Table.InsertRow() failed: %v", err)
 		}
-	}
-	for id := uint32(2); id <= 100; id += 2 {
-		_, err := db.Query("delete Table --id " + strconv.Itoa(int(id)))
-		if err != nil {
-			t.Fatalf("DB.Query() failed: %v", err)
+		if err := table.SetValue("Value", id, float64(i)+0.5); err != nil {
+			t.Fatalf("Table.SetValue() failed: %v", err)
 		}
 	}
-	for id := uint32(1); id <= 100; id++ {
-		if (id % 2) == 0 {
-			if _, err := column.GetValue(id); err == nil {
-				t.Fatalf("Column.GetValue() succeeded for an invalid row")
-			}
-			if err := column.SetValue(id, int64(id)+200); err == nil {
-				t.Fatalf("Column.SetValue() succeeded for an invalid row")
-			}
-		} else {
-			if _, err := column.GetValue(id); err != nil {
-				t.Fatalf("Column.GetValue() failed: %v", err)
-			}
-			if err := column.SetValue(id, int64(id)+200); err != nil {
-				t.Fatalf("Column.GetValue() failed: %v", err)
-			}
-		}
+	output, err := db.QueryEx("status", nil)
+	if err != nil {
+		t.Fatalf("DB.QueryEx() failed: %v", err)
+	}
+	status, err := DecodeMessagePack(output)
+	if err != nil {
+		t.Fatalf("DecodeMessagePack() failed: %v", err)
+	}
+	if _, ok := status.(map[string]interface{}); !ok {
+		t.Fatalf("DecodeMessagePack() failed: status = %v", status)
+	}
+	s := NewSelect("Table")
+	s.OutputColumns = "_id,Value"
+	msgpackResult, err := db.Select(s)
+	if err != nil {
+		t.Fatalf("DB.Select() failed: %v", err)
+	}
+	db.SetOutputType("")
+	jsonResult, err := db.Select(s)
+	if err != nil {
+		t.Fatalf("DB.Select() failed: %v", err)
+	}
+	if (msgpackResult.NHits != 3) ||
+		!reflect.DeepEqual(msgpackResult.Rows, jsonResult.Rows) {
+		t.Fatalf("DB.Select() failed: msgpack = %v, json = %v",
+			msgpackResult.Rows, jsonResult.Rows)
 	}
 }
 
-func TestRef(t *testing.T) {
-	dirPath, _, db := createTempDB(t)
-	defer removeTempDB(t, dirPath, db)
+func TestQueryArrow(t *testing.T) {
 	options := NewTableOptions()
 	options.KeyType = "ShortText"
-	table, err := db.CreateTable("Table", options)
+	dirPath, _, db, table := createTempTable(t, "Table", options)
+	defer removeTempDB(t, dirPath, db)
+	output, err := db.QueryEx("status", nil)
 	if err != nil {
-		t.Fatalf("DB.CreateTable() failed: %v", err)
+		t.Fatalf("DB.QueryEx() failed: %v", err)
 	}
-	for i := 0; i < 100; i++ {
-		key := []byte(strconv.Itoa(i))
-		if _, _, err := table.InsertRow(key); err != nil {
-			t.Fatalf("Table.InsertRow() failed: %v", err)
-		}
+	status, err := DecodeJSON(output)
+	if err != nil {
+		t.Fatalf("DecodeJSON() failed: %v", err)
 	}
-	column, err := table.CreateColumn("Ref", "Table", nil)
-	for i := 0; i < 100; i++ {
-		id := uint32(i + 1)
-		value := []byte(strconv.Itoa((i + 1) % 100))
-		if err := column.SetValue(id, value); err != nil {
-			t.Fatalf("Column.SetValue() failed: %v", err)
-		}
-		storedValue, err := column.GetValue(id)
-		if err != nil {
-			t.Fatalf("Column.GetValue() failed: %v", err)
-		}
-		if !reflect.DeepEqual(value, storedValue) {
-			t.Fatalf("Column.GetValue() failed: value = %v, storedValue = %v",
-				value, storedValue)
-		}
+	features, _ := status.(map[string]interface{})["features"].(map[string]interface{})
+	if features["apache_arrow"] != true {
+		t.Skip("Groonga is built without Apache Arrow")
 	}
-	column, err = table.FindColumn("Ref._key")
-	for i := 0; i < 100; i++ {
-		id := uint32(i + 1)
-		value := []byte(strconv.Itoa((i + 1) % 100))
-		storedValue, err := column.GetValue(id)
+	if _, err := table.CreateColumn("Value", "Int32", nil); err != nil {
+		t.Fatalf("Table.CreateColumn() failed: %v", err)
+	}
+	var keys []string
+	var values []int32
+	for i := 0; i < 1000; i++ {
+		key := strconv.Itoa(i)
+		_, id, err := table.InsertRow([]byte(key))
 		if err != nil {
-			t.Fatalf("Column.GetValue() failed: %v", err)
+			t.Fatalf("Table.InsertRow() failed: %v", err)
 		}
-		if !reflect.DeepEqual(value, storedValue) {
-			t.Fatalf("Column.GetValue() failed: value = %v, storedValue = %v",
-				value, storedValue)
+		if err := table.SetValue("Value", id, int64(i*10)); err != nil {
+			t.Fatalf("Table.SetValue() failed: %v", err)
 		}
+		keys = append(keys, key)
+		values = append(values, int32(i*10))
 	}
-	column, err = table.FindColumn("Ref.Ref.Ref")
-	for i := 0; i < 100; i++ {
-		id := uint32(i + 1)
-		value := []byte(strconv.Itoa((i + 3) % 100))
-		storedValue, err := column.GetValue(id)
-		if err != nil {
-			t.Fatalf("Column.GetValue() failed: %v", err)
-		}
-		if !reflect.DeepEqual(value, storedValue) {
-			t.Fatalf("Column.GetValue() failed: value = %v, storedValue = %v",
-				value, storedValue)
+	reader, err := db.QueryArrow("select", map[string]string{
+		"table":          "Table",
+		"output_columns": "_key,Value",
+		"sort_keys":      "_id",
+		"limit":          "-1",
+	})
+	if err != nil {
+		t.Fatalf("DB.QueryArrow() failed: %v", err)
+	}
+	var storedKeys []string
+	var storedValues []int32
+	for reader.Next() {
+		record := reader.Record()
+		for _, column := range record.Columns {
+			switch column.Field.Name {
+			case "_key":
+				storedKeys = append(storedKeys, column.Values.([]string)...)
+			case "Value":
+				storedValues = append(storedValues, column.Values.([]int32)...)
+			}
 		}
 	}
+	if err := reader.Err(); err != nil {
+		t.Fatalf("ArrowReader.Next() failed: %v", err)
+	}
+	if !reflect.DeepEqual(storedKeys, keys) || !reflect.DeepEqual(storedValues, values) {
+		t.Fatalf("DB.QueryArrow() failed: keys = %v, values = %v",
+			storedKeys, storedValues)
+	}
+
+	// Close stops reading and then db is available.
+	reader, err = db.QueryArrow("select", map[string]string{"table": "Table"})
+	if err != nil {
+		t.Fatalf("DB.QueryArrow() failed: %v", err)
+	}
+	if err := reader.Close(); err != nil {
+		t.Fatalf("ArrowReader.Close() failed: %v", err)
+	}
+	reader, err = db.QueryArrow("select", map[string]string{"table": "NoSuchTable"})
+	if err != nil {
+		t.Fatalf("DB.QueryArrow() failed: %v", err)
+	}
+	if reader.Next() || (reader.Err() == nil) {
+		t.Fatalf("ArrowReader.Next() succeeded for an invalid command")
+	}
+	if _, err := db.Query("status"); err != nil {
+		t.Fatalf("DB.Query() failed: %v", err)
+	}
 }
 
-/*
 func TestRefs(t *testing.T) {
 	dirPath, _, db := createTempDB(t)
 	defer removeTempDB(t, dirPath, db)
@@ -818,8 +2816,48 @@ func TestRefs(t *testing.T) {
 				value, storedValue)
 		}
 	}
+
+	column, err = table.FindColumn("Ref")
+	if err != nil {
+		t.Fatalf("Table.FindColumn() failed: %v", err)
+	}
+	if err := column.SetValue(1, []uint32{3, 2, 1}); err != nil {
+		t.Fatalf("Column.SetValue() failed: %v", err)
+	}
+	storedValue, err := column.GetValue(1)
+	if err != nil {
+		t.Fatalf("Column.GetValue() failed: %v", err)
+	}
+	if value := [][]byte{[]byte("2"), []byte("1"), []byte("0")}; !reflect.DeepEqual(value, storedValue) {
+		t.Fatalf("Column.GetValue() failed: value = %v, storedValue = %v",
+			value, storedValue)
+	}
+	if err := column.SetValue(1, []uint32{1000}); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("Column.SetValue() failed: err = %v", err)
+	}
+	if err := column.SetValue(2, [][]byte{[]byte("New")}); err != nil {
+		t.Fatalf("Column.SetValue() failed: %v", err)
+	}
+	if _, found, err := table.FindRow([]byte("New")); (err != nil) || !found {
+		t.Fatalf("Table.FindRow() failed: found = %v, err = %v", found, err)
+	}
+	keyVectors := [][][]byte{{[]byte("3")}, {}, {[]byte("Newer"), []byte("4")}}
+	if err := column.SetValues([]uint32{1, 2, 3}, keyVectors); err != nil {
+		t.Fatalf("Column.SetValues() failed: %v", err)
+	}
+	if err := column.SetValues([]uint32{4}, [][]uint32{{2, 1}}); err != nil {
+		t.Fatalf("Column.SetValues() failed: %v", err)
+	}
+	storedValues, err := column.GetValues([]uint32{1, 2, 3, 4})
+	if err != nil {
+		t.Fatalf("Column.GetValues() failed: %v", err)
+	}
+	values := append(keyVectors, [][]byte{[]byte("1"), []byte("0")})
+	if !reflect.DeepEqual(values, storedValues) {
+		t.Fatalf("Column.GetValues() failed: values = %q, storedValues = %q",
+			values, storedValues)
+	}
 }
-*/
 
 /*
 func TestDeepVector(t *testing.T) {
@@ -0,0 +1,231 @@
+package grngo
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// DecodeMessagePack decodes MessagePack output into the same values as
+// DecodeJSON. Strings and binaries are decoded into string and a timestamp
+// extension is decoded into float64 seconds.
+func DecodeMessagePack(output []byte) (interface{}, error) {
+	decoder := msgpackDecoder{buf: output}
+	return decoder.decodeAll()
+}
+
+// msgpackDecoder is a MessagePack decoder.
+type msgpackDecoder struct {
+	buf     []byte // The input.
+	pos     int    // The current position.
+	ordered bool   // Whether a map is decoded into *selectObject.
+}
+
+// next consumes n bytes.
+func (decoder *msgpackDecoder) next(n int) ([]byte, error) {
+	if (n < 0) || (n > len(decoder.buf)-decoder.pos) {
+		return nil, fmt.Errorf("invalid MessagePack: unexpected end of data")
+	}
+	data := decoder.buf[decoder.pos : decoder.pos+n]
+	decoder.pos += n
+	return data, nil
+}
+
+// uint consumes an n-byte big-endian unsigned integer.
+func (decoder *msgpackDecoder) uint(n int) (uint64, error) {
+	data, err := decoder.next(n)
+	if err != nil {
+		return 0, err
+	}
+	var value uint64
+	for _, b := range data {
+		value = (value << 8) | uint64(b)
+	}
+	return value, nil
+}
+
+// decodeAll decodes a value and rejects trailing data.
+func (decoder *msgpackDecoder) decodeAll() (interface{}, error) {
+	value, err := decoder.decode()
+	if err != nil {
+		return nil, err
+	}
+	if decoder.pos != len(decoder.buf) {
+		return nil, fmt.Errorf("invalid MessagePack: trailing data at %d", decoder.pos)
+	}
+	return value, nil
+}
+
+// decode decodes a value.
+func (decoder *msgpackDecoder) decode() (interface{}, error) {
+	head, err := decoder.uint(1)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case head <= 0x7f:
+		return int64(head), nil
+	case head >= 0xe0:
+		return int64(int8(head)), nil
+	case head <= 0x8f:
+		return decoder.decodeMap(int(head & 0x0f))
+	case head <= 0x9f:
+		return decoder.decodeArray(int(head & 0x0f))
+	case head <= 0xbf:
+		return decoder.decodeString(int(head & 0x1f))
+	}
+	switch head {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6, 0xd9, 0xda, 0xdb:
+		sizes := map[uint64]int{0xc4: 1, 0xc5: 2, 0xc6: 4, 0xd9: 1, 0xda: 2, 0xdb: 4}
+		n, err := decoder.uint(sizes[head])
+		if err != nil {
+			return nil, err
+		}
+		return decoder.decodeString(int(n))
+	case 0xc7, 0xc8, 0xc9:
+		n, err := decoder.uint(1 << (head - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return decoder.decodeExt(int(n))
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return decoder.decodeExt(1 << (head - 0xd4))
+	case 0xca:
+		bits, err := decoder.uint(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(uint32(bits))), nil
+	case 0xcb:
+		bits, err := decoder.uint(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(bits), nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		value, err := decoder.uint(1 << (head - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		if value > math.MaxInt64 {
+			return value, nil
+		}
+		return int64(value), nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (head - 0xd0)
+		value, err := decoder.uint(size)
+		if err != nil {
+			return nil, err
+		}
+		shift := uint(64 - size*8)
+		return int64(value<<shift) >> shift, nil
+	case 0xdc, 0xdd:
+		n, err := decoder.uint(2 << (head - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return decoder.decodeArray(int(n))
+	case 0xde, 0xdf:
+		n, err := decoder.uint(2 << (head - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return decoder.decodeMap(int(n))
+	}
+	return nil, fmt.Errorf("invalid MessagePack: format = 0x%02x", head)
+}
+
+// decodeString decodes a string or a binary of n bytes.
+func (decoder *msgpackDecoder) decodeString(n int) (interface{}, error) {
+	data, err := decoder.next(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// decodeArray decodes an array of n values.
+func (decoder *msgpackDecoder) decodeArray(n int) (interface{}, error) {
+	if n > len(decoder.buf)-decoder.pos {
+		return nil, fmt.Errorf("invalid MessagePack: unexpected end of data")
+	}
+	values := make([]interface{}, n)
+	for i := range values {
+		value, err := decoder.decode()
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// decodeMap decodes a map of n pairs.
+func (decoder *msgpackDecoder) decodeMap(n int) (interface{}, error) {
+	if n > len(decoder.buf)-decoder.pos {
+		return nil, fmt.Errorf("invalid MessagePack: unexpected end of data")
+	}
+	object := &selectObject{
+		keys:   make([]string, n),
+		values: make([]interface{}, n),
+	}
+	for i := 0; i < n; i++ {
+		key, err := decoder.decode()
+		if err != nil {
+			return nil, err
+		}
+		keyString, ok := key.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid MessagePack: unsupported key: %v", key)
+		}
+		value, err := decoder.decode()
+		if err != nil {
+			return nil, err
+		}
+		object.keys[i] = keyString
+		object.values[i] = value
+	}
+	if decoder.ordered {
+		return object, nil
+	}
+	return object.toMap(), nil
+}
+
+// decodeExt decodes an extension of n bytes.
+// Only the timestamp extension (-1) is supported.
+func (decoder *msgpackDecoder) decodeExt(n int) (interface{}, error) {
+	extType, err := decoder.uint(1)
+	if err != nil {
+		return nil, err
+	}
+	data, err := decoder.next(n)
+	if err != nil {
+		return nil, err
+	}
+	if int8(extType) != -1 {
+		return nil, fmt.Errorf("invalid MessagePack: unsupported extension: %d",
+			int8(extType))
+	}
+	var sec int64
+	var nsec uint32
+	switch n {
+	case 4:
+		sec = int64(binary.BigEndian.Uint32(data))
+	case 8:
+		value := binary.BigEndian.Uint64(data)
+		sec = int64(value & 0x3ffffffff)
+		nsec = uint32(value >> 34)
+	case 12:
+		nsec = binary.BigEndian.Uint32(data)
+		sec = int64(binary.BigEndian.Uint64(data[4:]))
+	default:
+		return nil, fmt.Errorf("invalid MessagePack: invalid timestamp size: %d", n)
+	}
+	return float64(sec) + float64(nsec)/1e9, nil
+}
@@ -0,0 +1,77 @@
+package grngo
+
+import (
+	"math"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// The fixtures in testdata are generated by testdata/gen.
+
+func TestDecodeMessagePack(t *testing.T) {
+	output, err := os.ReadFile("testdata/values.msgpack")
+	if err != nil {
+		t.Fatalf("os.ReadFile() failed: %v", err)
+	}
+	value, err := DecodeMessagePack(output)
+	if err != nil {
+		t.Fatalf("DecodeMessagePack() failed: %v", err)
+	}
+	array16 := make([]interface{}, 20)
+	map16 := make(map[string]interface{}, 20)
+	for i := range array16 {
+		array16[i] = int64(i)
+		map16[strconv.Itoa(i)] = int64(i)
+	}
+	object, err := DecodeJSON([]byte(`{"k":300,"nested":["v",null]}`))
+	if err != nil {
+		t.Fatalf("DecodeJSON() failed: %v", err)
+	}
+	expected := []interface{}{
+		int64(1), int64(-1), int64(-100), int64(200), int64(300), int64(-300),
+		int64(70000), int64(-70000), int64(1) << 40, -(int64(1) << 40),
+		uint64(math.MaxUint64),
+		"ab", strings.Repeat("x", 40), strings.Repeat("y", 300),
+		true, false, nil, 1.5, 0.25, "bin", object, array16, map16,
+		1.0, 1.5, float64(1<<34) + 0.5,
+	}
+	if !reflect.DeepEqual(value, expected) {
+		t.Fatalf("DecodeMessagePack() failed: value = %v, expected = %v",
+			value, expected)
+	}
+	if _, err := DecodeMessagePack(output[:len(output)-1]); err == nil {
+		t.Fatalf("DecodeMessagePack() succeeded for truncated data")
+	}
+	if _, err := DecodeMessagePack(append(output, 0xc0)); err == nil {
+		t.Fatalf("DecodeMessagePack() succeeded for trailing data")
+	}
+}
+
+func TestDecodeSelect(t *testing.T) {
+	output, err := os.ReadFile("testdata/select.msgpack")
+	if err != nil {
+		t.Fatalf("os.ReadFile() failed: %v", err)
+	}
+	value, err := decodeSelect(OutputMessagePack, output)
+	if err != nil {
+		t.Fatalf("decodeSelect() failed: %v", err)
+	}
+	expected, err := decodeSelect(OutputJSON, []byte(
+		`[[[2],[["_id","UInt32"],["tags","Tags"]],[1,{"b":2,"a":1}],[2,{}]]]`))
+	if err != nil {
+		t.Fatalf("decodeSelect() failed: %v", err)
+	}
+	if !reflect.DeepEqual(value, expected) {
+		t.Fatalf("decodeSelect() failed: value = %v, expected = %v",
+			value, expected)
+	}
+	object := value.([]interface{})[0].([]interface{})[2].([]interface{})[1].(*selectObject)
+	if !reflect.DeepEqual(object.keys, []string{"b", "a"}) ||
+		!reflect.DeepEqual(object.values, []interface{}{int64(2), int64(1)}) {
+		t.Fatalf("decodeSelect() failed: keys = %v, values = %v",
+			object.keys, object.values)
+	}
+}
@@ -0,0 +1,9 @@
+module github.com/groonga/grngo/testdata/gen
+
+go 1.21
+
+require (
+	github.com/apache/arrow/go/arrow v0.0.0-20211112161151-bc219186db40
+	github.com/google/flatbuffers v1.12.1
+	github.com/vmihailenco/msgpack/v5 v5.4.1
+)
@@ -0,0 +1,389 @@
+// Command gen generates the MessagePack and Apache Arrow fixtures in testdata.
+//
+// Run "go mod tidy && go run ." in this directory to regenerate them.
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/arrow"
+	"github.com/apache/arrow/go/arrow/array"
+	"github.com/apache/arrow/go/arrow/ipc"
+	"github.com/apache/arrow/go/arrow/memory"
+	flatbuffers "github.com/google/flatbuffers/go"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func main() {
+	files := map[string][]byte{
+		"values.msgpack":    genValues(),
+		"select.msgpack":    genSelect(),
+		"types.arrows":      genTypes(),
+		"zstd.arrows":       genZstd(),
+		"dictionary.arrows": genDictionary(),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join("..", name), data, 0644); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// orderedMap is a map encoded in order.
+type orderedMap [][2]interface{}
+
+func (m orderedMap) EncodeMsgpack(enc *msgpack.Encoder) error {
+	if err := enc.EncodeMapLen(len(m)); err != nil {
+		return err
+	}
+	for _, pair := range m {
+		if err := enc.Encode(pair[0]); err != nil {
+			return err
+		}
+		if err := enc.Encode(pair[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeMsgpack(value interface{}) []byte {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.UseCompactInts(true)
+	enc.SetSortMapKeys(true)
+	if err := enc.Encode(value); err != nil {
+		log.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// genValues covers every format used by DecodeMessagePack.
+// The values must match TestDecodeMessagePack.
+func genValues() []byte {
+	array16 := make([]int, 20)
+	map16 := make(map[string]int, 20)
+	for i := range array16 {
+		array16[i] = i
+		map16[strconv.Itoa(i)] = i
+	}
+	return encodeMsgpack([]interface{}{
+		1, -1, -100, 200, 300, -300, 70000, -70000,
+		int64(1) << 40, -(int64(1) << 40), uint64(math.MaxUint64),
+		"ab", strings.Repeat("x", 40), strings.Repeat("y", 300),
+		true, false, nil, 1.5, float32(0.25), []byte("bin"),
+		map[string]interface{}{"k": 300, "nested": []interface{}{"v", nil}},
+		array16, map16,
+		time.Unix(1, 0), time.Unix(1, 500000000), time.Unix(1<<34, 500000000),
+	})
+}
+
+// genSelect encodes a select result with a weight vector.
+func genSelect() []byte {
+	return encodeMsgpack([]interface{}{
+		[]interface{}{
+			[]interface{}{2},
+			[]interface{}{
+				[]interface{}{"_id", "UInt32"},
+				[]interface{}{"tags", "Tags"},
+			},
+			[]interface{}{1, orderedMap{{"b", 2}, {"a", 1}}},
+			[]interface{}{2, orderedMap{}},
+		},
+	})
+}
+
+// typesSchema is the schema of types.arrows and zstd.arrows.
+func typesSchema() *arrow.Schema {
+	metadata := arrow.NewMetadata([]string{"GROONGA:n_hits"}, []string{"3"})
+	return arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Uint32},
+		{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "score", Type: arrow.PrimitiveTypes.Float64},
+		{Name: "tags", Type: arrow.ListOf(arrow.BinaryTypes.String), Nullable: true},
+		{Name: "time", Type: arrow.FixedWidthTypes.Timestamp_us},
+		{Name: "flag", Type: arrow.FixedWidthTypes.Boolean},
+		{Name: "int8", Type: arrow.PrimitiveTypes.Int8},
+		{Name: "int16", Type: arrow.PrimitiveTypes.Int16},
+		{Name: "int64", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "uint64", Type: arrow.PrimitiveTypes.Uint64},
+		{Name: "float32", Type: arrow.PrimitiveTypes.Float32},
+		{Name: "data", Type: arrow.BinaryTypes.Binary},
+		{Name: "none", Type: arrow.Null, Nullable: true},
+	}, &metadata)
+}
+
+// typesRecord returns a record batch of 3 rows if first and 1 row otherwise.
+// The values must match TestArrowReader.
+func typesRecord(first bool) array.Record {
+	mem := memory.NewGoAllocator()
+	schema := typesSchema()
+	builder := array.NewRecordBuilder(mem, schema)
+	defer builder.Release()
+	ids := builder.Field(0).(*array.Uint32Builder)
+	names := builder.Field(1).(*array.StringBuilder)
+	scores := builder.Field(2).(*array.Float64Builder)
+	tags := builder.Field(3).(*array.ListBuilder)
+	tagValues := tags.ValueBuilder().(*array.StringBuilder)
+	times := builder.Field(4).(*array.TimestampBuilder)
+	flags := builder.Field(5).(*array.BooleanBuilder)
+	int8s := builder.Field(6).(*array.Int8Builder)
+	int16s := builder.Field(7).(*array.Int16Builder)
+	int64s := builder.Field(8).(*array.Int64Builder)
+	uint64s := builder.Field(9).(*array.Uint64Builder)
+	float32s := builder.Field(10).(*array.Float32Builder)
+	data := builder.Field(11).(*array.BinaryBuilder)
+	nones := builder.Field(12).(*array.NullBuilder)
+	if !first {
+		ids.Append(4)
+		names.Append("dddd")
+		scores.Append(-1)
+		tags.AppendNull()
+		times.Append(arrow.Timestamp(-1500000))
+		flags.Append(true)
+		int8s.Append(math.MinInt8)
+		int16s.Append(math.MinInt16)
+		int64s.Append(math.MinInt64)
+		uint64s.Append(0)
+		float32s.Append(-0.5)
+		data.Append(nil)
+		nones.AppendNull()
+		return builder.NewRecord()
+	}
+	ids.AppendValues([]uint32{1, 2, 3}, nil)
+	names.AppendValues([]string{"a", "", "ccc"}, []bool{true, false, true})
+	scores.AppendValues([]float64{0.5, 1.5, 2.5}, nil)
+	tags.Append(true)
+	tagValues.Append("x")
+	tags.Append(true)
+	tags.Append(true)
+	tagValues.AppendValues([]string{"y", "z"}, nil)
+	times.AppendValues([]arrow.Timestamp{1000000, -1000, 0}, nil)
+	flags.AppendValues([]bool{true, false, true}, nil)
+	int8s.AppendValues([]int8{-1, 0, math.MaxInt8}, nil)
+	int16s.AppendValues([]int16{-1, 0, math.MaxInt16}, nil)
+	int64s.AppendValues([]int64{-1, 0, math.MaxInt64}, nil)
+	uint64s.AppendValues([]uint64{1, 0, math.MaxUint64}, nil)
+	float32s.AppendValues([]float32{0.25, 0, 1.5}, nil)
+	data.AppendValues([][]byte{[]byte("\x00\x01"), nil, []byte("b")}, nil)
+	nones.AppendNull()
+	nones.AppendNull()
+	nones.AppendNull()
+	return builder.NewRecord()
+}
+
+// writeTypes writes two record batches.
+func writeTypes(options ...ipc.Option) []byte {
+	var buf bytes.Buffer
+	options = append(options, ipc.WithSchema(typesSchema()))
+	writer := ipc.NewWriter(&buf, options...)
+	for _, first := range []bool{true, false} {
+		record := typesRecord(first)
+		if err := writer.Write(record); err != nil {
+			log.Fatal(err)
+		}
+		record.Release()
+	}
+	if err := writer.Close(); err != nil {
+		log.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func genTypes() []byte {
+	return writeTypes()
+}
+
+func genZstd() []byte {
+	return writeTypes(ipc.WithZstd())
+}
+
+// The ipc package of this Arrow version does not write dictionaries, so
+// dictionary.arrows is built with the FlatBuffers builder following
+// Schema.fbs and Message.fbs.
+
+// arrowBody builds the body of a record batch.
+type arrowBody struct {
+	nodes   []int64 // Pairs of the length and the null count.
+	buffers []int64 // Pairs of the offset and the length.
+	data    []byte
+}
+
+func (body *arrowBody) node(length, nullCount int64) {
+	body.nodes = append(body.nodes, length, nullCount)
+}
+
+func (body *arrowBody) buffer(values ...interface{}) {
+	var buf bytes.Buffer
+	for _, value := range values {
+		binary.Write(&buf, binary.LittleEndian, value)
+	}
+	body.buffers = append(body.buffers, int64(len(body.data)), int64(buf.Len()))
+	body.data = append(body.data, buf.Bytes()...)
+	for len(body.data)%8 != 0 {
+		body.data = append(body.data, 0)
+	}
+}
+
+// recordBatch builds a RecordBatch table.
+func (body *arrowBody) recordBatch(b *flatbuffers.Builder, length int64) flatbuffers.UOffsetT {
+	structs := func(values []int64) flatbuffers.UOffsetT {
+		b.StartVector(16, len(values)/2, 8)
+		for i := len(values) - 2; i >= 0; i -= 2 {
+			b.Prep(8, 16)
+			b.PrependInt64(values[i+1])
+			b.PrependInt64(values[i])
+		}
+		return b.EndVector(len(values) / 2)
+	}
+	nodes := structs(body.nodes)
+	buffers := structs(body.buffers)
+	b.StartObject(4)
+	b.PrependInt64Slot(0, length, 0)
+	b.PrependUOffsetTSlot(1, nodes, 0)
+	b.PrependUOffsetTSlot(2, buffers, 0)
+	return b.EndObject()
+}
+
+// writeMessage writes an encapsulated message.
+func writeMessage(w *bytes.Buffer, headerType byte, header func(b *flatbuffers.Builder) flatbuffers.UOffsetT, body []byte) {
+	b := flatbuffers.NewBuilder(0)
+	headerPos := header(b)
+	b.StartObject(5)
+	b.PrependInt16Slot(0, 4, 0) // V5
+	b.PrependByteSlot(1, headerType, 0)
+	b.PrependUOffsetTSlot(2, headerPos, 0)
+	b.PrependInt64Slot(3, int64(len(body)), 0)
+	b.Finish(b.EndObject())
+	metadata := b.FinishedBytes()
+	size := (len(metadata) + 8 + 7) / 8 * 8
+	binary.Write(w, binary.LittleEndian, uint32(0xffffffff))
+	binary.Write(w, binary.LittleEndian, uint32(size-8))
+	w.Write(metadata)
+	w.Write(make([]byte, size-8-len(metadata)))
+	w.Write(body)
+}
+
+// field builds a Field table. A dictionary-encoded field has an index type
+// of the given bit width.
+func field(b *flatbuffers.Builder, name string, typeType byte, dictionaryID int64, bitWidth int32, children ...flatbuffers.UOffsetT) flatbuffers.UOffsetT {
+	namePos := b.CreateString(name)
+	b.StartObject(0)
+	typePos := b.EndObject()
+	var dictionaryPos flatbuffers.UOffsetT
+	if bitWidth != 0 {
+		b.StartObject(2)
+		b.PrependInt32Slot(0, bitWidth, 0)
+		b.PrependBoolSlot(1, true, false)
+		indexType := b.EndObject()
+		b.StartObject(4)
+		b.PrependInt64Slot(0, dictionaryID, 0)
+		b.PrependUOffsetTSlot(1, indexType, 0)
+		dictionaryPos = b.EndObject()
+	}
+	b.StartVector(4, len(children), 4)
+	for i := len(children) - 1; i >= 0; i-- {
+		b.PrependUOffsetT(children[i])
+	}
+	childrenPos := b.EndVector(len(children))
+	b.StartObject(7)
+	b.PrependUOffsetTSlot(0, namePos, 0)
+	b.PrependBoolSlot(1, true, false)
+	b.PrependByteSlot(2, typeType, 0)
+	b.PrependUOffsetTSlot(3, typePos, 0)
+	if dictionaryPos != 0 {
+		b.PrependUOffsetTSlot(4, dictionaryPos, 0)
+	}
+	b.PrependUOffsetTSlot(5, childrenPos, 0)
+	return b.EndObject()
+}
+
+// Arrow type IDs in Schema.fbs.
+const (
+	typeUtf8 = 5
+	typeList = 12
+)
+
+// genDictionary writes a stream with dictionary-encoded "tag" (int8 indices
+// of dictionary 0) and "tags" (list of int16 indices of dictionary 1).
+// The values must match TestArrowReaderDictionary.
+func genDictionary() []byte {
+	var w bytes.Buffer
+	writeMessage(&w, 1, func(b *flatbuffers.Builder) flatbuffers.UOffsetT {
+		tag := field(b, "tag", typeUtf8, 0, 8)
+		item := field(b, "item", typeUtf8, 1, 16)
+		tags := field(b, "tags", typeList, 0, 0, item)
+		b.StartVector(4, 2, 4)
+		b.PrependUOffsetT(tags)
+		b.PrependUOffsetT(tag)
+		fields := b.EndVector(2)
+		b.StartObject(4)
+		b.PrependUOffsetTSlot(1, fields, 0)
+		return b.EndObject()
+	}, nil)
+	dictionary := func(id int64, isDelta bool, values ...string) {
+		body := new(arrowBody)
+		body.node(int64(len(values)), 0)
+		body.buffer()
+		offsets := []int32{0}
+		for _, value := range values {
+			offsets = append(offsets, offsets[len(offsets)-1]+int32(len(value)))
+		}
+		body.buffer(offsets)
+		body.buffer([]byte(strings.Join(values, "")))
+		writeMessage(&w, 2, func(b *flatbuffers.Builder) flatbuffers.UOffsetT {
+			data := body.recordBatch(b, int64(len(values)))
+			b.StartObject(3)
+			b.PrependInt64Slot(0, id, 0)
+			b.PrependUOffsetTSlot(1, data, 0)
+			b.PrependBoolSlot(2, isDelta, false)
+			return b.EndObject()
+		}, body.data)
+	}
+	record := func(length int64, body *arrowBody) {
+		writeMessage(&w, 3, func(b *flatbuffers.Builder) flatbuffers.UOffsetT {
+			return body.recordBatch(b, length)
+		}, body.data)
+	}
+	dictionary(0, false, "a", "b")
+	dictionary(1, false, "x", "y")
+
+	// tag = ["b", null, "a"], tags = [["y"], [], ["x", "x"]]
+	body := new(arrowBody)
+	body.node(3, 1)
+	body.buffer(uint8(0x05))
+	body.buffer([]int8{1, 0, 0})
+	body.node(3, 0)
+	body.buffer()
+	body.buffer([]int32{0, 1, 1, 3})
+	body.node(3, 0)
+	body.buffer()
+	body.buffer([]int16{1, 0, 0})
+	record(3, body)
+
+	dictionary(0, true, "c")
+
+	// tag = ["c"], tags = [["x"]]
+	body = new(arrowBody)
+	body.node(1, 0)
+	body.buffer()
+	body.buffer([]int8{2})
+	body.node(1, 0)
+	body.buffer()
+	body.buffer([]int32{0, 1})
+	body.node(1, 0)
+	body.buffer()
+	body.buffer([]int16{0})
+	record(1, body)
+
+	binary.Write(&w, binary.LittleEndian, []uint32{0xffffffff, 0})
+	return w.Bytes()
+}